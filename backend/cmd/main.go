@@ -1,62 +1,277 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"vaultseed-backend/internal/config"
 	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/ens"
+	"vaultseed-backend/internal/ethrpc"
 	"vaultseed-backend/internal/handlers"
+	"vaultseed-backend/internal/jobs"
+	"vaultseed-backend/internal/middleware"
+	"vaultseed-backend/internal/selfcheck"
+	"vaultseed-backend/internal/siem"
+	"vaultseed-backend/internal/utils"
+	"vaultseed-backend/internal/webhook"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
+	// 加载配置
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	// 结构化 JSON 日志；设为默认后标准库 log 的输出也走同一格式
+	logLevel := slog.LevelInfo
+	if cfg.Debug {
+		logLevel = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+	slog.SetDefault(logger)
+
 	// 初始化数据库
-	if err := database.InitDB(); err != nil {
+	if err := database.InitDB(cfg); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
+	// 启动自检
+	checks := []selfcheck.Check{
+		{Name: "database connectivity", Critical: true, Run: database.Ping},
+		{Name: "database migrations", Critical: true, Run: func(ctx context.Context) error {
+			return database.CheckMigrations()
+		}},
+		{Name: "secure random source", Critical: true, Run: func(ctx context.Context) error {
+			return utils.CheckRandomness()
+		}},
+	}
+	if err := selfcheck.Run(context.Background(), checks); err != nil {
+		log.Println("Startup aborted:", err)
+		os.Exit(selfcheck.ExitCode)
+	}
+
+	handlers.Init(cfg)
+	utils.SetAppName(cfg.AppName)
+	utils.SetAllowShortSignatures(cfg.AllowShortSignatures)
+	if err := utils.SetServerKey(cfg.ServerSigningKey); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	if err := utils.SetEscrowKey(cfg.TitleEscrowKey); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+	if utils.EscrowEnabled() {
+		log.Println("TITLE_ESCROW_KEY is set; users who opt into title escrow give up zero-knowledge titles")
+	}
+	generated, err := utils.SetTokenSecret(cfg.JWTSecret, cfg.JWTTTL)
+	if err != nil {
+		log.Fatal("Failed to initialize token secret: ", err)
+	}
+	if generated {
+		log.Println("JWT_SECRET not set; using a random secret, issued tokens will not survive a restart")
+	}
+	webhook.Configure(cfg.WebhookURL, cfg.WebhookSecret)
+	siem.Configure(siem.Options{
+		SyslogAddr: cfg.SIEMSyslogAddr,
+		SyslogTLS:  cfg.SIEMSyslogTLS,
+		HTTPURL:    cfg.SIEMHTTPURL,
+		BufferSize: cfg.SIEMBufferSize,
+		AppName:    cfg.AppName,
+	})
+	ethrpc.Configure(cfg.EthRPCURL)
+	if cfg.AllowContractSignatures && !ethrpc.Enabled() {
+		log.Println("ALLOW_CONTRACT_SIGNATURES is set but ETH_RPC_URL is not; contract wallet signatures will be rejected")
+	}
+	if cfg.Lockdown {
+		if err := database.SetLockdown(true); err != nil {
+			log.Fatal("Failed to enable lockdown:", err)
+		}
+	}
+	if database.LockdownActive() {
+		log.Println("Emergency lockdown is active; decrypt and content endpoints will return 503")
+	}
+	// 收到 SIGINT/SIGTERM 时取消 ctx：后台任务随之退出，HTTP 服务开始优雅停机
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.NonceRotationMaxAge > 0 {
+		jobs.StartNonceRotation(ctx, cfg.NonceRotationInterval, cfg.NonceRotationMaxAge)
+	}
+	jobs.StartUploadCleanup(ctx, cfg.UploadTTL)
+	jobs.StartChallengeCleanup(ctx, cfg.DecryptNonceGrace)
+	jobs.StartMetricsRefresher(ctx, cfg.MetricsRefreshInterval)
+	ens.Configure(cfg.ENSCacheTTL)
+	if ens.Enabled() {
+		ens.StartRefresher(ctx)
+	}
+
 	// 设置 Gin 模式
-	gin.SetMode(gin.ReleaseMode)
+	gin.SetMode(cfg.GinMode)
 
-	// 创建路由
-	r := gin.Default()
+	// 创建路由，用 JSON 访问日志替代 gin 默认的文本日志
+	r := gin.New()
+	// 默认不信任任何代理，避免客户端伪造 X-Forwarded-For 绕过按 IP 的限流
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+	r.Use(middleware.Logger(logger), middleware.Recovery(logger), middleware.Metrics())
 
 	// CORS 配置
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "Accept"}
-	r.Use(cors.New(config))
+	if len(cfg.CORSAllowedOrigins) == 0 {
+		log.Println("CORS_ALLOWED_ORIGINS not set; allowing requests from any origin, do not use this in production")
+	}
+	r.Use(middleware.CORS(cfg.CORSAllowedOrigins))
+
+	// Prometheus 指标，不在 /api 下，部署时应仅对监控网络开放
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// API 路由
-	api := r.Group("/api")
+	// 所有 /api 路由默认限制请求体大小，下面按路由设置的 MaxBodySize 会替换该默认值
+	api := r.Group("/api", middleware.MaxBodySize(cfg.DefaultBodyLimit))
 	{
-		// 认证相关
-		auth := api.Group("/auth")
+		// 认证相关：未登录即可调用且每次都读写数据库，按客户端 IP 限流
+		var authLimits middleware.RateLimitStore
+		if cfg.AuthRateLimit > 0 {
+			authLimits = middleware.NewMemoryRateLimitStore(cfg.AuthRateLimit, cfg.AuthRateWindow)
+		}
+		auth := api.Group("/auth", middleware.RateLimit(authLimits), middleware.MaxBodySize(cfg.AuthBodyLimit))
 		{
 			auth.POST("/login", handlers.LoginHandler)
 			auth.POST("/register-public-key", handlers.RegisterPublicKeyHandler)
 			auth.GET("/nonce", handlers.GetNonceHandler)
+			auth.POST("/reset-nonce", handlers.ResetNonceHandler)
+			auth.POST("/match-signer", handlers.MatchSignerHandler)
+			auth.GET("/keys", middleware.RequireAuth(), handlers.ListKeysHandler)
+			auth.GET("/profile", middleware.RequireAuth(), handlers.ProfileHandler)
+			auth.PUT("/title-escrow", middleware.RequireAuth(), handlers.SetTitleEscrowHandler)
+			auth.POST("/rotate-key", middleware.RequireAuth(), middleware.MaxBodySize(cfg.ImportBodyLimit), handlers.RotateKeyHandler)
+			auth.GET("/reauth-challenge", handlers.ReauthChallengeHandler)
+		}
+
+		// 用户公钥查询（无需登录），与认证接口共用限流额度
+		users := api.Group("/users", middleware.RateLimit(authLimits))
+		{
+			users.GET("/:address/public-key", handlers.PublicKeyHandler)
+		}
+
+		// 分享链接（无需登录）
+		shared := api.Group("/content/shared", middleware.BlockDuringLockdown())
+		{
+			shared.GET("/:token", handlers.GetSharedContentHandler)
+			shared.GET("/:token/qr", handlers.SharedContentQRHandler)
 		}
 
 		// 内容相关
-		content := api.Group("/content")
+		content := api.Group("/content", middleware.RequireAuth(), middleware.BlockDuringLockdown())
 		{
-			content.POST("/create", handlers.CreateContentHandler)
+			content.POST("/create", middleware.MaxBodySize(cfg.CreateBodyLimit), handlers.CreateContentHandler)
+			content.POST("/uploads", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.StartUploadHandler)
+			content.PUT("/uploads/:upload_id/chunks/:index", middleware.MaxBodySize(cfg.CreateBodyLimit), handlers.UploadChunkHandler)
+			content.GET("/uploads/:upload_id/chunks/status", handlers.UploadStatusHandler)
+			content.POST("/uploads/:upload_id/complete", middleware.MaxBodySize(cfg.CreateBodyLimit), handlers.CompleteUploadHandler)
+			content.DELETE("/uploads/:upload_id", handlers.AbortUploadHandler)
 			content.GET("/list", handlers.ListContentHandler)
-			content.POST("/decrypt", handlers.DecryptContentHandler)
+			content.GET("/archived", handlers.ListArchivedContentHandler)
+			content.GET("/search", handlers.SearchContentHandler)
+			content.GET("/summary", handlers.ContentSummaryHandler)
+			content.GET("/count", handlers.CountContentHandler)
+			content.GET("/trash", handlers.ListTrashHandler)
+			content.POST("/move", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.MoveContentHandler)
+			content.POST("/bulk-tag", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.BulkTagHandler)
+			content.GET("/folders", handlers.ListFoldersHandler)
+			content.POST("/folders", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.CreateFolderHandler)
+			content.GET("/labels", handlers.ListLabelsHandler)
+			content.POST("/labels", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.CreateLabelHandler)
+			content.DELETE("/labels/:label_id", handlers.DeleteLabelHandler)
+			content.POST("/decrypt", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.DecryptContentHandler)
+			content.POST("/import", middleware.MaxBodySize(cfg.ImportBodyLimit), handlers.ImportContentHandler)
+			content.POST("/import/stream", middleware.MaxBodySize(cfg.ImportBodyLimit), handlers.ImportContentStreamHandler)
+			content.POST("/import/preflight", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.ImportPreflightHandler)
+			content.GET("/export", handlers.ExportContentHandler)
+			content.GET("/by-key/:key_id", handlers.ListContentByKeyHandler)
+			content.POST("/transfer", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.TransferContentHandler)
+			content.DELETE("/shares/:token", handlers.RevokeShareLinkHandler)
+			content.DELETE("/shares/by-id/:share_id", handlers.RevokeShareLinkByIDHandler)
 			content.GET("/:id", handlers.GetContentDetailHandler)
+			content.PUT("/:id", middleware.MaxBodySize(cfg.CreateBodyLimit), handlers.UpdateContentHandler)
+			content.DELETE("/:id", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.DeleteContentHandler)
+			content.POST("/:id/restore", handlers.RestoreContentHandler)
+			content.GET("/:id/revisions", handlers.ListRevisionsHandler)
+			content.POST("/:id/restore/:version", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.RestoreRevisionHandler)
+			content.POST("/:id/archive", handlers.ArchiveContentHandler)
+			content.POST("/:id/unarchive", handlers.UnarchiveContentHandler)
+			content.GET("/:id/decrypt-challenge", handlers.DecryptChallengeHandler)
+			content.GET("/:id/access-log", handlers.AccessLogHandler)
+			content.PUT("/:id/label", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.SetContentLabelHandler)
+			content.POST("/:id/shares", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.CreateShareLinkHandler)
+			content.GET("/:id/shares", handlers.ListShareLinksHandler)
+			content.POST("/:id/recipients", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.AddRecipientHandler)
+			content.POST("/:id/share", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.ShareContentHandler)
+			content.GET("/:id/recipients", handlers.ListRecipientsHandler)
+			content.DELETE("/:id/recipients/:address", handlers.RemoveRecipientHandler)
 		}
 
-		// 健康检查
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(200, gin.H{"status": "ok"})
-		})
+		// 管理员接口
+		admin := api.Group("/admin", middleware.RequireAuth(), middleware.RequireAdmin(cfg.AdminAddresses))
+		{
+			admin.GET("/shares", handlers.AdminListShareLinksHandler)
+			admin.DELETE("/shares/:id", handlers.AdminRevokeShareLinkHandler)
+			admin.GET("/users/:address", handlers.AdminUserInfoHandler)
+			admin.DELETE("/users/:address", handlers.AdminPurgeUserHandler)
+			admin.PUT("/users/:address/session-policy", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.AdminSetSessionPolicyHandler)
+			admin.PUT("/users/:address/quota", middleware.MaxBodySize(cfg.AuthBodyLimit), handlers.AdminSetUserQuotaHandler)
+			admin.GET("/activity", handlers.AdminActivityHandler)
+			admin.GET("/stream", handlers.AdminStreamHandler)
+			admin.GET("/stats", handlers.AdminStatsHandler)
+			admin.POST("/webhook/test", handlers.AdminTestWebhookHandler)
+			admin.GET("/lockdown", handlers.AdminLockdownHandler)
+			admin.PUT("/lockdown", handlers.AdminSetLockdownHandler)
+		}
+
+		// 健康检查：/health 探测数据库连接，/health/live 仅检查进程存活
+		api.GET("/health", handlers.HealthHandler)
+		api.GET("/health/live", handlers.LivenessHandler)
 	}
 
 	// 启动服务器
-	log.Println("VaultSeed backend server starting on :8080")
-	if err := r.Run(":8080"); err != nil {
+	srv := &http.Server{Addr: cfg.ListenAddr, Handler: r}
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Println("VaultSeed backend server starting on", cfg.ListenAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
 		log.Fatal("Failed to start server:", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// 停止接收新连接，等待进行中的请求完成，避免 nonce 轮换等写入中途被打断
+	log.Println("Shutting down, waiting up to", cfg.ShutdownTimeout, "for in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("HTTP server did not shut down cleanly:", err)
+	} else {
+		log.Println("HTTP server stopped")
+	}
+
+	if err := database.Close(); err != nil {
+		log.Println("Failed to close database:", err)
+	} else {
+		log.Println("Database connections closed")
 	}
 }
@@ -0,0 +1,835 @@
+package config
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+)
+
+// Config 服务配置，启动时从环境变量（及可选的配置文件）加载并校验
+type Config struct {
+	// 输出调试日志
+	Debug bool // DEBUG
+
+	// HTTP 监听地址，如 :8080 或 127.0.0.1:9090
+	ListenAddr string // LISTEN_ADDR
+
+	// 允许跨域访问的来源，如 https://app.example.com；为空时允许所有来源（仅适合开发环境）
+	CORSAllowedOrigins []string // CORS_ALLOWED_ORIGINS，逗号分隔
+
+	// 收到 SIGINT/SIGTERM 后等待进行中请求完成的最长时间
+	ShutdownTimeout time.Duration // SHUTDOWN_TIMEOUT
+
+	// Gin 运行模式：release（默认）、debug 或 test
+	GinMode string // GIN_MODE
+
+	// 签名消息中显示的应用名称（白标部署可自定义）
+	AppName string // APP_NAME
+
+	// 服务端签名私钥（十六进制），用于签发删除证明，为空时不签发
+	ServerSigningKey string // SERVER_SIGNING_KEY
+
+	// 标题托管主密钥（32 字节十六进制），配置后用户可选择托管标题密钥以便服务端搜索加密标题
+	// 该密钥与数据库同时泄露时，所有开启托管用户的标题均可被解密
+	TitleEscrowKey string // TITLE_ESCROW_KEY
+
+	// 数据库
+	DatabaseDriver     string        // DB_DRIVER，sqlite（默认）或 postgres
+	DatabaseDSN        string        // DB_DSN，postgres 连接串；sqlite 时为空则使用 DB_PATH
+	DatabasePath       string        // DB_PATH
+	ReplicaDatabaseURL string        // REPLICA_DATABASE_URL，可选的只读副本
+	ReplicaLagWindow   time.Duration // REPLICA_LAG_WINDOW，写入后该时间内读请求仍走主库
+
+	// 请求体大小上限（字节），DefaultBodyLimit 用于未单独设置上限的 /api 路由
+	DefaultBodyLimit int64 // MAX_REQUEST_BODY
+	AuthBodyLimit    int64 // AUTH_BODY_LIMIT
+	CreateBodyLimit  int64 // CREATE_BODY_LIMIT，未设置时由 MAX_ENCRYPTED_DATA_SIZE 推算（见 CreateBodyLimitFor）
+	ImportBodyLimit  int64 // IMPORT_BODY_LIMIT
+
+	// 单条内容 encrypted_data 解码后的最大字节数
+	MaxEncryptedDataSize int64 // MAX_ENCRYPTED_DATA_SIZE
+
+	// 管理员地址
+	AdminAddresses []string // ADMIN_ADDRESSES，逗号分隔
+
+	// 登录 nonce 有效期，超过后必须重新获取
+	LoginNonceTTL time.Duration // LOGIN_NONCE_TTL
+
+	// 接受 Sign-In with Ethereum（EIP-4361）登录消息时要求的 domain（如 app.example.com），为空时只接受旧格式消息
+	SIWEDomain string // SIWE_DOMAIN
+
+	// 本服务所在链的 ID，EIP-712 解密授权签名的域必须使用该链
+	ChainID uint64 // CHAIN_ID，默认 1（以太坊主网）
+
+	// 解密 nonce
+	DecryptNonceTTL   time.Duration // DECRYPT_NONCE_TTL
+	DecryptNonceGrace time.Duration // DECRYPT_NONCE_GRACE，0 表示关闭宽限
+
+	// 不活跃用户的登录 nonce 自动轮换
+	NonceRotationMaxAge   time.Duration // NONCE_ROTATION_MAX_AGE，超过该时长的 nonce 会被轮换，0 表示关闭
+	NonceRotationInterval time.Duration // NONCE_ROTATION_INTERVAL，轮换任务的执行间隔
+
+	// nonce 重放检测
+	NonceReuseAlertThreshold int           // NONCE_REUSE_ALERT_THRESHOLD
+	NonceReuseLockout        time.Duration // NONCE_REUSE_LOCKOUT，0 表示不锁定
+
+	// 告警 webhook
+	WebhookURL    string // WEBHOOK_URL
+	WebhookSecret string // WEBHOOK_SECRET
+
+	// 审计事件转发到 SIEM（异步、带缓冲和重试），两类接收端均可选
+	SIEMSyslogAddr string // SIEM_SYSLOG_ADDR，RFC 5424 over TCP，host:port
+	SIEMSyslogTLS  bool   // SIEM_SYSLOG_TLS
+	SIEMHTTPURL    string // SIEM_HTTP_URL，通用 HTTP 收集端
+	SIEMBufferSize int    // SIEM_BUFFER_SIZE
+
+	// 认证接口按客户端 IP 限流（令牌桶）
+	AuthRateLimit  int           // AUTH_RATE_LIMIT，每个窗口允许的请求数，0 表示不限流
+	AuthRateWindow time.Duration // AUTH_RATE_WINDOW
+
+	// 受信任的反向代理（IP 或 CIDR），只有来自这些地址的请求才采信 X-Forwarded-For；为空时客户端 IP 取 TCP 对端地址
+	TrustedProxies []string // TRUSTED_PROXIES，逗号分隔
+
+	// /metrics 中用户数、内容数仪表的刷新间隔
+	MetricsRefreshInterval time.Duration // METRICS_REFRESH_INTERVAL
+
+	// 导出限制
+	ExportCooldown      time.Duration // EXPORT_COOLDOWN，同一用户两次导出的最小间隔
+	ExportMaxConcurrent int           // EXPORT_MAX_CONCURRENT，全局同时进行的导出数
+
+	// 同一 encrypted_key 下 IV 重用的处理方式：off、warn 或 reject
+	IVReusePolicy string // IV_REUSE_POLICY
+
+	// 分享链接二维码中使用的地址前缀，token 追加在其后；为空时 release 模式下二维码接口不可用，其他模式下使用本服务的分享接口
+	ShareURLBase string // SHARE_URL_BASE
+
+	// 每条内容最多可带的标签数
+	MaxTagsPerContent int // MAX_TAGS_PER_CONTENT
+
+	// 每条内容最多可添加的接收者数
+	MaxRecipientsPerContent int // MAX_RECIPIENTS_PER_CONTENT
+
+	// 分块上传：单次上传的最大分块数，以及未完成的上传保留多久
+	MaxUploadChunks int           // MAX_UPLOAD_CHUNKS
+	UploadTTL       time.Duration // UPLOAD_TTL
+	// 分块上传拼接后 encrypted_data 解码后的最大字节数，独立于 MAX_ENCRYPTED_DATA_SIZE，不得小于它
+	MaxUploadSize int64 // MAX_UPLOAD_SIZE
+	// 单个分块 data 字段的最大长度（编码后的字节数），须小于 CREATE_BODY_LIMIT
+	MaxUploadChunkSize int64 // MAX_UPLOAD_CHUNK_SIZE
+	// 每个用户同时进行（未完成且未过期）的分块上传数
+	MaxConcurrentUploads int // MAX_CONCURRENT_UPLOADS
+
+	// 每条内容保留的历史版本数，为 0 时不保留历史
+	MaxContentRevisions int // MAX_CONTENT_REVISIONS
+
+	// 导入限制
+	ImportBatchSize int // IMPORT_BATCH_SIZE，每批写入的条数
+	ImportMaxItems  int // IMPORT_MAX_ITEMS，单次导入的最大条数
+
+	// 用户配额，0 表示不限制
+	MaxContentPerUser int64 // MAX_CONTENT_PER_USER，内容条数
+	MaxStoragePerUser int64 // MAX_STORAGE_PER_USER，密文总字节数（含回收站），创建、更新、导入和恢复版本时检查
+
+	// 以太坊 RPC 与区块新鲜度校验
+	EthRPCURL             string // ETH_RPC_URL
+	RequireBlockFreshness bool   // REQUIRE_BLOCK_FRESHNESS，登录签名须引用最近的区块
+	BlockFreshnessWindow  uint64 // BLOCK_FRESHNESS_WINDOW，允许的最大区块落后数
+
+	// 是否接受不带 V 的 64 字节旧式签名（依次尝试两个恢复位）
+	AllowShortSignatures bool // ALLOW_SHORT_SIGNATURES
+
+	// 访问令牌（JWT，HS256）签名密钥与有效期；密钥为空时每次启动随机生成
+	JWTSecret string        // JWT_SECRET
+	JWTTTL    time.Duration // JWT_TTL
+
+	// 管理员实时视图推送统计快照的间隔
+	AdminStreamInterval time.Duration // ADMIN_STREAM_INTERVAL
+
+	// 敏感操作二次认证：列出的操作须附带 REAUTH_WINDOW 内签发的签名，export 无论是否列出都必须二次认证
+	ReauthOperations []string      // REAUTH_OPERATIONS，可选 export、rotate-key、transfer、purge-user、title-escrow
+	ReauthWindow     time.Duration // REAUTH_WINDOW
+
+	// ECDSA 校验失败时是否按 EIP-1271 询问合约钱包（需要 ETH_RPC_URL，未配置时跳过）
+	AllowContractSignatures bool // ALLOW_CONTRACT_SIGNATURES
+
+	// 各签名操作接受的签名方案（personal_sign、eip1271、eip712），未列出的操作接受全部方案
+	SignatureSchemes map[string][]string // SIGNATURE_SCHEMES，如 "delete:personal_sign;transfer:personal_sign"
+
+	// 启动时强制进入紧急锁定（锁定状态本身持久化在数据库中，由管理员接口解除）
+	Lockdown bool // LOCKDOWN
+
+	// ENS 解析结果缓存有效期（需要 ETH_RPC_URL）
+	ENSCacheTTL time.Duration // ENS_CACHE_TTL
+
+	// 各内容类型必须提供的（加密）元数据字段
+	ContentTypeFields map[string][]string // CONTENT_TYPE_FIELDS，如 "file:filename,mime;password:username"
+}
+
+// maxAppNameLength 应用名称的最大长度（字符数）
+const maxAppNameLength = 64
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		ListenAddr:       ":8080",
+		ShutdownTimeout:  15 * time.Second,
+		GinMode:          gin.ReleaseMode,
+		AppName:          "VaultSeed",
+		DatabaseDriver:   "sqlite",
+		DatabasePath:     "vaultseed.db",
+		ReplicaLagWindow: 5 * time.Second,
+
+		DefaultBodyLimit: 4 << 20,
+		AuthBodyLimit:    16 << 10,
+		CreateBodyLimit:  CreateBodyLimitFor(1 << 20),
+		ImportBodyLimit:  50 << 20,
+
+		MaxEncryptedDataSize: 1 << 20,
+
+		LoginNonceTTL: 5 * time.Minute,
+		ChainID:       1,
+
+		DecryptNonceTTL:   5 * time.Minute,
+		DecryptNonceGrace: 30 * time.Second,
+
+		NonceRotationInterval: time.Hour,
+
+		NonceReuseAlertThreshold: 3,
+
+		AuthRateLimit:  10,
+		AuthRateWindow: time.Minute,
+
+		MetricsRefreshInterval: time.Minute,
+
+		ExportCooldown:      time.Minute,
+		ExportMaxConcurrent: 4,
+
+		IVReusePolicy: "warn",
+
+		MaxTagsPerContent:       20,
+		MaxRecipientsPerContent: 50,
+		MaxContentRevisions:     10,
+
+		MaxUploadChunks:      1000,
+		UploadTTL:            24 * time.Hour,
+		MaxUploadSize:        16 << 20,
+		MaxUploadChunkSize:   512 << 10,
+		MaxConcurrentUploads: 3,
+
+		ImportBatchSize: 100,
+		ImportMaxItems:  10000,
+
+		BlockFreshnessWindow: 20,
+		ReauthWindow:         5 * time.Minute,
+		JWTTTL:               24 * time.Hour,
+		AdminStreamInterval:  10 * time.Second,
+		SIEMBufferSize:       1000,
+		ENSCacheTTL:          time.Hour,
+
+		ContentTypeFields: map[string][]string{
+			"file": {"filename"},
+		},
+	}
+}
+
+// Load 加载配置：环境变量优先，其次是 CONFIG_FILE 指定的 KEY=VALUE 文件，最后使用默认值
+func Load() (*Config, error) {
+	l := &loader{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+		}
+		l.file = values
+	}
+
+	cfg := Default()
+	cfg.Debug = l.bool("DEBUG", cfg.Debug)
+	cfg.ListenAddr = l.str("LISTEN_ADDR", cfg.ListenAddr)
+	cfg.CORSAllowedOrigins = l.list("CORS_ALLOWED_ORIGINS")
+	cfg.ShutdownTimeout = l.duration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout)
+	cfg.GinMode = strings.ToLower(l.str("GIN_MODE", cfg.GinMode))
+	cfg.AppName = l.str("APP_NAME", cfg.AppName)
+	cfg.ServerSigningKey = l.str("SERVER_SIGNING_KEY", cfg.ServerSigningKey)
+	cfg.TitleEscrowKey = l.str("TITLE_ESCROW_KEY", cfg.TitleEscrowKey)
+	cfg.DatabaseDriver = strings.ToLower(l.str("DB_DRIVER", cfg.DatabaseDriver))
+	cfg.DatabaseDSN = l.str("DB_DSN", cfg.DatabaseDSN)
+	cfg.DatabasePath = l.str("DB_PATH", cfg.DatabasePath)
+	cfg.ReplicaDatabaseURL = l.str("REPLICA_DATABASE_URL", cfg.ReplicaDatabaseURL)
+	cfg.ReplicaLagWindow = l.duration("REPLICA_LAG_WINDOW", cfg.ReplicaLagWindow)
+
+	cfg.DefaultBodyLimit = l.int64("MAX_REQUEST_BODY", cfg.DefaultBodyLimit)
+	cfg.AuthBodyLimit = l.int64("AUTH_BODY_LIMIT", cfg.AuthBodyLimit)
+	cfg.ImportBodyLimit = l.int64("IMPORT_BODY_LIMIT", cfg.ImportBodyLimit)
+
+	cfg.MaxEncryptedDataSize = l.int64("MAX_ENCRYPTED_DATA_SIZE", cfg.MaxEncryptedDataSize)
+	cfg.CreateBodyLimit = l.int64("CREATE_BODY_LIMIT", CreateBodyLimitFor(cfg.MaxEncryptedDataSize))
+
+	cfg.AdminAddresses = l.list("ADMIN_ADDRESSES")
+
+	cfg.LoginNonceTTL = l.duration("LOGIN_NONCE_TTL", cfg.LoginNonceTTL)
+	cfg.SIWEDomain = l.str("SIWE_DOMAIN", cfg.SIWEDomain)
+	cfg.ChainID = l.uint64("CHAIN_ID", cfg.ChainID)
+
+	cfg.DecryptNonceTTL = l.duration("DECRYPT_NONCE_TTL", cfg.DecryptNonceTTL)
+	cfg.DecryptNonceGrace = l.duration("DECRYPT_NONCE_GRACE", cfg.DecryptNonceGrace)
+
+	cfg.NonceRotationMaxAge = l.duration("NONCE_ROTATION_MAX_AGE", cfg.NonceRotationMaxAge)
+	cfg.NonceRotationInterval = l.duration("NONCE_ROTATION_INTERVAL", cfg.NonceRotationInterval)
+
+	cfg.NonceReuseAlertThreshold = l.int("NONCE_REUSE_ALERT_THRESHOLD", cfg.NonceReuseAlertThreshold)
+	cfg.NonceReuseLockout = l.duration("NONCE_REUSE_LOCKOUT", cfg.NonceReuseLockout)
+
+	cfg.WebhookURL = l.str("WEBHOOK_URL", cfg.WebhookURL)
+	cfg.WebhookSecret = l.str("WEBHOOK_SECRET", cfg.WebhookSecret)
+	cfg.SIEMSyslogAddr = l.str("SIEM_SYSLOG_ADDR", cfg.SIEMSyslogAddr)
+	cfg.SIEMSyslogTLS = l.bool("SIEM_SYSLOG_TLS", cfg.SIEMSyslogTLS)
+	cfg.SIEMHTTPURL = l.str("SIEM_HTTP_URL", cfg.SIEMHTTPURL)
+	cfg.SIEMBufferSize = l.int("SIEM_BUFFER_SIZE", cfg.SIEMBufferSize)
+
+	cfg.AuthRateLimit = l.int("AUTH_RATE_LIMIT", cfg.AuthRateLimit)
+	cfg.AuthRateWindow = l.duration("AUTH_RATE_WINDOW", cfg.AuthRateWindow)
+	cfg.TrustedProxies = l.list("TRUSTED_PROXIES")
+	cfg.MetricsRefreshInterval = l.duration("METRICS_REFRESH_INTERVAL", cfg.MetricsRefreshInterval)
+
+	cfg.ExportCooldown = l.duration("EXPORT_COOLDOWN", cfg.ExportCooldown)
+	cfg.ExportMaxConcurrent = l.int("EXPORT_MAX_CONCURRENT", cfg.ExportMaxConcurrent)
+
+	cfg.IVReusePolicy = l.str("IV_REUSE_POLICY", cfg.IVReusePolicy)
+
+	cfg.ShareURLBase = l.str("SHARE_URL_BASE", cfg.ShareURLBase)
+
+	cfg.MaxTagsPerContent = l.int("MAX_TAGS_PER_CONTENT", cfg.MaxTagsPerContent)
+	cfg.MaxRecipientsPerContent = l.int("MAX_RECIPIENTS_PER_CONTENT", cfg.MaxRecipientsPerContent)
+	cfg.MaxContentRevisions = l.int("MAX_CONTENT_REVISIONS", cfg.MaxContentRevisions)
+
+	cfg.MaxUploadChunks = l.int("MAX_UPLOAD_CHUNKS", cfg.MaxUploadChunks)
+	cfg.UploadTTL = l.duration("UPLOAD_TTL", cfg.UploadTTL)
+	cfg.MaxUploadSize = l.int64("MAX_UPLOAD_SIZE", cfg.MaxUploadSize)
+	cfg.MaxUploadChunkSize = l.int64("MAX_UPLOAD_CHUNK_SIZE", cfg.MaxUploadChunkSize)
+	cfg.MaxConcurrentUploads = l.int("MAX_CONCURRENT_UPLOADS", cfg.MaxConcurrentUploads)
+
+	cfg.ImportBatchSize = l.int("IMPORT_BATCH_SIZE", cfg.ImportBatchSize)
+	cfg.ImportMaxItems = l.int("IMPORT_MAX_ITEMS", cfg.ImportMaxItems)
+
+	cfg.MaxContentPerUser = l.int64("MAX_CONTENT_PER_USER", cfg.MaxContentPerUser)
+	cfg.MaxStoragePerUser = l.int64("MAX_STORAGE_PER_USER", cfg.MaxStoragePerUser)
+
+	cfg.EthRPCURL = l.str("ETH_RPC_URL", cfg.EthRPCURL)
+	cfg.RequireBlockFreshness = l.bool("REQUIRE_BLOCK_FRESHNESS", cfg.RequireBlockFreshness)
+	cfg.AllowContractSignatures = l.bool("ALLOW_CONTRACT_SIGNATURES", cfg.AllowContractSignatures)
+	cfg.Lockdown = l.bool("LOCKDOWN", cfg.Lockdown)
+	cfg.ENSCacheTTL = l.duration("ENS_CACHE_TTL", cfg.ENSCacheTTL)
+	cfg.BlockFreshnessWindow = uint64(l.int64("BLOCK_FRESHNESS_WINDOW", int64(cfg.BlockFreshnessWindow)))
+
+	cfg.AllowShortSignatures = l.bool("ALLOW_SHORT_SIGNATURES", cfg.AllowShortSignatures)
+
+	cfg.JWTSecret = l.str("JWT_SECRET", cfg.JWTSecret)
+	cfg.JWTTTL = l.duration("JWT_TTL", cfg.JWTTTL)
+
+	cfg.AdminStreamInterval = l.duration("ADMIN_STREAM_INTERVAL", cfg.AdminStreamInterval)
+
+	cfg.ReauthOperations = l.list("REAUTH_OPERATIONS")
+	cfg.ReauthWindow = l.duration("REAUTH_WINDOW", cfg.ReauthWindow)
+
+	cfg.ContentTypeFields = l.fieldMap("CONTENT_TYPE_FIELDS", cfg.ContentTypeFields)
+	cfg.SignatureSchemes = l.fieldMap("SIGNATURE_SCHEMES", cfg.SignatureSchemes)
+
+	if len(l.errs) > 0 {
+		return nil, errors.New(strings.Join(l.errs, "; "))
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 校验配置取值
+func (c *Config) Validate() error {
+	var errs []string
+
+	if strings.TrimSpace(c.ListenAddr) == "" {
+		errs = append(errs, "LISTEN_ADDR must not be empty")
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, "SHUTDOWN_TIMEOUT must be positive")
+	}
+	switch c.GinMode {
+	case gin.ReleaseMode, gin.DebugMode, gin.TestMode:
+	default:
+		errs = append(errs, "GIN_MODE must be release, debug or test")
+	}
+	if strings.TrimSpace(c.AppName) == "" {
+		errs = append(errs, "APP_NAME must not be empty")
+	} else if utf8.RuneCountInString(c.AppName) > maxAppNameLength {
+		errs = append(errs, fmt.Sprintf("APP_NAME must be at most %d characters", maxAppNameLength))
+	}
+	if c.ServerSigningKey != "" {
+		if _, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimPrefix(c.ServerSigningKey, "0x"), "0X")); err != nil {
+			errs = append(errs, "SERVER_SIGNING_KEY must be a hex-encoded secp256k1 private key")
+		}
+	}
+	if c.TitleEscrowKey != "" {
+		if key, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(c.TitleEscrowKey, "0x"), "0X")); err != nil || len(key) != 32 {
+			errs = append(errs, "TITLE_ESCROW_KEY must be 32 bytes of hex")
+		}
+	}
+	switch c.DatabaseDriver {
+	case "sqlite":
+		if c.DatabaseDSN == "" && c.DatabasePath == "" {
+			errs = append(errs, "DB_PATH must not be empty")
+		}
+	case "postgres":
+		if c.DatabaseDSN == "" {
+			errs = append(errs, "DB_DSN is required when DB_DRIVER=postgres")
+		}
+	default:
+		errs = append(errs, "DB_DRIVER must be sqlite or postgres")
+	}
+	if c.ReplicaLagWindow < 0 {
+		errs = append(errs, "REPLICA_LAG_WINDOW must not be negative")
+	}
+	if c.DefaultBodyLimit <= 0 {
+		errs = append(errs, "MAX_REQUEST_BODY must be positive")
+	}
+	if c.AuthBodyLimit <= 0 {
+		errs = append(errs, "AUTH_BODY_LIMIT must be positive")
+	}
+	if c.CreateBodyLimit <= 0 {
+		errs = append(errs, "CREATE_BODY_LIMIT must be positive")
+	}
+	if c.ImportBodyLimit <= 0 {
+		errs = append(errs, "IMPORT_BODY_LIMIT must be positive")
+	}
+	if c.MaxEncryptedDataSize <= 0 {
+		errs = append(errs, "MAX_ENCRYPTED_DATA_SIZE must be positive")
+	} else if c.CreateBodyLimit < CreateBodyLimitFor(c.MaxEncryptedDataSize) {
+		errs = append(errs, fmt.Sprintf("CREATE_BODY_LIMIT must be at least %d so that MAX_ENCRYPTED_DATA_SIZE is reachable with base64 encoding", CreateBodyLimitFor(c.MaxEncryptedDataSize)))
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if !validOrigin(origin) {
+			errs = append(errs, fmt.Sprintf("CORS_ALLOWED_ORIGINS contains invalid origin %q", origin))
+		}
+	}
+	for _, addr := range c.AdminAddresses {
+		if !common.IsHexAddress(addr) {
+			errs = append(errs, fmt.Sprintf("ADMIN_ADDRESSES contains invalid address %q", addr))
+		}
+	}
+	if c.LoginNonceTTL <= 0 {
+		errs = append(errs, "LOGIN_NONCE_TTL must be positive")
+	}
+	// SIWE 的 domain 是 host[:port]，不带协议和路径
+	if c.SIWEDomain != "" && strings.ContainsAny(c.SIWEDomain, "/ \t") {
+		errs = append(errs, "SIWE_DOMAIN must be a host[:port] without scheme or path")
+	}
+	if c.ChainID == 0 {
+		errs = append(errs, "CHAIN_ID must be positive")
+	}
+	if c.DecryptNonceTTL <= 0 {
+		errs = append(errs, "DECRYPT_NONCE_TTL must be positive")
+	}
+	if c.DecryptNonceGrace < 0 {
+		errs = append(errs, "DECRYPT_NONCE_GRACE must not be negative")
+	}
+	if c.NonceRotationMaxAge < 0 {
+		errs = append(errs, "NONCE_ROTATION_MAX_AGE must not be negative")
+	} else if c.NonceRotationMaxAge > 0 && c.NonceRotationMaxAge < time.Minute {
+		errs = append(errs, "NONCE_ROTATION_MAX_AGE must be at least 1m")
+	}
+	if c.NonceRotationInterval <= 0 {
+		errs = append(errs, "NONCE_ROTATION_INTERVAL must be positive")
+	}
+	if c.NonceReuseAlertThreshold < 1 {
+		errs = append(errs, "NONCE_REUSE_ALERT_THRESHOLD must be at least 1")
+	}
+	if c.NonceReuseLockout < 0 {
+		errs = append(errs, "NONCE_REUSE_LOCKOUT must not be negative")
+	}
+	if c.AuthRateLimit < 0 {
+		errs = append(errs, "AUTH_RATE_LIMIT must not be negative")
+	}
+	if c.AuthRateWindow <= 0 {
+		errs = append(errs, "AUTH_RATE_WINDOW must be positive")
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Sprintf("TRUSTED_PROXIES contains invalid IP or CIDR %q", proxy))
+			}
+		}
+	}
+	if c.MetricsRefreshInterval <= 0 {
+		errs = append(errs, "METRICS_REFRESH_INTERVAL must be positive")
+	}
+	if c.ExportCooldown < 0 {
+		errs = append(errs, "EXPORT_COOLDOWN must not be negative")
+	}
+	if c.ExportMaxConcurrent < 1 {
+		errs = append(errs, "EXPORT_MAX_CONCURRENT must be at least 1")
+	}
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, "WEBHOOK_URL must be an http(s) URL")
+		}
+	}
+	if c.SIEMSyslogAddr != "" {
+		if _, _, err := net.SplitHostPort(c.SIEMSyslogAddr); err != nil {
+			errs = append(errs, "SIEM_SYSLOG_ADDR must be host:port")
+		}
+	}
+	if c.SIEMHTTPURL != "" {
+		if u, err := url.Parse(c.SIEMHTTPURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, "SIEM_HTTP_URL must be an http(s) URL")
+		}
+	}
+	if c.SIEMBufferSize < 1 {
+		errs = append(errs, "SIEM_BUFFER_SIZE must be at least 1")
+	}
+	switch c.IVReusePolicy {
+	case "off", "warn", "reject":
+	default:
+		errs = append(errs, "IV_REUSE_POLICY must be one of off, warn, reject")
+	}
+	if c.MaxTagsPerContent < 1 {
+		errs = append(errs, "MAX_TAGS_PER_CONTENT must be at least 1")
+	}
+	if c.MaxRecipientsPerContent < 1 {
+		errs = append(errs, "MAX_RECIPIENTS_PER_CONTENT must be at least 1")
+	}
+	if c.MaxContentRevisions < 0 {
+		errs = append(errs, "MAX_CONTENT_REVISIONS must not be negative")
+	}
+	if c.MaxUploadChunks < 1 {
+		errs = append(errs, "MAX_UPLOAD_CHUNKS must be at least 1")
+	}
+	if c.UploadTTL < time.Minute {
+		errs = append(errs, "UPLOAD_TTL must be at least 1m")
+	}
+	if c.MaxUploadSize < c.MaxEncryptedDataSize {
+		errs = append(errs, "MAX_UPLOAD_SIZE must not be smaller than MAX_ENCRYPTED_DATA_SIZE")
+	}
+	if c.MaxUploadChunkSize <= 0 || c.MaxUploadChunkSize >= c.CreateBodyLimit {
+		errs = append(errs, "MAX_UPLOAD_CHUNK_SIZE must be positive and smaller than CREATE_BODY_LIMIT")
+	}
+	if c.MaxConcurrentUploads < 1 {
+		errs = append(errs, "MAX_CONCURRENT_UPLOADS must be at least 1")
+	}
+	if c.ImportBatchSize < 1 {
+		errs = append(errs, "IMPORT_BATCH_SIZE must be at least 1")
+	}
+	if c.ImportMaxItems < 1 {
+		errs = append(errs, "IMPORT_MAX_ITEMS must be at least 1")
+	}
+	if c.MaxContentPerUser < 0 {
+		errs = append(errs, "MAX_CONTENT_PER_USER must not be negative")
+	}
+	if c.MaxStoragePerUser < 0 {
+		errs = append(errs, "MAX_STORAGE_PER_USER must not be negative")
+	}
+	if c.ShareURLBase != "" {
+		if u, err := url.Parse(c.ShareURLBase); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, "SHARE_URL_BASE must be an http(s) URL")
+		}
+	}
+	if c.EthRPCURL != "" {
+		if u, err := url.Parse(c.EthRPCURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, "ETH_RPC_URL must be an http(s) URL")
+		}
+	}
+	if c.ENSCacheTTL <= 0 {
+		errs = append(errs, "ENS_CACHE_TTL must be positive")
+	}
+	if c.RequireBlockFreshness {
+		if c.EthRPCURL == "" {
+			errs = append(errs, "REQUIRE_BLOCK_FRESHNESS requires ETH_RPC_URL")
+		}
+		if c.BlockFreshnessWindow < 1 {
+			errs = append(errs, "BLOCK_FRESHNESS_WINDOW must be at least 1")
+		}
+	}
+	if c.JWTSecret != "" && len(c.JWTSecret) < 32 {
+		errs = append(errs, "JWT_SECRET must be at least 32 bytes")
+	}
+	if c.JWTTTL <= 0 {
+		errs = append(errs, "JWT_TTL must be positive")
+	}
+	if c.AdminStreamInterval < time.Second {
+		errs = append(errs, "ADMIN_STREAM_INTERVAL must be at least 1s")
+	}
+	for _, op := range c.ReauthOperations {
+		if !ReauthOperationKnown(op) {
+			errs = append(errs, fmt.Sprintf("REAUTH_OPERATIONS contains unknown operation %q", op))
+		}
+	}
+	if c.ReauthWindow <= 0 {
+		errs = append(errs, "REAUTH_WINDOW must be positive")
+	}
+	for op, schemes := range c.SignatureSchemes {
+		if !knownName(signatureOperations, op) {
+			errs = append(errs, fmt.Sprintf("SIGNATURE_SCHEMES contains unknown operation %q", op))
+		}
+		if len(schemes) == 0 {
+			errs = append(errs, fmt.Sprintf("SIGNATURE_SCHEMES must list at least one scheme for %q", op))
+		}
+		for _, scheme := range schemes {
+			if !knownName(signatureSchemes, scheme) {
+				errs = append(errs, fmt.Sprintf("SIGNATURE_SCHEMES contains unknown scheme %q", scheme))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// reauthOperations 支持二次认证的敏感操作
+var reauthOperations = []string{"export", "rotate-key", "transfer", "purge-user", "title-escrow"}
+
+// ReauthOperationKnown 判断操作名是否受支持
+func ReauthOperationKnown(op string) bool {
+	return knownName(reauthOperations, op)
+}
+
+// mandatoryReauthOperations 始终需要二次认证的操作：导出一次返回全部密文，被盗的访问令牌不能单独完成导出
+var mandatoryReauthOperations = []string{"export"}
+
+// RequiresReauth 判断操作是否需要二次认证（始终需要或已配置）
+func (c *Config) RequiresReauth(op string) bool {
+	if knownName(mandatoryReauthOperations, op) {
+		return true
+	}
+	for _, configured := range c.ReauthOperations {
+		if configured == op {
+			return true
+		}
+	}
+	return false
+}
+
+// 签名方案
+const (
+	SchemePersonalSign = "personal_sign" // EOA 的 ECDSA personal_sign 签名
+	SchemeEIP1271      = "eip1271"       // 合约钱包按 EIP-1271 确认的签名
+	SchemeEIP712       = "eip712"        // EOA 的 EIP-712 结构化签名（eth_signTypedData_v4），目前仅用于 decrypt
+)
+
+// signatureSchemes 支持的签名方案
+var signatureSchemes = []string{SchemePersonalSign, SchemeEIP1271, SchemeEIP712}
+
+// signatureOperations 需要钱包签名、可单独限定签名方案的操作
+var signatureOperations = []string{
+	"login", "register-key", "rotate-key", "decrypt", "update", "restore-version",
+	"delete", "transfer", "reset-nonce", "reauth",
+}
+
+// AcceptsSignatureScheme 判断操作是否接受指定签名方案，未配置的操作接受全部方案
+func (c *Config) AcceptsSignatureScheme(op, scheme string) bool {
+	schemes, ok := c.SignatureSchemes[op]
+	if !ok {
+		return true
+	}
+	return knownName(schemes, scheme)
+}
+
+// knownName 判断 name 是否在 names 中
+func knownName(names []string, name string) bool {
+	for _, known := range names {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// validOrigin 来源须为 http(s)://host[:port]，不带路径
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.User == nil
+}
+
+// loader 读取配置项并收集解析错误
+type loader struct {
+	file map[string]string
+	errs []string
+}
+
+func (l *loader) lookup(key string) (string, bool) {
+	if v, ok := os.LookupEnv(key); ok {
+		return v, true
+	}
+	v, ok := l.file[key]
+	return v, ok
+}
+
+func (l *loader) str(key, def string) string {
+	if v, ok := l.lookup(key); ok {
+		return strings.TrimSpace(v)
+	}
+	return def
+}
+
+func (l *loader) int(key string, def int) int {
+	v, ok := l.lookup(key)
+	if !ok || v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s: invalid integer %q", key, v))
+		return def
+	}
+	return n
+}
+
+func (l *loader) int64(key string, def int64) int64 {
+	v, ok := l.lookup(key)
+	if !ok || v == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s: invalid integer %q", key, v))
+		return def
+	}
+	return n
+}
+
+func (l *loader) uint64(key string, def uint64) uint64 {
+	v, ok := l.lookup(key)
+	if !ok || v == "" {
+		return def
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s: invalid integer %q", key, v))
+		return def
+	}
+	return n
+}
+
+func (l *loader) bool(key string, def bool) bool {
+	v, ok := l.lookup(key)
+	if !ok || v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s: invalid boolean %q", key, v))
+		return def
+	}
+	return b
+}
+
+func (l *loader) duration(key string, def time.Duration) time.Duration {
+	v, ok := l.lookup(key)
+	if !ok || v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		l.errs = append(l.errs, fmt.Sprintf("%s: invalid duration %q", key, v))
+		return def
+	}
+	return d
+}
+
+func (l *loader) list(key string) []string {
+	v, ok := l.lookup(key)
+	if !ok {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// fieldMap 解析 "type:field1,field2;type2:field3" 格式的映射
+func (l *loader) fieldMap(key string, def map[string][]string) map[string][]string {
+	v, ok := l.lookup(key)
+	if !ok {
+		return def
+	}
+	m := make(map[string][]string)
+	for _, entry := range strings.Split(v, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, fields, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			l.errs = append(l.errs, fmt.Sprintf("%s: invalid entry %q, expected type:field1,field2", key, entry))
+			continue
+		}
+		m[name] = nil
+		for _, field := range strings.Split(fields, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				m[name] = append(m[name], field)
+			}
+		}
+	}
+	return m
+}
+
+// readConfigFile 读取 KEY=VALUE 格式的配置文件，忽略空行和 # 注释
+func readConfigFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return values, scanner.Err()
+}
+
+// createBodyOverhead 创建/更新请求中 encrypted_data 以外字段（标题、密钥、元数据、标签等）预留的字节数
+const createBodyOverhead = 64 << 10
+
+// CreateBodyLimitFor 能容纳 maxDataSize 字节密文的创建/更新请求体上限：base64 编码约放大 4/3，再加上其他字段
+// 十六进制编码的密文放大一倍，此时实际可提交的密文约为 MAX_ENCRYPTED_DATA_SIZE 的 2/3
+func CreateBodyLimitFor(maxDataSize int64) int64 {
+	return (maxDataSize+2)/3*4 + createBodyOverhead
+}
+
+// DatabaseSource 主库连接串：postgres 使用 DB_DSN，sqlite 优先使用 DB_DSN，否则使用 DB_PATH
+func (c *Config) DatabaseSource() string {
+	if c.DatabaseDSN != "" {
+		return c.DatabaseDSN
+	}
+	return c.DatabasePath
+}
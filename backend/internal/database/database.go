@@ -1,36 +1,174 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"vaultseed-backend/internal/config"
 	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
 
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
+// migratedModels 需要自动迁移的表
+var migratedModels = []interface{}{
+	&models.User{},
+	&models.UserKey{},
+	&models.EncryptedContent{},
+	&models.ShareLink{},
+	&models.ContentRecipient{},
+	&models.ContentRevision{},
+	&models.DecryptChallenge{},
+	&models.DecryptLog{},
+	&models.Upload{},
+	&models.UploadChunk{},
+	&models.Tag{},
+	&models.ContentTag{},
+	&models.Folder{},
+	&models.Label{},
+	&models.UsedNonce{},
+	&models.AuditLog{},
+	&models.Setting{},
+}
+
+// openDialector 按 DB_DRIVER 选择 GORM 驱动，只读副本与主库使用同一驱动
+func openDialector(driver, dsn string) gorm.Dialector {
+	if driver == "postgres" {
+		return postgres.Open(dsn)
+	}
+	return sqlite.Open(dsn)
+}
+
 // InitDB 初始化数据库连接
-func InitDB() error {
+func InitDB(cfg *config.Config) error {
 	var err error
-	DB, err = gorm.Open(sqlite.Open("vaultseed.db"), &gorm.Config{})
+	DB, err = gorm.Open(openDialector(cfg.DatabaseDriver, cfg.DatabaseSource()), &gorm.Config{})
 	if err != nil {
 		return err
 	}
 
 	// 自动迁移表结构
-	err = DB.AutoMigrate(
-		&models.User{},
-		&models.EncryptedContent{},
-	)
+	err = DB.AutoMigrate(migratedModels...)
 	if err != nil {
 		return err
 	}
 
+	if err := backfillUserKeys(DB); err != nil {
+		return err
+	}
+	if err := backfillKeyIVHashes(DB); err != nil {
+		return err
+	}
+	if err := loadLockdown(DB); err != nil {
+		return err
+	}
+
+	// 只读副本（可选），表结构由主库迁移
+	if cfg.ReplicaDatabaseURL != "" {
+		ReadDB, err = gorm.Open(openDialector(cfg.DatabaseDriver, cfg.ReplicaDatabaseURL), &gorm.Config{})
+		if err != nil {
+			return err
+		}
+		replicaLagWindow = cfg.ReplicaLagWindow
+		log.Println("Read replica connected")
+	}
+
 	log.Println("Database connected and migrated successfully")
 	return nil
 }
 
+// backfillUserKeys 为已注册公钥但没有公钥历史的用户补建记录
+func backfillUserKeys(db *gorm.DB) error {
+	var users []models.User
+	err := db.Where("public_key <> '' AND address NOT IN (?)",
+		db.Model(&models.UserKey{}).Select("address")).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		key := models.UserKey{Address: user.Address, PublicKey: user.PublicKey, Active: true}
+		if err := db.Create(&key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillKeyIVHashes 为缺少 key_iv_hash 的历史内容补算哈希
+func backfillKeyIVHashes(db *gorm.DB) error {
+	var contents []models.EncryptedContent
+	return db.Unscoped().Select("id", "encrypted_key", "iv").Where("key_iv_hash = '' OR key_iv_hash IS NULL").
+		FindInBatches(&contents, 500, func(tx *gorm.DB, batch int) error {
+			for _, content := range contents {
+				if err := tx.Unscoped().Model(&models.EncryptedContent{}).Where("id = ?", content.ID).
+					Update("key_iv_hash", utils.HashKeyIV(content.EncryptedKey, content.IV)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// Ping 检查主库（及副本）连接
+func Ping(ctx context.Context) error {
+	for _, db := range []*gorm.DB{DB, ReadDB} {
+		if db == nil {
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		if err := sqlDB.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 关闭主库（及副本）连接，停机时在 HTTP 服务停止后调用
+func Close() error {
+	var errs []error
+	for _, db := range []*gorm.DB{DB, ReadDB} {
+		if db == nil {
+			continue
+		}
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckMigrations 确认所有表都已迁移
+func CheckMigrations() error {
+	for _, model := range migratedModels {
+		if !DB.Migrator().HasTable(model) {
+			return fmt.Errorf("table for %T is missing", model)
+		}
+	}
+	// 软删除依赖 deleted_at 列，缺失时所有删除都会变成永久删除
+	if !DB.Migrator().HasColumn(&models.EncryptedContent{}, "DeletedAt") {
+		return fmt.Errorf("column deleted_at on encrypted_contents is missing")
+	}
+	return nil
+}
+
+// Backend 主库所用的数据库类型，sqlite 或 postgres
+func Backend() string {
+	return DB.Dialector.Name()
+}
+
 // GetDB 获取数据库实例
 func GetDB() *gorm.DB {
 	return DB
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/models"
+)
+
+// postgresDSNEnv 设置后运行 PostgreSQL 集成测试，例如
+// VAULTSEED_TEST_POSTGRES_DSN="host=localhost user=vaultseed password=vaultseed dbname=vaultseed_test sslmode=disable"
+const postgresDSNEnv = "VAULTSEED_TEST_POSTGRES_DSN"
+
+func TestSQLiteMigrationAndRoundTrip(t *testing.T) {
+	cfg := config.Default()
+	cfg.DatabasePath = filepath.Join(t.TempDir(), "test.db")
+	testMigrationAndRoundTrip(t, cfg)
+}
+
+func TestPostgresMigrationAndRoundTrip(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skip(postgresDSNEnv + " not set")
+	}
+	cfg := config.Default()
+	cfg.DatabaseDriver = "postgres"
+	cfg.DatabaseDSN = dsn
+	testMigrationAndRoundTrip(t, cfg)
+}
+
+// testMigrationAndRoundTrip 迁移两次（重启时的重复迁移），并写入超过 varchar(255) 的 type:text 字段后原样读回
+func testMigrationAndRoundTrip(t *testing.T, cfg *config.Config) {
+	for i := 0; i < 2; i++ {
+		if err := InitDB(cfg); err != nil {
+			t.Fatalf("InitDB (run %d): %v", i+1, err)
+		}
+		if i == 0 {
+			Close()
+		}
+	}
+	t.Cleanup(func() { Close() })
+
+	// 每次运行使用不同的地址，共享的测试库中不会与历史数据冲突
+	address := "0xtest" + strings.ReplaceAll(time.Now().Format("150405.000000000"), ".", "")
+	data := strings.Repeat("QUJD", 20000)
+	content := models.EncryptedContent{
+		UserAddress:   address,
+		Title:         "entry",
+		ContentType:   "mnemonic",
+		Metadata:      `{"words":24}`,
+		EncryptedData: data,
+		EncryptedKey:  strings.Repeat("a", 600),
+		IV:            "MTIzNDU2Nzg5MDEy",
+		Nonce:         "nonce",
+		NonceIssuedAt: time.Now(),
+	}
+	if err := DB.Create(&content).Error; err != nil {
+		t.Fatalf("create content: %v", err)
+	}
+	t.Cleanup(func() { DB.Unscoped().Delete(&models.EncryptedContent{}, content.ID) })
+
+	recipient := models.ContentRecipient{ContentID: content.ID, OwnerAddress: address, RecipientAddress: address + "r", EncryptedKey: strings.Repeat("b", 600)}
+	if err := DB.Create(&recipient).Error; err != nil {
+		t.Fatalf("create recipient: %v", err)
+	}
+	t.Cleanup(func() { DB.Delete(&recipient) })
+	// (content_id, recipient_address) 唯一索引同样生效
+	duplicate := recipient
+	duplicate.ID = 0
+	if err := DB.Create(&duplicate).Error; err == nil {
+		t.Error("duplicate recipient was accepted")
+	}
+
+	var stored models.EncryptedContent
+	if err := GetDB().First(&stored, content.ID).Error; err != nil {
+		t.Fatalf("read content: %v", err)
+	}
+	if stored.EncryptedData != data || stored.EncryptedKey != content.EncryptedKey || stored.Metadata != content.Metadata {
+		t.Error("text columns did not round-trip")
+	}
+}
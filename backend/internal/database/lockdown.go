@@ -0,0 +1,51 @@
+package database
+
+import (
+	"sync/atomic"
+	"vaultseed-backend/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockdownKey 紧急锁定状态在 settings 表中的键
+const lockdownKey = "lockdown"
+
+// lockdownActive 内存中的锁定状态，每个请求都会读取，避免查库
+var lockdownActive atomic.Bool
+
+// loadLockdown 启动时从数据库恢复锁定状态
+func loadLockdown(db *gorm.DB) error {
+	var setting models.Setting
+	err := db.Where("key = ?", lockdownKey).First(&setting).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	lockdownActive.Store(setting.Value == "true")
+	return nil
+}
+
+// LockdownActive 是否处于紧急锁定
+func LockdownActive() bool {
+	return lockdownActive.Load()
+}
+
+// SetLockdown 开启或解除紧急锁定并持久化，重启后保持
+func SetLockdown(active bool) error {
+	value := "false"
+	if active {
+		value = "true"
+	}
+	setting := models.Setting{Key: lockdownKey, Value: value}
+	if err := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&setting).Error; err != nil {
+		return err
+	}
+	lockdownActive.Store(active)
+	return nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ReadDB 只读副本连接，未配置时为 nil
+	ReadDB *gorm.DB
+
+	// replicaLagWindow 写入后在该时间内读取仍走主库，避免副本延迟导致读不到刚写入的数据
+	replicaLagWindow time.Duration
+
+	recentWrites sync.Map // 小写地址 -> 最近一次写入时间
+)
+
+// GetReadDB 获取只读查询使用的数据库实例，未配置副本时返回主库
+func GetReadDB() *gorm.DB {
+	if ReadDB != nil {
+		return ReadDB
+	}
+	return DB
+}
+
+// GetReadDBFor 获取指定用户的只读数据库实例
+// 该用户刚写入过数据时返回主库，保证能读到自己的最新内容（仅在当前实例内生效）
+func GetReadDBFor(address string) *gorm.DB {
+	if ReadDB == nil {
+		return DB
+	}
+	if v, ok := recentWrites.Load(strings.ToLower(address)); ok {
+		if time.Since(v.(time.Time)) < replicaLagWindow {
+			return DB
+		}
+		recentWrites.Delete(strings.ToLower(address))
+	}
+	return ReadDB
+}
+
+// MarkWrite 记录用户刚刚写入过数据
+func MarkWrite(address string) {
+	if ReadDB == nil {
+		return
+	}
+	recentWrites.Store(strings.ToLower(address), time.Now())
+}
@@ -0,0 +1,133 @@
+package ens
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"vaultseed-backend/internal/ethrpc"
+)
+
+// entry 缓存项，lastUsed 用于判断是否为活跃用户
+type entry struct {
+	profile  Profile
+	fetched  time.Time
+	lastUsed time.Time
+}
+
+var (
+	mu      sync.Mutex
+	entries = make(map[string]*entry)
+	ttl     = time.Hour
+
+	// inflight 限制同时进行的 RPC 解析数量
+	inflight = make(chan struct{}, 4)
+)
+
+// Configure 设置缓存有效期
+func Configure(cacheTTL time.Duration) {
+	mu.Lock()
+	ttl = cacheTTL
+	mu.Unlock()
+}
+
+// Enabled 是否可以进行 ENS 解析（依赖 ETH_RPC_URL）
+func Enabled() bool {
+	return ethrpc.Enabled()
+}
+
+// Lookup 返回地址的 ENS 信息，缓存未过期时不访问 RPC
+// 查询失败时若有旧缓存则返回旧值
+func Lookup(ctx context.Context, address string) (Profile, error) {
+	key := strings.ToLower(address)
+	now := time.Now()
+
+	mu.Lock()
+	e, ok := entries[key]
+	if ok {
+		e.lastUsed = now
+		if now.Sub(e.fetched) < ttl {
+			profile := e.profile
+			mu.Unlock()
+			return profile, nil
+		}
+	}
+	mu.Unlock()
+
+	select {
+	case inflight <- struct{}{}:
+	case <-ctx.Done():
+		return Profile{}, ctx.Err()
+	}
+	profile, err := resolve(ctx, address)
+	<-inflight
+	if err != nil {
+		if ok {
+			return e.profile, nil
+		}
+		return Profile{}, err
+	}
+	store(key, profile, now)
+	return profile, nil
+}
+
+// store 写入缓存，空结果同样缓存以免重复查询
+func store(key string, profile Profile, now time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	e, ok := entries[key]
+	if !ok {
+		e = &entry{lastUsed: now}
+		entries[key] = e
+	}
+	e.profile = profile
+	e.fetched = now
+}
+
+// StartRefresher 后台定期刷新活跃用户（最近一个有效期内查询过）即将过期的缓存，并清理不活跃的项
+func StartRefresher(ctx context.Context) {
+	go func() {
+		for {
+			mu.Lock()
+			interval := ttl / 2
+			mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			refresh(ctx)
+		}
+	}()
+}
+
+func refresh(ctx context.Context) {
+	now := time.Now()
+	var due []string
+
+	mu.Lock()
+	for key, e := range entries {
+		switch {
+		case now.Sub(e.lastUsed) > 2*ttl:
+			delete(entries, key)
+		case now.Sub(e.lastUsed) <= ttl && now.Sub(e.fetched) >= ttl/2:
+			due = append(due, key)
+		}
+	}
+	mu.Unlock()
+
+	// 逐个刷新，避免突发大量 RPC 调用
+	for _, key := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		profile, err := resolve(ctx, key)
+		if err != nil {
+			log.Printf("ENS refresh failed for %s: %v", key, err)
+			continue
+		}
+		store(key, profile, time.Now())
+	}
+}
@@ -0,0 +1,129 @@
+package ens
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+	"vaultseed-backend/internal/ethrpc"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// registryAddress ENS 注册表合约地址（主网及主要测试网相同）
+const registryAddress = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+// 合约方法选择器
+var (
+	selectorResolver = crypto.Keccak256([]byte("resolver(bytes32)"))[:4]
+	selectorName     = crypto.Keccak256([]byte("name(bytes32)"))[:4]
+	selectorAddr     = crypto.Keccak256([]byte("addr(bytes32)"))[:4]
+	selectorText     = crypto.Keccak256([]byte("text(bytes32,string)"))[:4]
+)
+
+var errMalformed = errors.New("malformed ABI response")
+
+// Profile 地址对应的 ENS 主名称和头像
+type Profile struct {
+	Name   string `json:"name,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// resolve 通过反向解析获取地址的主名称，正向校验名称确实指向该地址后再读取 avatar 文本记录
+// 未设置反向记录或校验不通过时返回空 Profile
+func resolve(ctx context.Context, address string) (Profile, error) {
+	addr := common.HexToAddress(address)
+	reverseNode := namehash(strings.ToLower(addr.Hex()[2:]) + ".addr.reverse")
+
+	resolver, err := resolverOf(ctx, reverseNode)
+	if err != nil || resolver == (common.Address{}) {
+		return Profile{}, err
+	}
+	out, err := ethrpc.CallContract(ctx, resolver.Hex(), append(append([]byte{}, selectorName...), reverseNode[:]...))
+	if err != nil {
+		return Profile{}, err
+	}
+	name, err := decodeString(out)
+	if err != nil || name == "" {
+		return Profile{}, err
+	}
+
+	// 正向校验，防止任意设置反向记录冒充名称
+	node := namehash(name)
+	resolver, err = resolverOf(ctx, node)
+	if err != nil || resolver == (common.Address{}) {
+		return Profile{}, err
+	}
+	out, err = ethrpc.CallContract(ctx, resolver.Hex(), append(append([]byte{}, selectorAddr...), node[:]...))
+	if err != nil {
+		return Profile{}, err
+	}
+	if len(out) < 32 || common.BytesToAddress(out[:32]) != addr {
+		return Profile{}, nil
+	}
+
+	profile := Profile{Name: name}
+	out, err = ethrpc.CallContract(ctx, resolver.Hex(), encodeText(node, "avatar"))
+	if err == nil {
+		profile.Avatar, _ = decodeString(out)
+	}
+	return profile, nil
+}
+
+// resolverOf 查询注册表中节点的解析器地址
+func resolverOf(ctx context.Context, node [32]byte) (common.Address, error) {
+	out, err := ethrpc.CallContract(ctx, registryAddress, append(append([]byte{}, selectorResolver...), node[:]...))
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(out) < 32 {
+		return common.Address{}, errMalformed
+	}
+	return common.BytesToAddress(out[:32]), nil
+}
+
+// namehash 按 EIP-137 计算名称哈希（名称统一转为小写，不做完整的 UTS-46 规范化）
+func namehash(name string) [32]byte {
+	var node [32]byte
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		copy(node[:], crypto.Keccak256(node[:], crypto.Keccak256([]byte(labels[i]))))
+	}
+	return node
+}
+
+// encodeText 编码 text(bytes32,string) 调用数据
+func encodeText(node [32]byte, key string) []byte {
+	data := append([]byte{}, selectorText...)
+	data = append(data, node[:]...)
+	data = append(data, common.LeftPadBytes(big.NewInt(64).Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(int64(len(key))).Bytes(), 32)...)
+	padded := make([]byte, (len(key)+31)/32*32)
+	copy(padded, key)
+	return append(data, padded...)
+}
+
+// decodeString 解码 ABI 编码的单个 string 返回值
+func decodeString(out []byte) (string, error) {
+	if len(out) == 0 {
+		return "", nil
+	}
+	if len(out) < 64 {
+		return "", errMalformed
+	}
+	offset := new(big.Int).SetBytes(out[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(out)-32) {
+		return "", errMalformed
+	}
+	start := offset.Uint64()
+	length := binary.BigEndian.Uint64(out[start+24 : start+32])
+	if new(big.Int).SetBytes(out[start:start+24]).Sign() != 0 || length > uint64(len(out))-start-32 {
+		return "", errMalformed
+	}
+	return string(out[start+32 : start+32+length]), nil
+}
@@ -0,0 +1,143 @@
+package ethrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	// endpoint 以太坊 JSON-RPC 地址，为空时不可用
+	endpoint string
+
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+// ErrNotConfigured 未配置 ETH_RPC_URL
+var ErrNotConfigured = errors.New("ethereum RPC is not configured")
+
+// Configure 设置 JSON-RPC 地址
+func Configure(rpcURL string) {
+	endpoint = rpcURL
+}
+
+// Enabled 是否配置了 RPC
+func Enabled() bool {
+	return endpoint != ""
+}
+
+type request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type response struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Call 调用 JSON-RPC 方法并把 result 解码到 result
+func Call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if !Enabled() {
+		return ErrNotConfigured
+	}
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(request{JSONRPC: "2.0", ID: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %d", method, resp.StatusCode)
+	}
+
+	var out response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if out.Error != nil {
+		return fmt.Errorf("%s: rpc error %d: %s", method, out.Error.Code, out.Error.Message)
+	}
+	if len(out.Result) == 0 || string(out.Result) == "null" {
+		return fmt.Errorf("%s: empty result", method)
+	}
+	return json.Unmarshal(out.Result, result)
+}
+
+// BlockNumber 返回最新区块高度
+func BlockNumber(ctx context.Context) (uint64, error) {
+	var n hexutil.Uint64
+	if err := Call(ctx, "eth_blockNumber", &n); err != nil {
+		return 0, err
+	}
+	return uint64(n), nil
+}
+
+// BlockHash 返回指定高度区块的哈希（0x 开头的小写十六进制）
+func BlockHash(ctx context.Context, number uint64) (string, error) {
+	var block struct {
+		Hash string `json:"hash"`
+	}
+	if err := Call(ctx, "eth_getBlockByNumber", &block, hexutil.EncodeUint64(number), false); err != nil {
+		return "", err
+	}
+	return strings.ToLower(block.Hash), nil
+}
+
+// CallContract 以 eth_call 调用合约（latest 区块），返回原始返回值
+func CallContract(ctx context.Context, to string, data []byte) ([]byte, error) {
+	var result hexutil.Bytes
+	call := map[string]string{"to": to, "data": hexutil.Encode(data)}
+	if err := Call(ctx, "eth_call", &result, call, "latest"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// eip1271MagicValue isValidSignature(bytes32,bytes) 校验通过时返回的值，同时也是该函数的选择器
+var eip1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// IsValidSignature 按 EIP-1271 调用合约钱包的 isValidSignature(hash, signature)
+// 返回值以魔数 0x1626ba7e 开头时视为有效；普通账户（无合约代码）返回 false
+func IsValidSignature(ctx context.Context, contract string, hash [32]byte, signature []byte) (bool, error) {
+	// ABI 编码：选择器 + bytes32 + bytes 偏移量 + bytes 长度 + 按 32 字节补齐的数据
+	padded := (len(signature) + 31) / 32 * 32
+	data := make([]byte, 4+32*3+padded)
+	copy(data, eip1271MagicValue)
+	copy(data[4:], hash[:])
+	data[4+32+31] = 0x40
+	binary.BigEndian.PutUint64(data[4+64+24:], uint64(len(signature)))
+	copy(data[4+96:], signature)
+
+	out, err := CallContract(ctx, contract, data)
+	if err != nil {
+		return false, err
+	}
+	return len(out) >= 4 && bytes.Equal(out[:4], eip1271MagicValue), nil
+}
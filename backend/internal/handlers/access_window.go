@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+	"vaultseed-backend/internal/models"
+)
+
+// accessWindowLayout 访问时间窗口的时间格式
+const accessWindowLayout = "15:04"
+
+// validateAccessWindow 校验创建请求中的访问时间窗口
+func validateAccessWindow(start, end *string, tz string) error {
+	if start == nil && end == nil {
+		return nil
+	}
+	if start == nil || end == nil {
+		return errors.New("access_window_start and access_window_end must be set together")
+	}
+	if _, err := time.Parse(accessWindowLayout, *start); err != nil {
+		return errors.New("invalid access_window_start, expected HH:MM")
+	}
+	if _, err := time.Parse(accessWindowLayout, *end); err != nil {
+		return errors.New("invalid access_window_end, expected HH:MM")
+	}
+	if *start == *end {
+		return errors.New("access window must not be empty")
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return errors.New("invalid access_window_tz")
+	}
+	return nil
+}
+
+// withinAccessWindow 判断当前时间是否位于内容的访问时间窗口内
+// 开始时间晚于结束时间时表示跨越午夜的窗口（如 22:00-06:00）
+func withinAccessWindow(content *models.EncryptedContent, now time.Time) bool {
+	if content.AccessWindowStart == nil || content.AccessWindowEnd == nil {
+		return true
+	}
+
+	loc, err := time.LoadLocation(content.AccessWindowTZ)
+	if err != nil {
+		return false
+	}
+	start, err := time.Parse(accessWindowLayout, *content.AccessWindowStart)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(accessWindowLayout, *content.AccessWindowEnd)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	if startMinutes < endMinutes {
+		return minutes >= startMinutes && minutes < endMinutes
+	}
+	return minutes >= startMinutes || minutes < endMinutes
+}
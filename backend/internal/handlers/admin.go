@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+	"vaultseed-backend/internal/webhook"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// adminTargetAddress 校验路径中的目标地址并统一为 EIP-55 校验和格式，不合法时返回 400
+// 管理员可能提交全小写的地址，查询时同样按小写比较以兼容早期未规范化的记录
+func adminTargetAddress(c *gin.Context) (string, bool) {
+	address := c.Param("address")
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid address"})
+		return "", false
+	}
+	return common.HexToAddress(address).Hex(), true
+}
+
+// AdminPurgeUserHandler 管理员彻底删除指定用户的全部数据（用于删除请求）
+func AdminPurgeUserHandler(c *gin.Context) {
+	address, ok := adminTargetAddress(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(address)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+
+	if !requireFreshAuth(c, "purge-user", c.GetString("adminAddress")) {
+		return
+	}
+
+	deleted := make(map[string]int64)
+	var contentIDs []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.EncryptedContent{}).Where("user_address = ?", user.Address).Pluck("id", &contentIDs).Error; err != nil {
+			return err
+		}
+		return purgeUserData(tx, user.Address, deleted)
+	})
+	if err != nil {
+		serverError(c, err, "Failed to purge user")
+		return
+	}
+
+	// 审计记录只保留目标地址的哈希
+	sum := sha256.Sum256([]byte(strings.ToLower(user.Address)))
+	detail := "target_sha256=" + hex.EncodeToString(sum[:])
+
+	attestation, err := utils.SignDeletion(user.Address, contentIDs, time.Now())
+	if err == nil {
+		detail += " attestation=" + attestation.Reference()
+	} else if err != utils.ErrNoServerKey {
+		requestLogger(c).Error("Failed to sign deletion attestation", "error", err)
+	}
+	recordAudit(db, c, AuditAdminPurge, c.GetString("adminAddress"), detail)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"deleted":     deleted,
+		"attestation": attestation,
+	})
+}
+
+// AdminUserInfoHandler 管理员查看指定用户的账户信息，包括最近一次登录签名的消息
+func AdminUserInfoHandler(c *gin.Context) {
+	address, ok := adminTargetAddress(c)
+	if !ok {
+		return
+	}
+
+	db := database.GetReadDB().WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(address)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+
+	var keys, contents int64
+	if err := db.Model(&models.UserKey{}).Where("address = ?", user.Address).Count(&keys).Error; err != nil {
+		serverError(c, err, "Database error")
+		return
+	}
+	if err := db.Model(&models.EncryptedContent{}).Where("user_address = ?", user.Address).Count(&contents).Error; err != nil {
+		serverError(c, err, "Database error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"user": gin.H{
+			"address":             user.Address,
+			"has_public_key":      user.PublicKey != "",
+			"created_at":          user.CreatedAt,
+			"locked_until":        user.LockedUntil,
+			"last_login_at":       user.LastLoginAt,
+			"last_signed_message": user.LastSignedMessage,
+			"keys":                keys,
+			"contents":            contents,
+			"session_policy":      sessionPolicy(&user),
+			"quota":               userQuota(&user),
+		},
+	})
+}
+
+// sessionPolicy 用户当前生效的会话策略，overridden 表示是否使用了按用户的覆盖值
+func sessionPolicy(user *models.User) gin.H {
+	return gin.H{
+		"nonce_ttl_seconds":  int(user.EffectiveNonceTTL(cfg.DecryptNonceTTL).Seconds()),
+		"nonce_ttl_override": user.NonceTTLSeconds != nil,
+		"token_ttl_seconds":  int(user.EffectiveTokenTTL(utils.TokenTTL()).Seconds()),
+		"token_ttl_override": user.TokenTTLSeconds != nil,
+	}
+}
+
+// AdminSetSessionPolicyHandler 设置用户的 nonce 与访问令牌有效期，null 表示恢复全局配置
+// 新的令牌有效期在用户下次登录时生效，已签发的令牌不受影响
+func AdminSetSessionPolicyHandler(c *gin.Context) {
+	var req models.SessionPolicyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	address, ok := adminTargetAddress(c)
+	if !ok {
+		return
+	}
+
+	adminAddress := c.GetString("adminAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(address)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+
+	if err := db.Model(&user).Updates(map[string]interface{}{
+		"nonce_ttl_seconds": req.NonceTTLSeconds,
+		"token_ttl_seconds": req.TokenTTLSeconds,
+	}).Error; err != nil {
+		serverError(c, err, "Failed to update session policy")
+		return
+	}
+	database.MarkWrite(user.Address)
+	user.NonceTTLSeconds = req.NonceTTLSeconds
+	user.TokenTTLSeconds = req.TokenTTLSeconds
+
+	policy := sessionPolicy(&user)
+	recordAudit(db, c, AuditSessionPolicy, adminAddress, fmt.Sprintf("target=%s nonce_ttl=%v token_ttl=%v",
+		user.Address, policy["nonce_ttl_seconds"], policy["token_ttl_seconds"]))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"session_policy": policy,
+	})
+}
+
+// userQuota 用户当前生效的内容条数上限（0 表示不限制），overridden 表示是否使用了按用户的覆盖值
+func userQuota(user *models.User) gin.H {
+	return gin.H{
+		"max_content":          user.EffectiveMaxContent(cfg.MaxContentPerUser),
+		"max_content_override": user.MaxContent != nil,
+	}
+}
+
+// AdminSetUserQuotaHandler 设置用户的内容条数上限，null 表示恢复 MAX_CONTENT_PER_USER，0 表示不限制
+// 只影响之后的新增，已超出新上限的内容不会被删除
+func AdminSetUserQuotaHandler(c *gin.Context) {
+	var req models.UserQuotaRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	address, ok := adminTargetAddress(c)
+	if !ok {
+		return
+	}
+
+	adminAddress := c.GetString("adminAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(address)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+
+	if err := db.Model(&user).Update("max_content", req.MaxContent).Error; err != nil {
+		serverError(c, err, "Failed to update quota")
+		return
+	}
+	database.MarkWrite(user.Address)
+	user.MaxContent = req.MaxContent
+
+	quota := userQuota(&user)
+	recordAudit(db, c, AuditUserQuota, adminAddress, fmt.Sprintf("target=%s max_content=%v override=%v",
+		user.Address, quota["max_content"], quota["max_content_override"]))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"quota":   quota,
+	})
+}
+
+// AdminTestWebhookHandler 向配置的 webhook 发送一条签名的 ping 事件，返回投递结果
+func AdminTestWebhookHandler(c *gin.Context) {
+	if !webhook.Enabled() {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Webhook is not configured"})
+		return
+	}
+
+	result, err := webhook.Send("ping", map[string]interface{}{
+		"requested_by": c.GetString("adminAddress"),
+	})
+	response := gin.H{
+		"success":     err == nil && result.StatusCode >= 200 && result.StatusCode < 300,
+		"status_code": result.StatusCode,
+		"latency_ms":  result.Latency.Milliseconds(),
+	}
+	if err != nil {
+		response["error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AdminActivityHandler 管理员查看全站最近活动（登录、创建、删除），按时间倒序分页
+// 可用 ?type=login|create|delete 筛选
+func AdminActivityHandler(c *gin.Context) {
+	var events []string
+	if kind := c.Query("type"); kind != "" {
+		var ok bool
+		if events, ok = activityEvents[kind]; !ok {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid activity type"})
+			return
+		}
+	} else {
+		for _, e := range activityEvents {
+			events = append(events, e...)
+		}
+	}
+
+	page, pageSize := parsePagination(c)
+
+	db := database.GetReadDB().WithContext(c.Request.Context())
+
+	var entries []models.AuditLog
+	if err := db.Where("event IN ?", events).Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&entries).Error; err != nil {
+		serverError(c, err, "Failed to fetch activity")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"page":      page,
+		"page_size": pageSize,
+		"activity":  entries,
+	})
+}
+
+// purgeUserData 硬删除与地址相关的所有记录，deleted 中记录各表删除的行数
+func purgeUserData(tx *gorm.DB, address string, deleted map[string]int64) error {
+	steps := []struct {
+		name  string
+		model interface{}
+		where string
+	}{
+		{"share_links", &models.ShareLink{}, "owner_address = @address"},
+		{"recipients", &models.ContentRecipient{}, "@address IN (owner_address, recipient_address)"},
+		{"upload_chunks", &models.UploadChunk{}, "upload_id IN (SELECT id FROM uploads WHERE user_address = @address)"},
+		{"uploads", &models.Upload{}, "user_address = @address"},
+		{"revisions", &models.ContentRevision{}, "content_id IN (SELECT id FROM encrypted_contents WHERE user_address = @address)"},
+		// 该地址自己的内容的记录，以及它作为接收者解密他人内容留下的记录
+		{"decrypt_logs", &models.DecryptLog{}, "user_address = @address OR content_id IN (SELECT id FROM encrypted_contents WHERE user_address = @address)"},
+		{"decrypt_challenges", &models.DecryptChallenge{}, "user_address = @address OR content_id IN (SELECT id FROM encrypted_contents WHERE user_address = @address)"},
+		{"contents", &models.EncryptedContent{}, "user_address = @address"},
+		{"folders", &models.Folder{}, "owner_address = @address"},
+		{"labels", &models.Label{}, "address = @address"},
+		{"content_tags", &models.ContentTag{}, "tag_id IN (SELECT id FROM tags WHERE address = @address)"},
+		{"tags", &models.Tag{}, "address = @address"},
+		{"keys", &models.UserKey{}, "address = @address"},
+		{"used_nonces", &models.UsedNonce{}, "address = @address"},
+		{"audit_logs", &models.AuditLog{}, "address = @address"},
+		{"users", &models.User{}, "address = @address"},
+	}
+	for _, step := range steps {
+		result := tx.Unscoped().Where(step.where, sql.Named("address", address)).Delete(step.model)
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted[step.name] = result.RowsAffected
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// activitySubscriberBuffer 每个订阅者可积压的事件数，超出后丢弃新事件
+const activitySubscriberBuffer = 64
+
+// activitySubscriber 实时活动流的订阅者
+type activitySubscriber struct {
+	events  chan models.AuditLog
+	dropped atomic.Int64 // 因订阅者消费过慢而丢弃的事件数
+}
+
+// activityHub 向实时订阅者广播审计事件
+type activityHub struct {
+	mu   sync.Mutex
+	subs map[*activitySubscriber]struct{}
+}
+
+var liveActivity = &activityHub{subs: make(map[*activitySubscriber]struct{})}
+
+func (h *activityHub) subscribe() *activitySubscriber {
+	sub := &activitySubscriber{events: make(chan models.AuditLog, activitySubscriberBuffer)}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *activityHub) unsubscribe(sub *activitySubscriber) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
+
+// publish 非阻塞地投递事件，订阅者缓冲已满时丢弃并计数，不拖慢请求处理
+func (h *activityHub) publish(entry models.AuditLog) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		select {
+		case sub.events <- entry:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// AdminStreamHandler 以 SSE 推送实时运营视图
+// 定期发送 snapshot 事件（用户数、内容数、最近一小时各类事件数），审计事件发生时发送 activity 事件
+func AdminStreamHandler(c *gin.Context) {
+	sub := liveActivity.subscribe()
+	defer liveActivity.unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(cfg.AdminStreamInterval)
+	defer ticker.Stop()
+
+	sendSnapshot := func() bool {
+		snapshot, err := adminSnapshot(c, sub)
+		if err != nil {
+			c.SSEvent("error", gin.H{"error": "Failed to collect stats"})
+			return false
+		}
+		c.SSEvent("snapshot", snapshot)
+		return true
+	}
+	if !sendSnapshot() {
+		return
+	}
+	c.Writer.Flush()
+
+	// Stream 在客户端断开时返回
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+			return sendSnapshot()
+		case entry := <-sub.events:
+			c.SSEvent("activity", entry)
+			return true
+		}
+	})
+}
+
+// adminSnapshot 汇总实时视图的统计数据
+func adminSnapshot(c *gin.Context, sub *activitySubscriber) (gin.H, error) {
+	db := database.GetReadDB().WithContext(c.Request.Context())
+
+	var users, contents int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&models.EncryptedContent{}).Count(&contents).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Event string
+		Count int64
+	}
+	if err := db.Model(&models.AuditLog{}).Select("event, COUNT(*) AS count").
+		Where("created_at >= ?", time.Now().Add(-time.Hour)).
+		Group("event").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	recent := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		recent[r.Event] = r.Count
+	}
+
+	return gin.H{
+		"users":            users,
+		"contents":         contents,
+		"recent_activity":  recent,
+		"dropped_events":   sub.dropped.Load(),
+		"generated_at":     time.Now().UTC(),
+		"interval_seconds": int(cfg.AdminStreamInterval.Seconds()),
+	}, nil
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestPurgeUserDataRemovesRecipientActivity(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice, bob := newWallet(t), newWallet(t)
+	createUser(t, alice.address)
+	createUser(t, bob.address)
+	shared := seedContent(t, alice.address)
+	own := seedContent(t, bob.address, func(c *models.EncryptedContent) {
+		c.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	})
+
+	expires := time.Now().Add(time.Hour)
+	db.Create(&models.ContentRecipient{ContentID: shared.ID, OwnerAddress: alice.address, RecipientAddress: bob.address, EncryptedKey: randomBase64(t, 32)})
+	db.Create(&[]models.DecryptLog{
+		{ContentID: shared.ID, UserAddress: bob.address, Success: true},
+		{ContentID: shared.ID, UserAddress: alice.address, Success: true},
+		{ContentID: own.ID, UserAddress: bob.address, Success: true},
+	})
+	db.Create(&[]models.DecryptChallenge{
+		{ContentID: shared.ID, Nonce: "bob-on-alice", UserAddress: bob.address, ExpiresAt: expires},
+		{ContentID: shared.ID, Nonce: "alice-own", UserAddress: alice.address, ExpiresAt: expires},
+		{ContentID: own.ID, Nonce: "bob-own", UserAddress: bob.address, ExpiresAt: expires},
+	})
+
+	deleted := map[string]int64{}
+	if err := db.Transaction(func(tx *gorm.DB) error { return purgeUserData(tx, bob.address, deleted) }); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		model interface{}
+		where string
+		want  int64
+	}{
+		{"bob decrypt logs", &models.DecryptLog{}, "user_address = ?", 0},
+		{"bob challenges", &models.DecryptChallenge{}, "user_address = ?", 0},
+		{"bob recipient rows", &models.ContentRecipient{}, "recipient_address = ?", 0},
+		{"bob content", &models.EncryptedContent{}, "user_address = ?", 0},
+	}
+	for _, tt := range tests {
+		var n int64
+		db.Unscoped().Model(tt.model).Where(tt.where, bob.address).Count(&n)
+		if n != tt.want {
+			t.Errorf("%s: %d rows left, want %d", tt.name, n, tt.want)
+		}
+	}
+	if deleted["decrypt_logs"] != 2 || deleted["decrypt_challenges"] != 2 {
+		t.Errorf("deleted counts = %v", deleted)
+	}
+
+	// 所有者自己的记录不受影响
+	var logs, challenges int64
+	db.Model(&models.DecryptLog{}).Where("user_address = ?", alice.address).Count(&logs)
+	db.Model(&models.DecryptChallenge{}).Where("user_address = ?", alice.address).Count(&challenges)
+	if logs != 1 || challenges != 1 {
+		t.Errorf("alice rows: %d logs, %d challenges, want 1 and 1", logs, challenges)
+	}
+}
+
+func TestAdminUserLookupNormalizesAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		address func(checksummed string) string
+		status  int
+	}{
+		{"checksummed", func(a string) string { return a }, http.StatusOK},
+		{"lowercase", strings.ToLower, http.StatusOK},
+		{"uppercase hex", func(a string) string { return "0x" + strings.ToUpper(a[2:]) }, http.StatusOK},
+		{"not an address", func(string) string { return "alice" }, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) { c.ReauthOperations = nil })
+			db := database.GetDB()
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			content := seedContent(t, alice.address)
+			path := "/admin/users/" + tt.address(alice.address)
+
+			r := newRouter("")
+			r.GET("/admin/users/:address", AdminUserInfoHandler)
+			r.DELETE("/admin/users/:address", AdminPurgeUserHandler)
+			w := doJSON(t, r, http.MethodGet, path, nil)
+			expectStatus(t, w, tt.status)
+			if tt.status == http.StatusOK {
+				if user := decodeBody(t, w)["user"].(map[string]interface{}); user["address"] != alice.address || user["contents"] != float64(1) {
+					t.Errorf("user = %v", user)
+				}
+			}
+
+			expectStatus(t, doJSON(t, r, http.MethodDelete, path, nil), tt.status)
+			var users, contents int64
+			db.Model(&models.User{}).Where("address = ?", alice.address).Count(&users)
+			db.Unscoped().Model(&models.EncryptedContent{}).Where("id = ?", content.ID).Count(&contents)
+			if purged := users == 0 && contents == 0; purged != (tt.status == http.StatusOK) {
+				t.Errorf("after purge: %d users, %d contents (status %d)", users, contents, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ArchiveContentHandler 归档内容，归档后不出现在默认列表中但仍可解密
+func ArchiveContentHandler(c *gin.Context) {
+	setArchived(c, true)
+}
+
+// UnarchiveContentHandler 取消归档
+func UnarchiveContentHandler(c *gin.Context) {
+	setArchived(c, false)
+}
+
+// setArchived 更新内容的归档状态，重复操作视为成功
+func setArchived(c *gin.Context, archived bool) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var content models.EncryptedContent
+	if err := db.Select("id").Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	if err := db.Model(&content).Update("archived", archived).Error; err != nil {
+		serverError(c, err, "Failed to update content")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "archived": archived})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/siem"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 审计事件类型
+const (
+	AuditNonceGrace    = "NONCE_GRACE"
+	AuditNonceReuse    = "NONCE_REUSE"
+	AuditExport        = "EXPORT"
+	AuditImport        = "IMPORT"
+	AuditAdminPurge    = "ADMIN_PURGE_USER"
+	AuditLockdown      = "LOCKDOWN"
+	AuditTitleEscrow   = "TITLE_ESCROW"
+	AuditSessionPolicy = "SESSION_POLICY"
+	AuditUserQuota     = "USER_QUOTA"
+	AuditKeyRotation   = "KEY_ROTATION"
+
+	AuditLogin          = "LOGIN"
+	AuditContentCreate  = "CONTENT_CREATE"
+	AuditContentUpdate  = "CONTENT_UPDATE"
+	AuditContentDelete  = "CONTENT_DELETE"
+	AuditContentTrash   = "CONTENT_TRASH"
+	AuditContentRestore = "CONTENT_RESTORE"
+	AuditContentRevert  = "CONTENT_REVERT"
+	AuditShareRevoke    = "SHARE_REVOKE"
+)
+
+// activityEvents 活动流按类型筛选时对应的审计事件
+var activityEvents = map[string][]string{
+	"login":    {AuditLogin},
+	"create":   {AuditContentCreate},
+	"delete":   {AuditContentTrash, AuditContentDelete, AuditShareRevoke, AuditAdminPurge},
+	"lockdown": {AuditLockdown},
+}
+
+// recordAudit 写入审计日志，失败时只记录日志而不影响请求
+// 审计记录不随客户端断开而取消
+func recordAudit(db *gorm.DB, c *gin.Context, event, address, detail string) {
+	db = db.WithContext(context.WithoutCancel(c.Request.Context()))
+	entry := models.AuditLog{
+		Event:    event,
+		Address:  address,
+		Detail:   detail,
+		ClientIP: c.ClientIP(),
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		requestLogger(c).Error("Failed to write audit log", "error", err, "event", event)
+		return
+	}
+	liveActivity.publish(entry)
+	siem.Forward(entry)
+}
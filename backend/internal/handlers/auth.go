@@ -1,138 +1,578 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/ens"
 	"vaultseed-backend/internal/models"
 	"vaultseed-backend/internal/utils"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // LoginHandler 处理用户登录
 func LoginHandler(c *gin.Context) {
 	var req models.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	// 验证签名
-	if !utils.VerifyEthereumSignature(req.Message, req.Signature, req.Address) {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid signature"})
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	// 查找用户：nonce 由 GetNonceHandler 签发，未申请过 nonce 的地址无法登录
+	var user models.User
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(req.Address)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		} else {
+			serverError(c, err, "Database error")
+		}
 		return
 	}
 
-	db := database.GetDB()
+	if accountLocked(&user) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: lockedMessage(&user)})
+		return
+	}
 
-	// 查找或创建用户
-	var user models.User
-	result := db.Where("address = ?", req.Address).First(&user)
+	// 验证 nonce（防重放）
+	if user.Nonce != req.Nonce {
+		// 客户端提交的消息必须确实包含这个旧 nonce，签名才算是对它的重放
+		stale := ""
+		if strings.Contains(req.Message, req.Nonce) {
+			stale = req.Message
+		}
+		detectNonceReuse(db, c, user.Address, req.Nonce, signedBy(c, stale, req.Signature, user.Address))
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	}
+	// nonce 过期后须重新调用 GET /nonce，截获的旧签名不能无限期使用
+	if clock().Sub(user.NonceIssuedAt) > loginNonceTTL(&user) {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Nonce expired"})
+		return
+	}
 
-	if result.Error == gorm.ErrRecordNotFound {
-		// 新用户，生成 nonce
-		nonce, err := utils.GenerateNonce()
+	// 配置了 SIWE_DOMAIN 时接受 EIP-4361 消息：签名覆盖客户端提交的原文，逐项校验 domain、地址、nonce 和有效期
+	var expected string
+	chainID := req.ChainID
+	if cfg.SIWEDomain != "" && utils.IsSIWEMessage(req.Message) {
+		siwe, err := utils.ParseSIWEMessage(req.Message)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate nonce"})
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid SIWE message: " + err.Error()})
 			return
 		}
-
-		user = models.User{
-			Address: req.Address,
-			Nonce:   nonce,
+		if msg := checkSIWELogin(siwe, req.Address, user.Nonce, req.ChainID, time.Now()); msg != "" {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: msg})
+			return
+		}
+		expected = req.Message
+		chainID = siwe.ChainID
+	} else {
+		// 签名的消息必须是服务端按当前 nonce 生成的消息，不能由客户端任意指定
+		expected = utils.GenerateMessageForSigning(req.Address, user.Nonce)
+		if req.BlockNumber != nil {
+			expected += ", " + utils.BlockReference(*req.BlockNumber, req.BlockHash)
 		}
-		if err := db.Create(&user).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create user"})
+		if strings.TrimSpace(req.Message) != expected {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Message does not match the issued nonce"})
 			return
 		}
-	} else if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Database error"})
+	}
+
+	// 验证签名
+	if err := verifySignature(c.Request.Context(), "login", expected, req.Signature, req.Address, chainID); err != nil {
+		rejectSignature(c, err, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	// 可选的区块新鲜度校验
+	if err := checkBlockFreshness(c.Request.Context(), expected, req.BlockNumber, req.BlockHash); err != nil {
+		switch err {
+		case errBlockMissing, errBlockMismatch, errBlockStale:
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: err.Error()})
+		default:
+			c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Failed to verify block freshness"})
+		}
 		return
 	}
 
 	// 更新 nonce（防重放）
 	newNonce, err := utils.GenerateNonce()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate nonce"})
+		serverError(c, err, "Failed to generate nonce")
 		return
 	}
 
-	user.Nonce = newNonce
-	db.Save(&user)
+	// 仅当 nonce 仍为本次使用的值时才轮换，并发提交同一签名只有一个能成功
+	now := clock()
+	result := db.Model(&models.User{}).
+		Where("id = ? AND nonce = ?", user.ID, req.Nonce).
+		Updates(map[string]interface{}{
+			"nonce":               newNonce,
+			"nonce_issued_at":     now,
+			"last_signed_message": expected,
+			"last_login_at":       now,
+			"nonce_reuse_count":   0, // 登录成功说明私钥仍在本人手中，重新开始累计重放次数
+		})
+	if result.Error != nil {
+		serverError(c, result.Error, "Database error")
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	}
+	markNonceUsed(db, user.Address, req.Nonce)
+	recordAudit(db, c, AuditLogin, user.Address, "")
 
-	// 生成简单的 token（在实际应用中应该使用 JWT）
-	token := req.Address + ":" + newNonce
+	// 签发访问令牌
+	token, expiresAt, err := utils.IssueToken(user.Address, user.EffectiveTokenTTL(utils.TokenTTL()))
+	if err != nil {
+		serverError(c, err, "Failed to issue token")
+		return
+	}
 
 	c.JSON(http.StatusOK, models.LoginResponse{
-		Success: true,
-		Token:   token,
-		Address: req.Address,
+		Success:   true,
+		Token:     token,
+		Address:   user.Address,
+		ExpiresAt: &expiresAt,
 	})
 }
 
 // RegisterPublicKeyHandler 处理公钥注册
+// 公钥须为请求地址自身的 secp256k1 公钥（内容密钥以 ECIES 包装到钱包公钥），不接受与地址无关的 RSA 等公钥
 func RegisterPublicKeyHandler(c *gin.Context) {
 	var req models.RegisterPublicKeyRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format"})
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// 公钥必须属于签名地址，防止为自己注册他人的公钥
+	keyAddress, err := utils.PublicKeyAddress(req.PublicKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid public key"})
+		return
+	}
+	if !strings.EqualFold(keyAddress, req.Address) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Public key does not match address"})
 		return
 	}
 
 	// 验证签名
-	if !utils.VerifyEthereumSignature(req.Message, req.Signature, req.Address) {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid signature"})
+	if err := verifySignature(c.Request.Context(), "register-key", req.Message, req.Signature, req.Address, 0); err != nil {
+		rejectSignature(c, err, http.StatusUnauthorized, "Invalid signature")
 		return
 	}
 
-	db := database.GetDB()
+	db := database.GetDB().WithContext(c.Request.Context())
 
 	// 查找用户
 	var user models.User
-	result := db.Where("address = ?", req.Address).First(&user)
+	result := db.Where("LOWER(address) = ?", strings.ToLower(req.Address)).First(&user)
 	if result.Error != nil {
 		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
 		return
 	}
 
-	// 更新公钥
-	user.PublicKey = req.PublicKey
-	if err := db.Save(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save public key"})
+	// 替换已有公钥属于密钥轮换：须显式确认，并提示多少内容无法再用新公钥解密
+	var undecryptable int64
+	replacing := user.PublicKey != "" && user.PublicKey != req.PublicKey
+	if replacing {
+		n, err := countUndecryptableContent(db, user.Address, req.PublicKey)
+		if err != nil {
+			serverError(c, err, "Database error")
+			return
+		}
+		undecryptable = n
+		if !req.ConfirmReplace {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":                 "A different public key is already registered; set confirm_replace to replace it",
+				"undecryptable_content": undecryptable,
+			})
+			return
+		}
+		if !requireFreshAuth(c, "rotate-key", user.Address) {
+			return
+		}
+	}
+
+	// 更新公钥并记录公钥历史
+	var key *models.UserKey
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		key, err = activatePublicKey(tx, &user, req.PublicKey, req.Label)
+		return err
+	})
+	if err != nil {
+		serverError(c, err, "Failed to save public key")
 		return
 	}
+	database.MarkWrite(user.Address)
+
+	response := gin.H{"success": true, "key_id": key.ID}
+	if replacing {
+		response["undecryptable_content"] = undecryptable
+		if undecryptable > 0 {
+			response["warnings"] = []string{fmt.Sprintf("%d content items were encrypted for a different key and cannot be decrypted with the new key", undecryptable)}
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// activatePublicKey 将公钥设为用户的当前公钥并记录公钥历史，其余公钥标记为未激活
+func activatePublicKey(tx *gorm.DB, user *models.User, publicKey, label string) (*models.UserKey, error) {
+	// 只更新公钥列，调用方可能已在同一事务中修改了 nonce 等字段
+	if err := tx.Model(user).Update("public_key", publicKey).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.UserKey{}).
+		Where("address = ? AND public_key <> ?", user.Address, publicKey).
+		Update("active", false).Error; err != nil {
+		return nil, err
+	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	// 重新注册历史公钥时复用原记录，保证已有内容的 key_id 仍然有效
+	var key models.UserKey
+	result := tx.Where("address = ? AND public_key = ?", user.Address, publicKey).First(&key)
+	if result.Error == gorm.ErrRecordNotFound {
+		key = models.UserKey{Address: user.Address, PublicKey: publicKey, Label: label, Active: true}
+		return &key, tx.Create(&key).Error
+	} else if result.Error != nil {
+		return nil, result.Error
+	}
+	key.Active = true
+	if label != "" {
+		key.Label = label
+	}
+	return &key, tx.Save(&key).Error
 }
 
-// GetNonceHandler 获取 nonce
+// countUndecryptableContent 统计未使用指定公钥加密的内容条数（未记录 key_id 的旧内容视为使用当前公钥）
+func countUndecryptableContent(db *gorm.DB, address, publicKey string) (int64, error) {
+	var keyIDs []uint
+	if err := db.Model(&models.UserKey{}).Where("address = ? AND public_key = ?", address, publicKey).Pluck("id", &keyIDs).Error; err != nil {
+		return 0, err
+	}
+	query := db.Unscoped().Model(&models.EncryptedContent{}).Where("user_address = ?", address)
+	if len(keyIDs) > 0 {
+		query = query.Where("key_id IS NULL OR key_id NOT IN ?", keyIDs)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// GetNonceHandler 签发登录 nonce
+// 已签发的 nonce 未过期时原样返回，过期或新地址时生成并持久化新的 nonce，新地址同时创建用户记录
 func GetNonceHandler(c *gin.Context) {
 	address := c.Query("address")
 	if address == "" {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Address is required"})
 		return
 	}
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid address"})
+		return
+	}
+	// 统一为 EIP-55 校验和格式，同一地址的不同大小写写法对应同一条记录
+	address = common.HexToAddress(address).Hex()
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	// 该接口无需认证：不能让任何人通过反复调用使用户正在签名的 nonce 失效
+	var existing models.User
+	err := db.Where("LOWER(address) = ?", strings.ToLower(address)).First(&existing).Error
+	switch {
+	case err == nil:
+		if clock().Sub(existing.NonceIssuedAt) <= loginNonceTTL(&existing) {
+			nonceResponse(c, &existing)
+			return
+		}
+		// 沿用已有记录的地址写法（早期版本未做规范化）
+		address = existing.Address
+	case err != gorm.ErrRecordNotFound:
+		serverError(c, err, "Database error")
+		return
+	}
+
+	nonce, err := utils.GenerateNonce()
+	if err != nil {
+		serverError(c, err, "Failed to generate nonce")
+		return
+	}
+
+	// 以 upsert 方式写入，同一地址的并发请求不会产生重复记录
+	user := models.User{
+		Address:       address,
+		Nonce:         nonce,
+		NonceIssuedAt: clock(),
+	}
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "address"}},
+		DoUpdates: clause.AssignmentColumns([]string{"nonce", "nonce_issued_at", "updated_at"}),
+	}).Create(&user).Error
+	if err != nil {
+		serverError(c, err, "Database error")
+		return
+	}
+
+	// 并发签发时以最终落库的 nonce 为准
+	var stored models.User
+	if err := db.Where("address = ?", address).First(&stored).Error; err != nil {
+		serverError(c, err, "Database error")
+		return
+	}
+	nonceResponse(c, &stored)
+}
+
+// nonceResponse 返回登录 nonce 及其有效期
+func nonceResponse(c *gin.Context, user *models.User) {
+	c.JSON(http.StatusOK, gin.H{
+		"nonce":      user.Nonce,
+		"address":    user.Address,
+		"issued_at":  user.NonceIssuedAt,
+		"expires_at": user.NonceIssuedAt.Add(loginNonceTTL(user)),
+	})
+}
+
+// ResetNonceHandler 用户自助重置登录 nonce，并使所有内容的待用解密 nonce 失效
+func ResetNonceHandler(c *gin.Context) {
+	var req models.ResetNonceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
 
-	db := database.GetDB()
+	db := database.GetDB().WithContext(c.Request.Context())
 
 	var user models.User
-	result := db.Where("address = ?", address).First(&user)
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(req.Address)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
 
-	var nonce string
-	if result.Error == gorm.ErrRecordNotFound {
-		// 新用户，生成 nonce
-		newNonce, err := utils.GenerateNonce()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate nonce"})
+	if accountLocked(&user) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: lockedMessage(&user)})
+		return
+	}
+
+	// 验证 nonce（防重放）
+	if user.Nonce != req.Nonce {
+		detectNonceReuse(db, c, user.Address, req.Nonce, signedBy(c, utils.GenerateNonceResetMessage(user.Address, req.Nonce), req.Signature, user.Address))
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	}
+
+	message := utils.GenerateNonceResetMessage(user.Address, req.Nonce)
+	if err := verifySignature(c.Request.Context(), "reset-nonce", message, req.Signature, user.Address, 0); err != nil {
+		rejectSignature(c, err, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	newNonce, err := utils.GenerateNonce()
+	if err != nil {
+		serverError(c, err, "Failed to generate nonce")
+		return
+	}
+
+	var reset int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		now := clock()
+		if err := tx.Model(&user).Updates(map[string]interface{}{"nonce": newNonce, "nonce_issued_at": now}).Error; err != nil {
+			return err
+		}
+
+		// 作废签发给该地址的全部解密挑战，每条内容重新签发独立的 nonce
+		if err := tx.Where("user_address = ?", user.Address).Delete(&models.DecryptChallenge{}).Error; err != nil {
+			return err
+		}
+		var contentIDs []uint
+		if err := tx.Unscoped().Model(&models.EncryptedContent{}).Where("user_address = ?", user.Address).Pluck("id", &contentIDs).Error; err != nil {
+			return err
+		}
+		for _, id := range contentIDs {
+			contentNonce, err := utils.GenerateNonce()
+			if err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Model(&models.EncryptedContent{}).Where("id = ?", id).
+				Updates(map[string]interface{}{"nonce": contentNonce, "nonce_issued_at": now}).Error; err != nil {
+				return err
+			}
+		}
+		reset = len(contentIDs)
+		return nil
+	})
+	if err != nil {
+		serverError(c, err, "Failed to reset nonce")
+		return
+	}
+	markNonceUsed(db, user.Address, req.Nonce)
+	database.MarkWrite(user.Address)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"nonce":          newNonce,
+		"contents_reset": reset,
+	})
+}
+
+// MatchSignerHandler 识别签名由哪个已注册地址产生
+func MatchSignerHandler(c *gin.Context) {
+	var req models.MatchSignerRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	recovered, err := utils.RecoverSigner(req.Message, req.Signature)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid signature"})
+		return
+	}
+
+	// 指定了候选地址时，签名者必须在候选列表中
+	if len(req.Addresses) > 0 {
+		found := false
+		for _, addr := range req.Addresses {
+			if strings.EqualFold(addr, recovered) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.JSON(http.StatusOK, gin.H{"success": true, "matched": false})
 			return
 		}
-		nonce = newNonce
-	} else if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Database error"})
+	}
+
+	db := database.GetReadDB().WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(recovered)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusOK, gin.H{"success": true, "matched": false})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"matched": true,
+		"address": user.Address,
+	})
+}
+
+// ProfileHandler 返回当前用户的资料，配置了 RPC 时附带 ENS 名称和头像
+func ProfileHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("address = ?", userAddress).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+
+	profile := gin.H{
+		"address":        user.Address,
+		"has_public_key": user.PublicKey != "",
+		"title_escrow":   user.TitleEscrow,
+		"created_at":     user.CreatedAt,
+	}
+	// ENS 解析失败不影响资料返回
+	if ens.Enabled() {
+		if info, err := ens.Lookup(c.Request.Context(), user.Address); err == nil {
+			profile["ens"] = info
+		} else {
+			requestLogger(c).Warn("ENS lookup failed", "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"profile": profile,
+	})
+}
+
+// ListKeysHandler 分页列出用户注册过的公钥及各自被内容引用的次数
+func ListKeysHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	page, pageSize := parsePagination(c)
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var total int64
+	if err := db.Model(&models.UserKey{}).Where("address = ?", userAddress).Count(&total).Error; err != nil {
+		serverError(c, err, "Failed to fetch keys")
+		return
+	}
+
+	var keys []models.UserKey
+	if err := db.Where("address = ?", userAddress).Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&keys).Error; err != nil {
+		serverError(c, err, "Failed to fetch keys")
 		return
-	} else {
-		nonce = user.Nonce
 	}
 
-	c.JSON(http.StatusOK, gin.H{"nonce": nonce})
+	// 分组统计每个公钥被引用的内容数
+	keyIDs := make([]uint, len(keys))
+	for i, key := range keys {
+		keyIDs[i] = key.ID
+	}
+	var usage []struct {
+		KeyID uint
+		Count int64
+	}
+	if len(keyIDs) > 0 {
+		if err := db.Unscoped().Model(&models.EncryptedContent{}).
+			Select("key_id, COUNT(*) AS count").
+			Where("user_address = ? AND key_id IN ?", userAddress, keyIDs).
+			Group("key_id").
+			Scan(&usage).Error; err != nil {
+			serverError(c, err, "Failed to count key usage")
+			return
+		}
+	}
+	counts := make(map[uint]int64, len(usage))
+	for _, u := range usage {
+		counts[u.KeyID] = u.Count
+	}
+
+	response := make([]models.UserKeyResponse, len(keys))
+	for i, key := range keys {
+		response[i] = models.UserKeyResponse{
+			ID:           key.ID,
+			Label:        key.Label,
+			PublicKey:    key.PublicKey,
+			Active:       key.Active,
+			ContentCount: counts[key.ID],
+			CreatedAt:    key.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+		"keys":      response,
+	})
 }
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGetNonceNormalizesAndKeepsValidNonce(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice := newWallet(t)
+	r := newRouter("")
+	r.GET("/nonce", GetNonceHandler)
+
+	nonceFor := func(address string) string {
+		t.Helper()
+		w := doJSON(t, r, http.MethodGet, "/nonce?address="+address, nil)
+		expectStatus(t, w, http.StatusOK)
+		body := decodeBody(t, w)
+		if body["address"] != alice.address {
+			t.Fatalf("address = %v, want checksum %s", body["address"], alice.address)
+		}
+		return body["nonce"].(string)
+	}
+
+	first := nonceFor(strings.ToLower(alice.address))
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{"checksum", alice.address},
+		{"uppercase", "0x" + strings.ToUpper(alice.address[2:])},
+		{"lowercase again", strings.ToLower(alice.address)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nonceFor(tt.address); got != first {
+				t.Errorf("nonce rotated to %s while %s was still valid", got, first)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("%d user rows, want 1", count)
+	}
+
+	// 过期后才签发新的 nonce
+	db.Model(&models.User{}).Where("address = ?", alice.address).Update("nonce_issued_at", time.Now().Add(-2*cfg.LoginNonceTTL))
+	if got := nonceFor(alice.address); got == first {
+		t.Errorf("expired nonce was not rotated")
+	}
+}
+
+func TestGetNonceReusesLegacyRow(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice := newWallet(t)
+	legacy := createUser(t, strings.ToLower(alice.address))
+	db.Model(&legacy).Update("nonce_issued_at", time.Now().Add(-2*cfg.LoginNonceTTL))
+
+	r := newRouter("")
+	r.GET("/nonce", GetNonceHandler)
+	r.POST("/login", LoginHandler)
+	w := doJSON(t, r, http.MethodGet, "/nonce?address="+alice.address, nil)
+	expectStatus(t, w, http.StatusOK)
+	nonce := decodeBody(t, w)["nonce"].(string)
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	if count != 1 || nonce == legacy.Nonce {
+		t.Fatalf("users = %d, nonce rotated = %v; want the legacy row refreshed", count, nonce != legacy.Nonce)
+	}
+
+	// 客户端以校验和格式登录，仍命中早期保存的小写记录
+	w = doJSON(t, r, http.MethodPost, "/login", alice.loginRequest(t, nonce))
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeBody(t, w)["address"]; got != legacy.Address {
+		t.Errorf("login address = %v, want stored %s", got, legacy.Address)
+	}
+}
+
+func TestGetNonceIssuesTheLoginNonce(t *testing.T) {
+	tests := []struct {
+		name   string
+		nonce  func(t *testing.T, issued string) string
+		status int
+	}{
+		{"issued nonce", func(t *testing.T, issued string) string { return issued }, http.StatusOK},
+		{"nonce the server never issued", func(t *testing.T, issued string) string {
+			nonce, err := utils.GenerateNonce()
+			if err != nil {
+				t.Fatal(err)
+			}
+			return nonce
+		}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			alice := newWallet(t)
+			r := newRouter("")
+			r.GET("/nonce", GetNonceHandler)
+			r.POST("/login", LoginHandler)
+
+			// 地址此前没有用户记录，GET /nonce 须保存签发的 nonce
+			w := doJSON(t, r, http.MethodGet, "/nonce?address="+alice.address, nil)
+			expectStatus(t, w, http.StatusOK)
+			issued := decodeBody(t, w)["nonce"].(string)
+			var user models.User
+			if err := database.GetDB().Where("address = ?", alice.address).First(&user).Error; err != nil {
+				t.Fatalf("no user row after GET /nonce: %v", err)
+			}
+			if user.Nonce != issued {
+				t.Fatalf("stored nonce %s, issued %s", user.Nonce, issued)
+			}
+
+			expectStatus(t, doJSON(t, r, http.MethodPost, "/login", alice.loginRequest(t, tt.nonce(t, issued))), tt.status)
+		})
+	}
+}
+
+func TestLoginRejectsReplayedSignature(t *testing.T) {
+	setupTest(t)
+	alice := newWallet(t)
+	user := createUser(t, alice.address)
+	r := newRouter("")
+	r.POST("/login", LoginHandler)
+
+	first := alice.loginRequest(t, user.Nonce)
+	expectStatus(t, doJSON(t, r, http.MethodPost, "/login", first), http.StatusOK)
+
+	var rotated models.User
+	reload(t, &rotated, user.ID)
+	custom := "Sign in to anything"
+
+	tests := []struct {
+		name string
+		req  models.LoginRequest
+	}{
+		{"replay after rotation", first},
+		{"old message with the new nonce", models.LoginRequest{Address: alice.address, Message: first.Message, Signature: first.Signature, Nonce: rotated.Nonce}},
+		{"message chosen by the client", models.LoginRequest{Address: alice.address, Message: custom, Signature: alice.sign(t, custom), Nonce: rotated.Nonce}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, doJSON(t, r, http.MethodPost, "/login", tt.req), http.StatusUnauthorized)
+		})
+	}
+
+	// 被拒绝的请求不会轮换 nonce
+	var after models.User
+	reload(t, &after, user.ID)
+	if after.Nonce != rotated.Nonce {
+		t.Errorf("nonce rotated by a rejected login")
+	}
+}
+
+func TestRegisterPublicKeyMustMatchAddress(t *testing.T) {
+	alice, bob := newWallet(t), newWallet(t)
+	compressed := hexutil.Encode(crypto.CompressPubkey(&alice.key.PublicKey))
+	tests := []struct {
+		name      string
+		publicKey string
+		status    int
+		error     string
+	}{
+		{"own uncompressed key", alice.publicKey(), http.StatusOK, ""},
+		{"own compressed key", compressed, http.StatusOK, ""},
+		{"own key without 0x prefix", strings.ToUpper(strings.TrimPrefix(alice.publicKey(), "0x")), http.StatusOK, ""},
+		{"another address's key", bob.publicKey(), http.StatusBadRequest, "Public key does not match address"},
+		{"not hex", "0xnot-a-key", http.StatusBadRequest, "Invalid public key"},
+		{"wrong length", alice.publicKey()[:100], http.StatusBadRequest, "Invalid public key"},
+		{"point not on the curve", "0x04" + strings.Repeat("11", 64), http.StatusBadRequest, "Invalid public key"},
+		{"RSA-OAEP SPKI key", "MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA" + randomBase64(t, 200), http.StatusBadRequest, "Invalid public key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			createUser(t, alice.address)
+
+			r := newRouter("")
+			r.POST("/auth/register-public-key", RegisterPublicKeyHandler)
+			message := "Register my VaultSeed public key"
+			w := doJSON(t, r, http.MethodPost, "/auth/register-public-key", models.RegisterPublicKeyRequest{
+				Address:   alice.address,
+				PublicKey: tt.publicKey,
+				Message:   message,
+				Signature: alice.sign(t, message),
+			})
+			expectStatus(t, w, tt.status)
+			if tt.error != "" && decodeBody(t, w)["error"] != tt.error {
+				t.Errorf("error = %v, want %q", decodeBody(t, w)["error"], tt.error)
+			}
+
+			var user models.User
+			if err := database.GetDB().Where("address = ?", alice.address).First(&user).Error; err != nil {
+				t.Fatal(err)
+			}
+			if registered := user.PublicKey != ""; registered != (tt.status == http.StatusOK) {
+				t.Errorf("public key registered = %v, want %v", registered, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestLoginNonceExpiry(t *testing.T) {
+	const ttl = 5 * time.Minute
+	tests := []struct {
+		name    string
+		elapsed time.Duration // GET /nonce 之后经过的时间
+		status  int
+		error   string
+	}{
+		{"fresh", 0, http.StatusOK, ""},
+		{"just inside the TTL", ttl - time.Second, http.StatusOK, ""},
+		{"exactly at the TTL", ttl, http.StatusOK, ""},
+		{"just past the TTL", ttl + time.Second, http.StatusUnauthorized, "Nonce expired"},
+		{"long expired", 24 * time.Hour, http.StatusUnauthorized, "Nonce expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) { c.LoginNonceTTL = ttl })
+			alice := newWallet(t)
+			r := newRouter("")
+			r.GET("/nonce", GetNonceHandler)
+			r.POST("/login", LoginHandler)
+
+			issued := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			setClock(t, issued)
+			w := doJSON(t, r, http.MethodGet, "/nonce?address="+alice.address, nil)
+			expectStatus(t, w, http.StatusOK)
+			nonce := decodeBody(t, w)["nonce"].(string)
+
+			setClock(t, issued.Add(tt.elapsed))
+			w = doJSON(t, r, http.MethodPost, "/login", alice.loginRequest(t, nonce))
+			expectStatus(t, w, tt.status)
+			if tt.error != "" && decodeBody(t, w)["error"] != tt.error {
+				t.Errorf("error = %v, want %q", decodeBody(t, w)["error"], tt.error)
+			}
+			if tt.status != http.StatusOK {
+				// 过期后重新获取的 nonce 可以正常登录
+				w = doJSON(t, r, http.MethodGet, "/nonce?address="+alice.address, nil)
+				expectStatus(t, w, http.StatusOK)
+				fresh := decodeBody(t, w)["nonce"].(string)
+				if fresh == nonce {
+					t.Fatal("GET /nonce returned the expired nonce")
+				}
+				expectStatus(t, doJSON(t, r, http.MethodPost, "/login", alice.loginRequest(t, fresh)), http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestLoginNonceExpiryUsesUserTTL(t *testing.T) {
+	const userTTL = time.Minute
+	tests := []struct {
+		name    string
+		elapsed time.Duration // GET /nonce 之后经过的时间，均在全局 LOGIN_NONCE_TTL 之内
+		status  int
+	}{
+		{"inside the user TTL", userTTL - time.Second, http.StatusOK},
+		{"past the user TTL", userTTL + time.Second, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) { c.LoginNonceTTL = 5 * time.Minute })
+			alice := newWallet(t)
+			user := createUser(t, alice.address)
+			issued := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+			seconds := int(userTTL / time.Second)
+			database.GetDB().Model(&user).Updates(map[string]interface{}{
+				"nonce_ttl_seconds": seconds,
+				"nonce_issued_at":   issued.Add(-time.Hour),
+			})
+			r := newRouter("")
+			r.GET("/nonce", GetNonceHandler)
+			r.POST("/login", LoginHandler)
+
+			setClock(t, issued)
+			w := doJSON(t, r, http.MethodGet, "/nonce?address="+alice.address, nil)
+			expectStatus(t, w, http.StatusOK)
+			body := decodeBody(t, w)
+			if want := issued.Add(userTTL).Format(time.RFC3339); body["expires_at"] != want {
+				t.Errorf("expires_at = %v, want %s", body["expires_at"], want)
+			}
+
+			setClock(t, issued.Add(tt.elapsed))
+			expectStatus(t, doJSON(t, r, http.MethodPost, "/login", alice.loginRequest(t, body["nonce"].(string))), tt.status)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import "github.com/gin-gonic/gin"
+
+// 批量操作中单个条目的处理状态
+const (
+	batchStatusOK      = "ok"
+	batchStatusSkipped = "skipped"
+	batchStatusError   = "error"
+)
+
+// batchItemResult partial 模式下每个条目的处理结果
+type batchItemResult struct {
+	Index  int    `json:"index"`
+	ID     *uint  `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// partialMode 批量操作是否使用 ?partial=true 逐条处理（默认整体事务）
+func partialMode(c *gin.Context) bool {
+	return c.Query("partial") == "true"
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON 绑定 JSON 请求体，请求体超出大小限制时返回 413
+func bindJSON(c *gin.Context, obj interface{}) bool {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{Error: "Request body too large"})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"time"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// issueDecryptChallenge 为地址签发一次性的解密 nonce，有效期取该用户的 nonce TTL
+// 同时清理该地址在这条内容上已过宽限期的旧记录
+func issueDecryptChallenge(db *gorm.DB, contentID uint, address string) (*models.DecryptChallenge, error) {
+	nonce, err := utils.GenerateNonce()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := db.Where("content_id = ? AND user_address = ? AND expires_at < ?", contentID, address, now.Add(-cfg.DecryptNonceGrace)).
+		Delete(&models.DecryptChallenge{}).Error; err != nil {
+		return nil, err
+	}
+	challenge := models.DecryptChallenge{
+		ContentID:   contentID,
+		Nonce:       nonce,
+		UserAddress: address,
+		ExpiresAt:   now.Add(nonceTTLFor(db, address)),
+	}
+	if err := db.Create(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// consumeDecryptChallenge 删除解密挑战，已被并发请求消费时返回 errNonceConsumed
+func consumeDecryptChallenge(db *gorm.DB, challenge *models.DecryptChallenge) error {
+	result := db.Delete(&models.DecryptChallenge{}, challenge.ID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errNonceConsumed
+	}
+	return nil
+}
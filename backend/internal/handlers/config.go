@@ -0,0 +1,13 @@
+package handlers
+
+import "vaultseed-backend/internal/config"
+
+// cfg 处理器使用的配置，启动时通过 Init 注入
+var cfg = config.Default()
+
+// Init 注入服务配置
+func Init(c *config.Config) {
+	cfg = c
+	registerConfiguredContentTypes(c.ContentTypeFields)
+	exportLimiter = newExportLimiter(c.ExportCooldown, c.ExportMaxConcurrent)
+}
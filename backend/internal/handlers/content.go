@@ -1,11 +1,21 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"vaultseed-backend/internal/config"
 	"vaultseed-backend/internal/database"
 	"vaultseed-backend/internal/models"
 	"vaultseed-backend/internal/utils"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -13,29 +23,55 @@ import (
 // CreateContentHandler 创建加密内容
 func CreateContentHandler(c *gin.Context) {
 	var req models.CreateContentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format"})
+	if !bindJSON(c, &req) {
 		return
 	}
+	createContent(c, &req, cfg.MaxEncryptedDataSize, nil)
+}
 
-	// 从 header 获取用户地址
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Missing authorization header"})
+// createContent 校验并保存一条内容，finish 非空时在同一事务中执行（例如清理分块上传记录）
+func createContent(c *gin.Context, req *models.CreateContentRequest, maxSize int64, finish func(tx *gorm.DB) error) {
+	if !checkEncryptedDataSize(c, req.EncryptedData, maxSize) {
 		return
 	}
-
-	// 简化处理：假设 token 是 address:nonce 格式
-	var userAddress string
-	if len(authHeader) > 0 {
-		// 实际应用中应该解析 token
-		userAddress = authHeader
-		if idx := len(userAddress); idx > 42 {
-			userAddress = userAddress[:42]
-		}
+	if err := utils.ValidateCiphertextFields(req.EncryptedData, req.EncryptedKey, req.IV); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validateAccessWindow(req.AccessWindowStart, req.AccessWindowEnd, req.AccessWindowTZ); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if req.Title != "" && req.EncryptedTitle != "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Provide either title or encrypted_title, not both"})
+		return
+	}
+	if err := validateContentType(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	metadata, err := json.Marshal(req.Metadata)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid metadata"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "expires_at must be in the future"})
+		return
+	}
+	if req.AvailableAt != nil && req.ExpiresAt != nil && !req.AvailableAt.Before(*req.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "available_at must be before expires_at"})
+		return
+	}
+	tags := normalizeTags(req.Tags)
+	if len(tags) > cfg.MaxTagsPerContent {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("At most %d tags are allowed per content", cfg.MaxTagsPerContent)})
+		return
 	}
 
-	db := database.GetDB()
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
 
 	// 验证用户存在
 	var user models.User
@@ -44,134 +80,421 @@ func CreateContentHandler(c *gin.Context) {
 		return
 	}
 
+	if !checkContentQuota(c, db, &user) || !checkStorageQuota(c, db, userAddress, int64(len(req.EncryptedData))) {
+		return
+	}
+
+	keyID, ok := resolveContentKey(c, db, userAddress, req.KeyID)
+	if !ok {
+		return
+	}
+
+	keyIVHash := utils.HashKeyIV(req.EncryptedKey, req.IV)
+	warnings, ok := checkIVReuse(c, db, userAddress, keyIVHash)
+	if !ok {
+		return
+	}
+
 	// 生成 nonce
 	nonce, err := utils.GenerateNonce()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate nonce"})
+		serverError(c, err, "Failed to generate nonce")
 		return
 	}
 
 	// 创建加密内容记录
 	content := models.EncryptedContent{
-		UserAddress:   userAddress,
-		Title:         req.Title,
-		EncryptedData: req.EncryptedData,
-		EncryptedKey:  req.EncryptedKey,
-		IV:            req.IV,
-		Nonce:         nonce,
+		UserAddress:    userAddress,
+		Title:          req.Title,
+		TitleEncrypted: req.EncryptedTitle != "",
+		EncryptedTitle: req.EncryptedTitle,
+		ContentType:    req.ContentType,
+		Metadata:       string(metadata),
+		EncryptedData:  req.EncryptedData,
+		EncryptedKey:   req.EncryptedKey,
+		IV:             req.IV,
+		KeyIVHash:      keyIVHash,
+		KeyID:          keyID,
+		Strength:       req.Strength,
+		Nonce:          nonce,
+		NonceIssuedAt:  time.Now(),
+
+		AccessWindowStart: req.AccessWindowStart,
+		AccessWindowEnd:   req.AccessWindowEnd,
+		AccessWindowTZ:    req.AccessWindowTZ,
+		ExpiresAt:         req.ExpiresAt,
+		AvailableAt:       req.AvailableAt,
 	}
 
-	if err := db.Create(&content).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save content"})
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&content).Error; err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := tagContent(tx, userAddress, content.ID, tags); err != nil {
+				return err
+			}
+		}
+		if finish != nil {
+			return finish(tx)
+		}
+		return nil
+	}); err != nil {
+		serverError(c, err, "Failed to save content")
 		return
 	}
+	database.MarkWrite(userAddress)
+	recordAudit(db, c, AuditContentCreate, userAddress, fmt.Sprintf("content_id=%d", content.ID))
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success": true,
 		"id":      content.ID,
-	})
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-// ListContentHandler 获取用户的内容列表
+// encryptedDataTooLarge 判断密文解码后是否超过 limit 字节
+// 直接提交的内容使用 MAX_ENCRYPTED_DATA_SIZE，分块上传和导入使用 MAX_UPLOAD_SIZE
+func encryptedDataTooLarge(data string, limit int64) bool {
+	return int64(utils.CiphertextSize(data)) > limit
+}
+
+// checkEncryptedDataSize 密文过大时返回 413
+func checkEncryptedDataSize(c *gin.Context, data string, limit int64) bool {
+	if encryptedDataTooLarge(data, limit) {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{Error: fmt.Sprintf("encrypted_data exceeds the maximum size of %d bytes", limit)})
+		return false
+	}
+	return true
+}
+
+// resolveContentKey 确定加密所用的公钥，默认为当前激活的公钥；用户尚未登记公钥时返回 nil
+func resolveContentKey(c *gin.Context, db *gorm.DB, userAddress string, requested *uint) (*uint, bool) {
+	var key models.UserKey
+	keyQuery := db.Where("address = ? AND active = ?", userAddress, true)
+	if requested != nil {
+		keyQuery = keyQuery.Where("id = ?", *requested)
+	}
+	if err := keyQuery.Order("id DESC").First(&key).Error; err == nil {
+		return &key.ID, true
+	} else if err != gorm.ErrRecordNotFound {
+		serverError(c, err, "Database error")
+		return nil, false
+	} else if requested != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Key not found or inactive"})
+		return nil, false
+	}
+	return nil, true
+}
+
+// checkIVReuse 同一 encrypted_key 下重复的 IV 说明客户端很可能重用了 IV
+// 按 IV_REUSE_POLICY 拒绝请求或返回警告
+func checkIVReuse(c *gin.Context, db *gorm.DB, userAddress, keyIVHash string) ([]string, bool) {
+	if cfg.IVReusePolicy == "off" {
+		return nil, true
+	}
+	reused, err := ivReused(db, userAddress, keyIVHash)
+	if err != nil {
+		serverError(c, err, "Database error")
+		return nil, false
+	}
+	if !reused {
+		return nil, true
+	}
+	if cfg.IVReusePolicy == "reject" {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: ivReusedMessage})
+		return nil, false
+	}
+	return []string{ivReusedMessage}, true
+}
+
+const ivReusedMessage = "IV already used with this encrypted_key"
+
+// ivReused 用户是否已有使用相同 (encrypted_key, iv) 的内容，回收站中的内容同样参与检测
+func ivReused(db *gorm.DB, userAddress, keyIVHash string) (bool, error) {
+	var reused int64
+	err := db.Unscoped().Model(&models.EncryptedContent{}).
+		Where("user_address = ? AND key_iv_hash = ?", userAddress, keyIVHash).
+		Count(&reused).Error
+	return reused > 0, err
+}
+
+// ListContentHandler 获取用户的内容列表，默认不含已归档内容，?archived=true 时只列出已归档内容
+// 可用 ?q= 按标题搜索，指定 ?page= 或 ?page_size= 时分页返回
 func ListContentHandler(c *gin.Context) {
-	// 从 header 获取用户地址
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Missing authorization header"})
+	archived := false
+	if v := c.Query("archived"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid archived"})
+			return
+		}
+		archived = parsed
+	}
+	listContent(c, archived)
+}
+
+// ListArchivedContentHandler 获取用户已归档的内容列表
+func ListArchivedContentHandler(c *gin.Context) {
+	listContent(c, true)
+}
+
+// listContent 按归档状态列出用户的内容
+func listContent(c *gin.Context, archived bool) {
+	userAddress := c.GetString("userAddress")
+
+	loc, ok := responseLocation(c)
+	if !ok {
 		return
 	}
 
-	var userAddress string
-	if len(authHeader) > 0 {
-		userAddress = authHeader
-		if idx := len(userAddress); idx > 42 {
-			userAddress = userAddress[:42]
+	// 列表查询走只读副本
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	// 查询用户的内容，可按文件夹或标签筛选
+	query, _, ok := filterContent(c, db, db.Where("user_address = ? AND archived = ?", userAddress, archived), userAddress)
+	if !ok {
+		return
+	}
+	// ?q= 按明文标题做不区分大小写的包含匹配，通配符按字面处理
+	q := strings.TrimSpace(c.Query("q"))
+	if q != "" {
+		if utf8.RuneCountInString(q) > maxSearchQueryLength {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "q must be at most 100 characters"})
+			return
 		}
+		query = query.Where("LOWER(title) LIKE ? ESCAPE '\\'", likePattern(q))
+	}
+	var contents []models.EncryptedContent
+	if err := query.Order("created_at DESC").Find(&contents).Error; err != nil {
+		serverError(c, err, "Failed to fetch content")
+		return
 	}
 
-	db := database.GetDB()
+	// 未按文件夹、标签筛选的默认列表同时包含他人共享给当前用户的内容（只读）
+	var shared []models.EncryptedContent
+	if !archived && c.Query("folder_id") == "" && c.Query("label_id") == "" && strings.TrimSpace(c.Query("tag")) == "" {
+		var err error
+		if shared, err = sharedContents(db, userAddress, q); err != nil {
+			serverError(c, err, "Failed to fetch shared content")
+			return
+		}
+	}
 
-	// 查询用户的内容
-	var contents []models.EncryptedContent
-	if err := db.Where("user_address = ?", userAddress).Order("created_at DESC").Find(&contents).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch content"})
+	labels, err := userLabels(db, userAddress)
+	if err != nil {
+		serverError(c, err, "Failed to fetch labels")
 		return
 	}
 
+	contentIDs := make([]uint, len(contents))
+	for i, content := range contents {
+		contentIDs[i] = content.ID
+	}
+	tags, err := contentTagNames(db, contentIDs)
+	if err != nil {
+		serverError(c, err, "Failed to fetch tags")
+		return
+	}
+
+	// 查询已停用的公钥，用于提示需要重新加密的内容
+	var inactiveKeyIDs []uint
+	if err := db.Model(&models.UserKey{}).Where("address = ? AND active = ?", userAddress, false).Pluck("id", &inactiveKeyIDs).Error; err != nil {
+		serverError(c, err, "Failed to fetch keys")
+		return
+	}
+	inactive := make(map[uint]bool, len(inactiveKeyIDs))
+	for _, id := range inactiveKeyIDs {
+		inactive[id] = true
+	}
+
 	// 构建响应
+	now := time.Now()
 	response := make([]models.ContentResponse, len(contents))
 	for i, content := range contents {
 		response[i] = models.ContentResponse{
-			ID:        content.ID,
-			Title:     content.Title,
-			CreatedAt: content.CreatedAt,
+			ID:             content.ID,
+			Title:          content.Title,
+			TitleEncrypted: content.TitleEncrypted,
+			EncryptedTitle: content.EncryptedTitle,
+			ContentType:    content.ContentType,
+			KeyID:          content.KeyID,
+			FolderID:       content.FolderID,
+			Strength:       content.Strength,
+			Archived:       content.Archived,
+			Version:        content.Version,
+			Tags:           tags[content.ID],
+			ExpiresAt:      inLocation(content.ExpiresAt, loc),
+			AvailableAt:    inLocation(content.AvailableAt, loc),
+			Status:         content.Status(now),
+			CreatedAt:      content.CreatedAt.In(loc),
+		}
+		if content.KeyID != nil && inactive[*content.KeyID] {
+			response[i].KeyDeactivated = true
+		}
+		if content.LabelID != nil {
+			response[i].Label = labels[*content.LabelID]
 		}
+		if response[i].Tags == nil {
+			response[i].Tags = []string{}
+		}
+	}
+	// 共享内容的文件夹、标签和公钥属于所有者，不返回给接收者
+	for _, content := range shared {
+		response = append(response, models.ContentResponse{
+			ID:             content.ID,
+			Title:          content.Title,
+			TitleEncrypted: content.TitleEncrypted,
+			EncryptedTitle: content.EncryptedTitle,
+			ContentType:    content.ContentType,
+			Strength:       content.Strength,
+			Version:        content.Version,
+			Tags:           []string{},
+			Shared:         true,
+			OwnerAddress:   content.UserAddress,
+			ExpiresAt:      inLocation(content.ExpiresAt, loc),
+			AvailableAt:    inLocation(content.AvailableAt, loc),
+			Status:         content.Status(now),
+			CreatedAt:      content.CreatedAt.In(loc),
+		})
+	}
+	if len(shared) > 0 {
+		sort.SliceStable(response, func(i, j int) bool { return response[i].CreatedAt.After(response[j].CreatedAt) })
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":  true,
-		"contents": response,
-	})
+	result := gin.H{"success": true}
+	// 指定 page 或 page_size 时分页返回；在合并共享内容并排序后截取，与 ?q= 等筛选条件同时生效
+	if c.Query("page") != "" || c.Query("page_size") != "" {
+		page, pageSize := parsePagination(c)
+		result["page"] = page
+		result["page_size"] = pageSize
+		result["total"] = len(response)
+		start := min((page-1)*pageSize, len(response))
+		response = response[start:min(start+pageSize, len(response))]
+	}
+	result["contents"] = response
+	c.JSON(http.StatusOK, result)
 }
 
 // DecryptContentHandler 解密内容
 func DecryptContentHandler(c *gin.Context) {
 	var req models.DecryptContentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request format"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	// 从 header 获取用户地址
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Missing authorization header"})
-		return
-	}
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
 
-	var userAddress string
-	if len(authHeader) > 0 {
-		userAddress = authHeader
-		if idx := len(userAddress); idx > 42 {
-			userAddress = userAddress[:42]
+	// 验证签名，失败的尝试同样记入访问历史
+	// signature_type 为 eip712 时签名的是 DecryptAuthorization{contentID, nonce}，否则为旧的文本消息
+	var err error
+	if req.SignatureType == config.SchemeEIP712 {
+		// 域中的链 ID 须是本服务配置的链，否则同一签名可在其他链的部署上复用
+		if req.ChainID != cfg.ChainID {
+			recordDecryptAttempt(db, c, req.ContentID, userAddress, false, decryptInvalidSignature)
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Chain ID mismatch"})
+			return
 		}
+		digest := utils.DecryptAuthorizationHash(req.ContentID, req.Nonce, req.ChainID)
+		err = verifyTypedSignature(c.Request.Context(), "decrypt", digest, req.Signature, userAddress)
+	} else {
+		expectedMessage := utils.GenerateDecryptMessage(req.ContentID, req.Nonce)
+		err = verifySignature(c.Request.Context(), "decrypt", expectedMessage, req.Signature, userAddress, 0)
 	}
-
-	// 验证签名
-	expectedMessage := utils.GenerateDecryptMessage(req.ContentID, req.Nonce)
-	if !utils.VerifyEthereumSignature(expectedMessage, req.Signature, userAddress) {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid signature"})
+	if err != nil {
+		recordDecryptAttempt(db, c, req.ContentID, userAddress, false, decryptInvalidSignature)
+		rejectSignature(c, err, http.StatusUnauthorized, "Invalid signature")
 		return
 	}
 
-	db := database.GetDB()
+	// 检查账户是否因重放被锁定
+	var user models.User
+	if err := db.Where("address = ?", userAddress).First(&user).Error; err == nil && accountLocked(&user) {
+		recordDecryptAttempt(db, c, req.ContentID, userAddress, false, decryptAccountLocked)
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: lockedMessage(&user)})
+		return
+	}
 
-	// 获取内容
-	var content models.EncryptedContent
-	if err := db.Where("id = ? AND user_address = ?", req.ContentID, userAddress).First(&content).Error; err != nil {
+	// 获取内容，接收者可解密共享给自己的内容
+	content, recipient, err := findAccessibleContent(db, req.ContentID, userAddress)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
+			recordDecryptAttempt(db, c, req.ContentID, userAddress, false, decryptNoAccess)
 			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
 		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch content"})
+			serverError(c, err, "Failed to fetch content")
 		}
 		return
 	}
 
-	// 验证 nonce（防重放）
-	if content.Nonce != req.Nonce {
+	// 验证 nonce（防重放）：须是签发给当前地址且尚未使用的解密挑战
+	var challenge models.DecryptChallenge
+	if err := db.Where("content_id = ? AND nonce = ? AND user_address = ?", content.ID, req.Nonce, userAddress).First(&challenge).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			serverError(c, err, "Failed to fetch nonce")
+			return
+		}
+		// 签名已在前面校验
+		detectNonceReuse(db, c, userAddress, req.Nonce, func() bool { return true })
+		recordDecryptAttempt(db, c, content.ID, userAddress, false, decryptInvalidNonce)
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
 		return
 	}
 
-	// 生成新的 nonce 并更新
-	newNonce, err := utils.GenerateNonce()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate nonce"})
+	// 过期内容不再允许解密
+	if content.Expired(time.Now()) {
+		recordDecryptAttempt(db, c, content.ID, userAddress, false, decryptExpired)
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Content expired"})
 		return
 	}
 
-	content.Nonce = newNonce
-	db.Save(&content)
+	// 定时开放的内容在开放前不可解密
+	if content.Scheduled(time.Now()) {
+		recordDecryptAttempt(db, c, content.ID, userAddress, false, decryptNotYetAvailable)
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Not yet available"})
+		return
+	}
+
+	// 验证访问时间窗口
+	if !withinAccessWindow(content, time.Now()) {
+		recordDecryptAttempt(db, c, content.ID, userAddress, false, decryptOutsideWindow)
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Outside access window"})
+		return
+	}
+
+	// 验证 nonce 时效，宽限期内仍允许使用（挑战随后即被消费）
+	switch checkNonceAge(challenge.CreatedAt, time.Now(), challenge.ExpiresAt.Sub(challenge.CreatedAt)) {
+	case nonceExpired:
+		recordDecryptAttempt(db, c, content.ID, userAddress, false, decryptNonceExpired)
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Nonce expired"})
+		return
+	case nonceInGrace:
+		recordAudit(db, c, AuditNonceGrace, userAddress, fmt.Sprintf("content_id=%d", content.ID))
+	}
+
+	// 消费挑战，同一 nonce 的并发请求只有一个能成功
+	if err := consumeDecryptChallenge(db, &challenge); err != nil {
+		if err == errNonceConsumed {
+			recordDecryptAttempt(db, c, content.ID, userAddress, false, decryptInvalidNonce)
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		} else {
+			serverError(c, err, "Failed to consume nonce")
+		}
+		return
+	}
+	markNonceUsed(db, userAddress, req.Nonce)
+	recordDecryptAttempt(db, c, content.ID, userAddress, true, "")
+
+	// 接收者拿到的是用其公钥重新包装的密钥
+	encryptedKey := content.EncryptedKey
+	if recipient != nil {
+		encryptedKey = recipient.EncryptedKey
+	}
 
 	// 返回加密数据（实际解密应该在前端进行）
 	c.JSON(http.StatusOK, gin.H{
@@ -182,10 +505,39 @@ func DecryptContentHandler(c *gin.Context) {
 			Content:   "[ENCRYPTED - DECRYPT ON CLIENT]", // 前端需要解密
 			CreatedAt: content.CreatedAt,
 		},
-		"encrypted_data": content.EncryptedData,
-		"encrypted_key":  content.EncryptedKey,
-		"iv":             content.IV,
+		"encrypted_data":  content.EncryptedData,
+		"encrypted_key":   encryptedKey,
+		"iv":              content.IV,
+		"encrypted_title": content.EncryptedTitle,
+		"shared":          recipient != nil,
+	})
+}
+
+// refreshContentNonce nonce 超出有效期时为内容签发新的 nonce，并写回 content
+// 只更新 nonce 两列，并以旧 nonce 为条件：不覆盖并发写入的其他字段，并发刷新时以先写入者为准
+// 使用 Unscoped，回收站中的内容同样可以刷新
+func refreshContentNonce(db *gorm.DB, content *models.EncryptedContent, ttl time.Duration) error {
+	if checkNonceAge(content.NonceIssuedAt, time.Now(), ttl) == nonceValid {
+		return nil
+	}
+	newNonce, err := utils.GenerateNonce()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	result := db.Unscoped().Model(content).Where("nonce = ?", content.Nonce).Updates(map[string]interface{}{
+		"nonce":           newNonce,
+		"nonce_issued_at": now,
 	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return db.Unscoped().Select("nonce", "nonce_issued_at").First(content, content.ID).Error
+	}
+	content.Nonce = newNonce
+	content.NonceIssuedAt = now
+	return nil
 }
 
 // GetContentDetailHandler 获取内容详情（包含 nonce）
@@ -196,22 +548,15 @@ func GetContentDetailHandler(c *gin.Context) {
 		return
 	}
 
-	// 从 header 获取用户地址
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Missing authorization header"})
-		return
-	}
+	userAddress := c.GetString("userAddress")
 
-	var userAddress string
-	if len(authHeader) > 0 {
-		userAddress = authHeader
-		if idx := len(userAddress); idx > 42 {
-			userAddress = userAddress[:42]
-		}
+	loc, ok := responseLocation(c)
+	if !ok {
+		return
 	}
 
-	db := database.GetDB()
+	// 详情返回更新、删除所需的 nonce 并签发解密挑战，必须读主库以免拿到副本中已轮换的旧 nonce
+	db := database.GetDB().WithContext(c.Request.Context())
 
 	// 获取内容
 	var content models.EncryptedContent
@@ -219,18 +564,594 @@ func GetContentDetailHandler(c *gin.Context) {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
 		} else {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch content"})
+			serverError(c, err, "Failed to fetch content")
 		}
 		return
 	}
 
+	// nonce 已过期时签发新的 nonce，保证客户端拿到的始终可用
+	if err := refreshContentNonce(db, &content, nonceTTLFor(db, userAddress)); err != nil {
+		serverError(c, err, "Failed to refresh nonce")
+		return
+	}
+
+	challenge, err := issueDecryptChallenge(db, content.ID, userAddress)
+	if err != nil {
+		serverError(c, err, "Failed to issue nonce")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	var label *models.Label
+	if content.LabelID != nil {
+		var found models.Label
+		if err := db.First(&found, *content.LabelID).Error; err == nil {
+			label = &found
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"content": gin.H{
-			"id":         content.ID,
-			"title":      content.Title,
-			"created_at": content.CreatedAt,
-			"nonce":      content.Nonce, // 返回 nonce 用于解密
+			"id":                 content.ID,
+			"title":              content.Title,
+			"title_encrypted":    content.TitleEncrypted,
+			"encrypted_title":    content.EncryptedTitle,
+			"content_type":       content.ContentType,
+			"metadata":           json.RawMessage(metadataOrEmpty(content.Metadata)),
+			"created_at":         content.CreatedAt.In(loc),
+			"key_id":             content.KeyID,
+			"folder_id":          content.FolderID,
+			"label":              label.Summary(),
+			"version":            content.Version, // 更新时原样提交
+			"nonce":              content.Nonce,   // 更新、删除、恢复版本时签名用
+			"decrypt_nonce":      challenge.Nonce,
+			"decrypt_expires_at": challenge.ExpiresAt.In(loc),
+
+			"access_window_start": content.AccessWindowStart,
+			"access_window_end":   content.AccessWindowEnd,
+			"access_window_tz":    content.AccessWindowTZ,
+			"expires_at":          inLocation(content.ExpiresAt, loc),
+			"available_at":        inLocation(content.AvailableAt, loc),
+			"status":              content.Status(time.Now()),
 		},
 	})
 }
+
+// DecryptChallengeHandler 签发一次性的解密 nonce 并返回待签名的消息
+// 客户端签名后直接提交到 /decrypt；之前签发且未使用的 nonce 在过期前仍然有效
+func DecryptChallengeHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	content, _, err := findAccessibleContent(db, c.Param("id"), userAddress)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	if content.Expired(time.Now()) {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Content expired"})
+		return
+	}
+
+	challenge, err := issueDecryptChallenge(db, content.ID, userAddress)
+	if err != nil {
+		serverError(c, err, "Failed to issue nonce")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"content_id": content.ID,
+		"nonce":      challenge.Nonce,
+		"message":    utils.GenerateDecryptMessage(content.ID, challenge.Nonce),
+		"expires_at": challenge.ExpiresAt,
+	})
+}
+
+// TransferContentHandler 将当前用户的全部内容（含回收站）及文件夹、标签、分享链接、接收者转移到新的钱包地址
+// 内容的重新加密由客户端完成，这里只更新归属地址；key_id 指向旧地址的公钥，转移后清空
+func TransferContentHandler(c *gin.Context) {
+	var req models.TransferContentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	// 验证地址格式
+	if !common.IsHexAddress(userAddress) || !common.IsHexAddress(req.NewAddress) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid address"})
+		return
+	}
+	if strings.EqualFold(userAddress, req.NewAddress) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "New address must differ from current address"})
+		return
+	}
+	if !requireFreshAuth(c, "transfer", userAddress) {
+		return
+	}
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("address = ?", userAddress).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	if accountLocked(&user) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: lockedMessage(&user)})
+		return
+	}
+
+	// 验证 nonce（防重放）
+	if user.Nonce != req.Nonce {
+		detectNonceReuse(db, c, userAddress, req.Nonce, signedBy(c, utils.GenerateTransferMessage(userAddress, req.NewAddress, req.Nonce), req.CurrentSignature, userAddress))
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	}
+
+	// 新旧地址都必须对同一条消息签名
+	message := utils.GenerateTransferMessage(userAddress, req.NewAddress, req.Nonce)
+	if err := verifySignature(c.Request.Context(), "transfer", message, req.CurrentSignature, userAddress, 0); err != nil {
+		rejectSignature(c, err, http.StatusUnauthorized, "Invalid current owner signature")
+		return
+	}
+	if err := verifySignature(c.Request.Context(), "transfer", message, req.NewSignature, req.NewAddress, 0); err != nil {
+		rejectSignature(c, err, http.StatusUnauthorized, "Invalid new owner signature")
+		return
+	}
+
+	newNonce, err := utils.GenerateNonce()
+	if err != nil {
+		serverError(c, err, "Failed to generate nonce")
+		return
+	}
+
+	var transferred int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 确保新地址存在用户记录
+		var target models.User
+		if err := tx.Where("address = ?", req.NewAddress).First(&target).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				return err
+			}
+			targetNonce, err := utils.GenerateNonce()
+			if err != nil {
+				return err
+			}
+			target = models.User{Address: req.NewAddress, Nonce: targetNonce, NonceIssuedAt: time.Now()}
+			if err := tx.Create(&target).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := checkTransferQuota(tx, userAddress, &target); err != nil {
+			return err
+		}
+
+		n, err := transferOwnedRows(tx, userAddress, req.NewAddress)
+		if err != nil {
+			return err
+		}
+		transferred = n
+
+		// 轮换旧地址的 nonce，使签名不可重放
+		return tx.Model(&user).Updates(map[string]interface{}{"nonce": newNonce, "nonce_issued_at": time.Now()}).Error
+	})
+	if err == nil {
+		markNonceUsed(db, userAddress, req.Nonce)
+		database.MarkWrite(userAddress)
+		database.MarkWrite(req.NewAddress)
+	}
+	var quota *quotaExceededError
+	var storage *storageExceededError
+	if errors.As(err, &quota) || errors.As(err, &storage) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err != nil {
+		serverError(c, err, "Failed to transfer content")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"new_address": req.NewAddress,
+		"transferred": transferred,
+	})
+}
+
+// checkTransferQuota 检查转移 from 的全部内容（含回收站）后新地址是否仍在条数和存储配额内
+func checkTransferQuota(tx *gorm.DB, from string, target *models.User) error {
+	count, bytes, err := contentUsage(tx, from)
+	if err != nil {
+		return err
+	}
+	limit, remaining, err := remainingContent(tx, target)
+	if err != nil {
+		return err
+	}
+	if remaining >= 0 && count > remaining {
+		return &quotaExceededError{limit: limit}
+	}
+	storage, err := remainingStorage(tx, target.Address)
+	if err != nil {
+		return err
+	}
+	if storage >= 0 && bytes > storage {
+		return &storageExceededError{limit: cfg.MaxStoragePerUser}
+	}
+	return nil
+}
+
+// transferOwnedRows 将 from 拥有的全部行改为归属 to，返回转移的内容条数，须在事务中调用
+// 新地址已有同名标签时合并到该标签；新地址原本是接收者的共享记录随之删除
+func transferOwnedRows(tx *gorm.DB, from, to string) (int64, error) {
+	var contentIDs []uint
+	if err := tx.Unscoped().Model(&models.EncryptedContent{}).Where("user_address = ?", from).Pluck("id", &contentIDs).Error; err != nil {
+		return 0, err
+	}
+
+	result := tx.Unscoped().Model(&models.EncryptedContent{}).
+		Where("user_address = ?", from).
+		Updates(map[string]interface{}{"user_address": to, "key_id": nil})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if len(contentIDs) > 0 {
+		if err := tx.Model(&models.ContentRevision{}).Where("content_id IN ?", contentIDs).Update("key_id", nil).Error; err != nil {
+			return 0, err
+		}
+		if err := tx.Where("content_id IN ? AND LOWER(recipient_address) = ?", contentIDs, strings.ToLower(to)).
+			Delete(&models.ContentRecipient{}).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	owned := []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.Folder{}, "owner_address"},
+		{&models.Label{}, "address"},
+		{&models.ShareLink{}, "owner_address"},
+		{&models.ContentRecipient{}, "owner_address"},
+	}
+	for _, o := range owned {
+		if err := tx.Model(o.model).Where(o.column+" = ?", from).Update(o.column, to).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	var tags []models.Tag
+	if err := tx.Where("address = ?", from).Find(&tags).Error; err != nil {
+		return 0, err
+	}
+	for _, tag := range tags {
+		var existing models.Tag
+		err := tx.Where("address = ? AND name = ?", to, tag.Name).First(&existing).Error
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			if err := tx.Model(&tag).Update("address", to).Error; err != nil {
+				return 0, err
+			}
+		case err != nil:
+			return 0, err
+		default:
+			if err := tx.Model(&models.ContentTag{}).Where("tag_id = ?", tag.ID).Update("tag_id", existing.ID).Error; err != nil {
+				return 0, err
+			}
+			if err := tx.Delete(&tag).Error; err != nil {
+				return 0, err
+			}
+		}
+	}
+	return result.RowsAffected, nil
+}
+
+// ListContentByKeyHandler 列出仍引用指定公钥的内容，便于停用前重新加密
+func ListContentByKeyHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	// 验证公钥归属
+	var key models.UserKey
+	if err := db.Where("id = ? AND address = ?", c.Param("key_id"), userAddress).First(&key).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Key not found"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+
+	var contents []models.EncryptedContent
+	if err := db.Where("user_address = ? AND key_id = ?", userAddress, key.ID).Order("created_at DESC").Find(&contents).Error; err != nil {
+		serverError(c, err, "Failed to fetch content")
+		return
+	}
+
+	response := make([]models.ContentResponse, len(contents))
+	for i, content := range contents {
+		response[i] = models.ContentResponse{
+			ID:             content.ID,
+			Title:          content.Title,
+			TitleEncrypted: content.TitleEncrypted,
+			EncryptedTitle: content.EncryptedTitle,
+			ContentType:    content.ContentType,
+			KeyID:          content.KeyID,
+			KeyDeactivated: !key.Active,
+			Status:         content.Status(time.Now()),
+			CreatedAt:      content.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"key_id":   key.ID,
+		"active":   key.Active,
+		"contents": response,
+	})
+}
+
+// DeleteContentHandler 删除单条内容：首次删除移入回收站，对回收站中的内容再次删除则永久删除
+// 需要对 GenerateDeleteMessage(content_id, 当前内容 nonce) 签名，防止跨站请求伪造；不存在或不属于当前用户时返回 404
+func DeleteContentHandler(c *gin.Context) {
+	var req models.DeleteContentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var content models.EncryptedContent
+	if err := db.Unscoped().Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+	permanent := content.DeletedAt.Valid
+
+	// 验证 nonce（防重放）
+	if content.Nonce != req.Nonce {
+		detectNonceReuse(db, c, userAddress, req.Nonce, signedBy(c, utils.GenerateDeleteMessage(content.ID, req.Nonce), req.Signature, userAddress))
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	}
+	if checkNonceAge(content.NonceIssuedAt, time.Now(), nonceTTLFor(db, userAddress)) == nonceExpired {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Nonce expired"})
+		return
+	}
+
+	message := utils.GenerateDeleteMessage(content.ID, req.Nonce)
+	if err := verifySignature(c.Request.Context(), "delete", message, req.Signature, userAddress, 0); err != nil {
+		rejectSignature(c, err, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+
+	if !permanent {
+		// 软删除：分享链接、接收者和标签保留，恢复后继续生效
+		// 同时轮换 nonce，避免本次签名被重放用于永久删除
+		newNonce, err := utils.GenerateNonce()
+		if err != nil {
+			serverError(c, err, "Failed to generate nonce")
+			return
+		}
+		now := time.Now()
+		if err := db.Model(&content).Updates(map[string]interface{}{
+			"deleted_at":      now,
+			"nonce":           newNonce,
+			"nonce_issued_at": now,
+		}).Error; err != nil {
+			serverError(c, err, "Failed to delete content")
+			return
+		}
+		database.MarkWrite(userAddress)
+		markNonceUsed(db, userAddress, req.Nonce)
+		recordAudit(db, c, AuditContentTrash, userAddress, fmt.Sprintf("content_id=%d", content.ID))
+
+		c.JSON(http.StatusOK, gin.H{"success": true, "trashed": true})
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return deleteContentRows(tx, []uint{content.ID})
+	}); err != nil {
+		serverError(c, err, "Failed to delete content")
+		return
+	}
+	database.MarkWrite(userAddress)
+	markNonceUsed(db, userAddress, req.Nonce)
+
+	detail := fmt.Sprintf("content_id=%d", content.ID)
+	attestation, err := utils.SignDeletion(userAddress, []uint{content.ID}, time.Now())
+	if err == nil {
+		detail += " attestation=" + attestation.Reference()
+	} else if err != utils.ErrNoServerKey {
+		requestLogger(c).Error("Failed to sign deletion attestation", "error", err)
+	}
+	recordAudit(db, c, AuditContentDelete, userAddress, detail)
+
+	response := gin.H{"success": true, "trashed": false}
+	if attestation != nil {
+		response["attestation"] = attestation
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateContentHandler 更新内容的标题和加密正文，保持内容 ID 不变
+// 需要对 GenerateUpdateMessage(content_id, 当前内容 nonce) 签名；成功后轮换 nonce
+func UpdateContentHandler(c *gin.Context) {
+	var req models.UpdateContentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !checkEncryptedDataSize(c, req.EncryptedData, cfg.MaxEncryptedDataSize) {
+		return
+	}
+	if err := utils.ValidateCiphertextFields(req.EncryptedData, req.EncryptedKey, req.IV); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	if content.Expired(time.Now()) {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Content expired"})
+		return
+	}
+	if req.Title != "" && content.TitleEncrypted {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Content title is encrypted"})
+		return
+	}
+
+	// 乐观并发控制：客户端读取后内容已被其他设备修改时拒绝覆盖
+	if req.Version != content.Version {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           "Content has been modified since it was read",
+			"current_version": content.Version,
+		})
+		return
+	}
+
+	// 验证 nonce（防重放）
+	if content.Nonce != req.Nonce {
+		detectNonceReuse(db, c, userAddress, req.Nonce, signedBy(c, utils.GenerateUpdateMessage(content.ID, req.Nonce), req.Signature, content.UserAddress))
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	}
+	if checkNonceAge(content.NonceIssuedAt, time.Now(), nonceTTLFor(db, userAddress)) == nonceExpired {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Nonce expired"})
+		return
+	}
+
+	// 签名必须来自内容所有者
+	message := utils.GenerateUpdateMessage(content.ID, req.Nonce)
+	if err := verifySignature(c.Request.Context(), "update", message, req.Signature, content.UserAddress, 0); err != nil {
+		rejectSignature(c, err, http.StatusForbidden, "Signature does not match the content owner")
+		return
+	}
+
+	if !checkStorageQuota(c, db, userAddress, int64(len(req.EncryptedData)-len(content.EncryptedData))) {
+		return
+	}
+	keyID, ok := resolveContentKey(c, db, userAddress, req.KeyID)
+	if !ok {
+		return
+	}
+	keyIVHash := utils.HashKeyIV(req.EncryptedKey, req.IV)
+	warnings, ok := checkIVReuse(c, db, userAddress, keyIVHash)
+	if !ok {
+		return
+	}
+
+	newNonce, err := utils.GenerateNonce()
+	if err != nil {
+		serverError(c, err, "Failed to generate nonce")
+		return
+	}
+
+	updates := map[string]interface{}{
+		"encrypted_data":  req.EncryptedData,
+		"encrypted_key":   req.EncryptedKey,
+		"iv":              req.IV,
+		"key_iv_hash":     keyIVHash,
+		"key_id":          keyID,
+		"version":         content.Version + 1,
+		"nonce":           newNonce,
+		"nonce_issued_at": time.Now(),
+	}
+	if req.Title != "" {
+		updates["title"] = req.Title
+	}
+	// 以旧 nonce 和版本号为条件更新，并发请求中只有一个能成功；被替换的正文保存为历史版本
+	var revoked revokedShares
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.EncryptedContent{}).
+			Where("id = ? AND nonce = ? AND version = ?", content.ID, req.Nonce, req.Version).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errNonceConsumed
+		}
+		if revoked, err = revokeSharesOnKeyChange(tx, content.ID, content.EncryptedKey, req.EncryptedKey); err != nil {
+			return err
+		}
+		return saveRevision(tx, &content)
+	})
+	switch {
+	case err == errNonceConsumed:
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	case err != nil:
+		serverError(c, err, "Failed to update content")
+		return
+	}
+	database.MarkWrite(userAddress)
+	markNonceUsed(db, userAddress, req.Nonce)
+	recordAudit(db, c, AuditContentUpdate, userAddress, fmt.Sprintf("content_id=%d", content.ID))
+
+	response := gin.H{
+		"success":        true,
+		"id":             content.ID,
+		"version":        content.Version + 1,
+		"revoked_shares": revoked,
+	}
+	if revoked.Recipients+revoked.ShareLinks > 0 {
+		warnings = append(warnings, sharesRevokedWarning(revoked))
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// deleteContentRows 永久删除内容（包括回收站中的）及其分享链接、接收者和标签关联
+// 解密记录不删除：保留原 content_id 作为事后审计依据（内容 ID 不会复用），只在管理员清除用户数据时删除
+func deleteContentRows(tx *gorm.DB, ids []uint) error {
+	if err := tx.Where("content_id IN ?", ids).Delete(&models.ShareLink{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("content_id IN ?", ids).Delete(&models.ContentRecipient{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("content_id IN ?", ids).Delete(&models.ContentTag{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("content_id IN ?", ids).Delete(&models.ContentRevision{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("content_id IN ?", ids).Delete(&models.DecryptChallenge{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("id IN ?", ids).Delete(&models.EncryptedContent{}).Error
+}
@@ -0,0 +1,503 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/middleware"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+func TestTransferContentMovesAllOwnedRows(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice, bob, carol := newWallet(t), newWallet(t), newWallet(t)
+	user := createUser(t, alice.address)
+	createUser(t, bob.address)
+
+	key := models.UserKey{Address: alice.address, PublicKey: alice.publicKey(), Active: true}
+	db.Create(&key)
+	folder := models.Folder{OwnerAddress: alice.address, Name: "wallets"}
+	db.Create(&folder)
+	label := models.Label{Address: alice.address, Name: "red", Color: "#ff0000"}
+	db.Create(&label)
+
+	live := seedContent(t, alice.address, func(c *models.EncryptedContent) {
+		c.KeyID = &key.ID
+		c.FolderID = &folder.ID
+		c.LabelID = &label.ID
+	})
+	trashed := seedContent(t, alice.address, func(c *models.EncryptedContent) {
+		c.KeyID = &key.ID
+		c.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	})
+	db.Create(&models.ContentRevision{ContentID: live.ID, Version: 1, EncryptedData: live.EncryptedData, EncryptedKey: live.EncryptedKey, IV: live.IV, KeyID: &key.ID})
+
+	// 同名标签合并到新地址已有的标签
+	db.Transaction(func(tx *gorm.DB) error { return tagContent(tx, alice.address, live.ID, []string{"work", "seed"}) })
+	var bobWork models.Tag
+	db.Transaction(func(tx *gorm.DB) error {
+		tags, err := ensureTags(tx, bob.address, []string{"work"})
+		bobWork = tags[0]
+		return err
+	})
+
+	link := models.ShareLink{Token: "tok", ContentID: live.ID, OwnerAddress: alice.address, EncryptedKey: randomBase64(t, 32), Active: true}
+	db.Create(&link)
+	toCarol := models.ContentRecipient{ContentID: live.ID, OwnerAddress: alice.address, RecipientAddress: carol.address, EncryptedKey: randomBase64(t, 32)}
+	db.Create(&toCarol)
+	toBob := models.ContentRecipient{ContentID: live.ID, OwnerAddress: alice.address, RecipientAddress: bob.address, EncryptedKey: randomBase64(t, 32)}
+	db.Create(&toBob)
+
+	message := utils.GenerateTransferMessage(alice.address, bob.address, user.Nonce)
+	r := newRouter(alice.address)
+	r.POST("/transfer", TransferContentHandler)
+	w := doJSON(t, r, http.MethodPost, "/transfer", models.TransferContentRequest{
+		NewAddress:       bob.address,
+		Nonce:            user.Nonce,
+		CurrentSignature: alice.sign(t, message),
+		NewSignature:     bob.sign(t, message),
+	})
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeBody(t, w)["transferred"]; got != float64(2) {
+		t.Fatalf("transferred = %v, want 2 (trashed rows included)", got)
+	}
+
+	for _, id := range []uint{live.ID, trashed.ID} {
+		var c models.EncryptedContent
+		reload(t, &c, id)
+		if c.UserAddress != bob.address || c.KeyID != nil {
+			t.Errorf("content %d: owner %s key_id %v, want %s and nil", id, c.UserAddress, c.KeyID, bob.address)
+		}
+	}
+	var rev models.ContentRevision
+	db.Where("content_id = ?", live.ID).First(&rev)
+	if rev.KeyID != nil {
+		t.Errorf("revision key_id = %v, want nil", *rev.KeyID)
+	}
+
+	reload(t, &folder, folder.ID)
+	reload(t, &label, label.ID)
+	reload(t, &link, link.ID)
+	reload(t, &toCarol, toCarol.ID)
+	if folder.OwnerAddress != bob.address || label.Address != bob.address || link.OwnerAddress != bob.address || toCarol.OwnerAddress != bob.address {
+		t.Errorf("owned rows not moved: folder %s label %s link %s recipient %s", folder.OwnerAddress, label.Address, link.OwnerAddress, toCarol.OwnerAddress)
+	}
+	if err := db.First(&models.ContentRecipient{}, toBob.ID).Error; err != gorm.ErrRecordNotFound {
+		t.Errorf("recipient row for the new owner should be removed, got %v", err)
+	}
+
+	var names []string
+	db.Model(&models.Tag{}).Where("address = ?", alice.address).Pluck("name", &names)
+	if len(names) != 0 {
+		t.Errorf("old owner still has tags %v", names)
+	}
+	tags, _ := contentTagNames(db, []uint{live.ID})
+	if got := tags[live.ID]; len(got) != 2 || got[0] != "seed" || got[1] != "work" {
+		t.Errorf("tags after transfer = %v", got)
+	}
+	var merged int64
+	db.Model(&models.ContentTag{}).Where("content_id = ? AND tag_id = ?", live.ID, bobWork.ID).Count(&merged)
+	if merged != 1 {
+		t.Errorf("content should be tagged with the new owner's existing tag")
+	}
+
+	// 旧所有者不能再管理已转移的接收者
+	r = newRouter(alice.address)
+	r.DELETE("/content/:id/recipients/:address", RemoveRecipientHandler)
+	w = doJSON(t, r, http.MethodDelete, "/content/"+itoa(live.ID)+"/recipients/"+carol.address, nil)
+	expectStatus(t, w, http.StatusNotFound)
+}
+
+func TestContentDetailRefreshesNonceWithoutClobbering(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	content := seedContent(t, alice.address, func(c *models.EncryptedContent) {
+		c.NonceIssuedAt = time.Now().Add(-48 * time.Hour)
+	})
+
+	// 处理器读出内容后、刷新 nonce 前，模拟一次并发更新
+	concurrent := randomBase64(t, 64)
+	fired := false
+	db.Callback().Query().After("gorm:query").Register("test:concurrent_update", func(tx *gorm.DB) {
+		if fired || tx.Statement.Table != "encrypted_contents" {
+			return
+		}
+		fired = true
+		tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Exec("UPDATE encrypted_contents SET encrypted_data = ? WHERE id = ?", concurrent, content.ID)
+	})
+
+	r := newRouter(alice.address)
+	r.GET("/content/:id", GetContentDetailHandler)
+	w := doJSON(t, r, http.MethodGet, "/content/"+itoa(content.ID), nil)
+	expectStatus(t, w, http.StatusOK)
+	nonce := decodeBody(t, w)["content"].(map[string]interface{})["nonce"]
+
+	var stored models.EncryptedContent
+	reload(t, &stored, content.ID)
+	if stored.Nonce == content.Nonce || nonce != stored.Nonce {
+		t.Errorf("nonce not refreshed: returned %v, stored %s, old %s", nonce, stored.Nonce, content.Nonce)
+	}
+	if stored.EncryptedData != concurrent {
+		t.Errorf("nonce refresh overwrote a concurrent update of encrypted_data")
+	}
+}
+
+func TestDeleteContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		asOther     bool // 以非所有者身份请求
+		signedOther bool // 由非所有者签名
+		missing     bool // 请求不存在的 ID
+		status      int
+	}{
+		{"owner", false, false, false, http.StatusOK},
+		{"not owner", true, true, false, http.StatusNotFound},
+		{"not found", false, false, true, http.StatusNotFound},
+		{"signed by someone else", false, true, false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			owner, other := newWallet(t), newWallet(t)
+			createUser(t, owner.address)
+			createUser(t, other.address)
+			content := seedContent(t, owner.address)
+
+			as, signer, id := owner, owner, content.ID
+			if tt.asOther {
+				as = other
+			}
+			if tt.signedOther {
+				signer = other
+			}
+			if tt.missing {
+				id += 100
+			}
+
+			r := newRouter(as.address)
+			r.DELETE("/content/:id", DeleteContentHandler)
+			w := doJSON(t, r, http.MethodDelete, "/content/"+itoa(id), models.DeleteContentRequest{
+				Nonce:     content.Nonce,
+				Signature: signer.sign(t, utils.GenerateDeleteMessage(id, content.Nonce)),
+			})
+			expectStatus(t, w, tt.status)
+			if tt.status == http.StatusOK && decodeBody(t, w)["success"] != true {
+				t.Errorf("body %s", w.Body.String())
+			}
+
+			var stored models.EncryptedContent
+			reload(t, &stored, content.ID)
+			if deleted := tt.status == http.StatusOK; stored.DeletedAt.Valid != deleted {
+				t.Errorf("deleted = %v, want %v", stored.DeletedAt.Valid, deleted)
+			}
+		})
+	}
+}
+
+func TestPermanentDeleteAfterNonceAgesInTrash(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	content := seedContent(t, alice.address)
+
+	// 回收站中停留时间超过 DECRYPT_NONCE_TTL 加宽限期
+	db.Model(&content).Updates(map[string]interface{}{
+		"deleted_at":      time.Now().Add(-time.Hour),
+		"nonce_issued_at": time.Now().Add(-time.Hour),
+	})
+
+	r := newRouter(alice.address)
+	r.GET("/trash", ListTrashHandler)
+	r.DELETE("/content/:id", DeleteContentHandler)
+	w := doJSON(t, r, http.MethodGet, "/trash", nil)
+	expectStatus(t, w, http.StatusOK)
+	items := decodeBody(t, w)["items"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("trash = %s", w.Body.String())
+	}
+	nonce := items[0].(map[string]interface{})["nonce"].(string)
+	if nonce == content.Nonce {
+		t.Fatalf("expired nonce was not refreshed")
+	}
+
+	w = doJSON(t, r, http.MethodDelete, "/content/"+itoa(content.ID), models.DeleteContentRequest{
+		Nonce:     nonce,
+		Signature: alice.sign(t, utils.GenerateDeleteMessage(content.ID, nonce)),
+	})
+	expectStatus(t, w, http.StatusOK)
+	var count int64
+	db.Unscoped().Model(&models.EncryptedContent{}).Where("id = ?", content.ID).Count(&count)
+	if count != 0 {
+		t.Errorf("content was not permanently deleted")
+	}
+}
+
+func TestUpdateContentRequiresOwnerSignature(t *testing.T) {
+	tests := []struct {
+		name        string
+		asOther     bool // 以非所有者身份请求
+		signedOther bool // 由非所有者签名
+		status      int
+	}{
+		{"owner", false, false, http.StatusOK},
+		{"signed by a different address", false, true, http.StatusForbidden},
+		{"not owner", true, true, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			owner, other := newWallet(t), newWallet(t)
+			createUser(t, owner.address)
+			createUser(t, other.address)
+			content := seedContent(t, owner.address)
+
+			as, signer := owner, owner
+			if tt.asOther {
+				as = other
+			}
+			if tt.signedOther {
+				signer = other
+			}
+
+			r := newRouter(as.address)
+			r.PUT("/content/:id", UpdateContentHandler)
+			w := doJSON(t, r, http.MethodPut, "/content/"+itoa(content.ID), models.UpdateContentRequest{
+				EncryptedData: randomBase64(t, 64),
+				EncryptedKey:  randomBase64(t, 32),
+				IV:            randomBase64(t, 12),
+				Version:       content.Version,
+				Nonce:         content.Nonce,
+				Signature:     signer.sign(t, utils.GenerateUpdateMessage(content.ID, content.Nonce)),
+			})
+			expectStatus(t, w, tt.status)
+
+			var stored models.EncryptedContent
+			reload(t, &stored, content.ID)
+			if updated := stored.EncryptedData != content.EncryptedData; updated != (tt.status == http.StatusOK) {
+				t.Errorf("updated = %v, want %v", updated, tt.status == http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestKeyChangeRevokesShares(t *testing.T) {
+	tests := []struct {
+		name       string
+		restore    bool // 通过恢复历史版本替换正文
+		keepKey    bool // 新正文沿用原对称密钥
+		wantShares bool
+	}{
+		{"update with new key", false, false, false},
+		{"update with same key", false, true, true},
+		{"restore with different key", true, false, false},
+		{"restore with same key", true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			db := database.GetDB()
+			alice, bob := newWallet(t), newWallet(t)
+			createUser(t, alice.address)
+			content := seedContent(t, alice.address, func(c *models.EncryptedContent) { c.Version = 2 })
+
+			recipient := models.ContentRecipient{ContentID: content.ID, OwnerAddress: alice.address, RecipientAddress: bob.address, EncryptedKey: randomBase64(t, 32)}
+			link := models.ShareLink{Token: "key-change-token-0123456789", ContentID: content.ID, OwnerAddress: alice.address, EncryptedKey: randomBase64(t, 32), Active: true}
+			for _, row := range []interface{}{&recipient, &link} {
+				if err := db.Create(row).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			newKey := randomBase64(t, 32)
+			if tt.keepKey {
+				newKey = content.EncryptedKey
+			}
+			r := newRouter(alice.address)
+			var w *httptest.ResponseRecorder
+			if tt.restore {
+				revision := models.ContentRevision{ContentID: content.ID, Version: 1, EncryptedData: randomBase64(t, 64), EncryptedKey: newKey, IV: randomBase64(t, 12)}
+				if err := db.Create(&revision).Error; err != nil {
+					t.Fatal(err)
+				}
+				r.POST("/content/:id/restore/:version", RestoreRevisionHandler)
+				w = doJSON(t, r, http.MethodPost, "/content/"+itoa(content.ID)+"/restore/1", models.RestoreRevisionRequest{
+					Nonce:     content.Nonce,
+					Signature: alice.sign(t, utils.GenerateRestoreRevisionMessage(content.ID, 1, content.Nonce)),
+				})
+			} else {
+				r.PUT("/content/:id", UpdateContentHandler)
+				w = doJSON(t, r, http.MethodPut, "/content/"+itoa(content.ID), models.UpdateContentRequest{
+					EncryptedData: randomBase64(t, 64),
+					EncryptedKey:  newKey,
+					IV:            randomBase64(t, 12),
+					Version:       content.Version,
+					Nonce:         content.Nonce,
+					Signature:     alice.sign(t, utils.GenerateUpdateMessage(content.ID, content.Nonce)),
+				})
+			}
+			expectStatus(t, w, http.StatusOK)
+
+			var recipients int64
+			db.Model(&models.ContentRecipient{}).Where("content_id = ?", content.ID).Count(&recipients)
+			reload(t, &link, link.ID)
+			if kept := recipients == 1 && link.Active; kept != tt.wantShares {
+				t.Errorf("recipients = %d, link active = %v, want shares kept = %v", recipients, link.Active, tt.wantShares)
+			}
+			if _, warned := decodeBody(t, w)["warnings"]; warned == tt.wantShares {
+				t.Errorf("warnings present = %v, want %v: %s", warned, !tt.wantShares, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCreateContentSizeBoundary(t *testing.T) {
+	tests := []struct {
+		name      string
+		dataBytes int
+		status    int
+	}{
+		{"at MAX_ENCRYPTED_DATA_SIZE", 1 << 20, http.StatusOK},
+		{"one byte over", 1<<20 + 1, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 使用默认配置：CREATE_BODY_LIMIT 须能容纳 base64 编码后达到上限的密文
+			c := setupTest(t)
+			alice := newWallet(t)
+			createUser(t, alice.address)
+
+			r := newRouter(alice.address)
+			r.POST("/content/create", middleware.MaxBodySize(c.CreateBodyLimit), CreateContentHandler)
+			w := doJSON(t, r, http.MethodPost, "/content/create", models.CreateContentRequest{
+				ContentFields: models.ContentFields{
+					Title:        strings.Repeat("t", 100),
+					EncryptedKey: randomBase64(t, 512),
+					IV:           randomBase64(t, 12),
+					Metadata:     map[string]string{"note": strings.Repeat("m", 4096)},
+				},
+				EncryptedData: randomBase64(t, tt.dataBytes),
+			})
+			expectStatus(t, w, tt.status)
+			if tt.status == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), "encrypted_data exceeds") {
+				t.Errorf("rejected by the body limit instead of the data size check: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMalformedCiphertextRejected(t *testing.T) {
+	setupTest(t)
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	content := seedContent(t, alice.address)
+
+	r := newRouter(alice.address)
+	r.POST("/content/create", CreateContentHandler)
+	r.PUT("/content/:id", UpdateContentHandler)
+
+	malformed := map[string][3]string{
+		"encrypted_data": {"not base64!", randomBase64(t, 32), randomBase64(t, 12)},
+		"encrypted_key":  {randomBase64(t, 64), "not a key!", randomBase64(t, 12)},
+		"iv":             {randomBase64(t, 64), randomBase64(t, 32), randomBase64(t, 8)},
+	}
+	for field, values := range malformed {
+		t.Run(field, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodPost, "/content/create", models.CreateContentRequest{
+				ContentFields: models.ContentFields{Title: "entry", EncryptedKey: values[1], IV: values[2]},
+				EncryptedData: values[0],
+			})
+			expectStatus(t, w, http.StatusBadRequest)
+
+			w = doJSON(t, r, http.MethodPut, "/content/"+itoa(content.ID), models.UpdateContentRequest{
+				EncryptedData: values[0],
+				EncryptedKey:  values[1],
+				IV:            values[2],
+				Version:       content.Version,
+				Nonce:         content.Nonce,
+				Signature:     alice.sign(t, utils.GenerateUpdateMessage(content.ID, content.Nonce)),
+			})
+			expectStatus(t, w, http.StatusBadRequest)
+			if !strings.Contains(decodeBody(t, w)["error"].(string), field) {
+				t.Errorf("error %s does not name %s", w.Body.String(), field)
+			}
+		})
+	}
+
+	var count int64
+	database.GetDB().Model(&models.EncryptedContent{}).Count(&count)
+	var stored models.EncryptedContent
+	reload(t, &stored, content.ID)
+	if count != 1 || stored.EncryptedData != content.EncryptedData {
+		t.Errorf("malformed input was saved: %d rows, data changed = %v", count, stored.EncryptedData != content.EncryptedData)
+	}
+}
+
+func TestStaleVersionUpdateConflicts(t *testing.T) {
+	setupTest(t)
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	content := seedContent(t, alice.address)
+
+	r := newRouter(alice.address)
+	r.GET("/content/:id", GetContentDetailHandler)
+	r.PUT("/content/:id", UpdateContentHandler)
+	read := func() (version int, nonce string) {
+		t.Helper()
+		w := doJSON(t, r, http.MethodGet, "/content/"+itoa(content.ID), nil)
+		expectStatus(t, w, http.StatusOK)
+		detail := decodeBody(t, w)["content"].(map[string]interface{})
+		return int(detail["version"].(float64)), detail["nonce"].(string)
+	}
+	update := func(version int, nonce string) *httptest.ResponseRecorder {
+		t.Helper()
+		return doJSON(t, r, http.MethodPut, "/content/"+itoa(content.ID), models.UpdateContentRequest{
+			EncryptedData: randomBase64(t, 64),
+			EncryptedKey:  content.EncryptedKey,
+			IV:            randomBase64(t, 12),
+			Version:       version,
+			Nonce:         nonce,
+			Signature:     alice.sign(t, utils.GenerateUpdateMessage(content.ID, nonce)),
+		})
+	}
+
+	// 手机和电脑读取到同一版本
+	phoneVersion, _ := read()
+	laptopVersion, nonce := read()
+	if phoneVersion != 1 || laptopVersion != 1 {
+		t.Fatalf("versions = %d, %d, want 1", phoneVersion, laptopVersion)
+	}
+
+	w := update(laptopVersion, nonce)
+	expectStatus(t, w, http.StatusOK)
+	if got := decodeBody(t, w)["version"]; got != float64(2) {
+		t.Fatalf("version after update = %v, want 2", got)
+	}
+
+	// 手机随后用旧版本号提交（nonce 是最新的），不能覆盖电脑的修改
+	var saved models.EncryptedContent
+	reload(t, &saved, content.ID)
+	w = update(phoneVersion, saved.Nonce)
+	expectStatus(t, w, http.StatusConflict)
+	if got := decodeBody(t, w)["current_version"]; got != float64(2) {
+		t.Errorf("current_version = %v, want 2", got)
+	}
+	var after models.EncryptedContent
+	reload(t, &after, content.ID)
+	if after.EncryptedData != saved.EncryptedData || after.Version != 2 {
+		t.Error("stale update overwrote the newer version")
+	}
+
+	// 重新读取后可以继续更新
+	version, nonce := read()
+	expectStatus(t, update(version, nonce), http.StatusOK)
+}
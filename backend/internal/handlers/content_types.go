@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"vaultseed-backend/internal/models"
+)
+
+// defaultContentType 未指定类型时使用的内容类型
+const defaultContentType = "note"
+
+// passwordContentType 可携带强度评分的内容类型
+const passwordContentType = "password"
+
+// weakPasswordStrength 强度评分不高于该值的密码视为弱密码
+const weakPasswordStrength = 1
+
+// ContentTypeValidator 特定内容类型的附加校验
+type ContentTypeValidator func(req *models.CreateContentRequest) error
+
+// contentTypeValidators 按内容类型注册的校验器，未注册的类型只做基础校验
+var contentTypeValidators = map[string]ContentTypeValidator{}
+
+// RegisterContentTypeValidator 注册内容类型校验器，同名类型会被覆盖
+func RegisterContentTypeValidator(contentType string, v ContentTypeValidator) {
+	contentTypeValidators[strings.ToLower(contentType)] = v
+}
+
+// RequireMetadata 返回要求提供指定元数据字段的校验器
+func RequireMetadata(fields ...string) ContentTypeValidator {
+	return func(req *models.CreateContentRequest) error {
+		for _, field := range fields {
+			if strings.TrimSpace(req.Metadata[field]) == "" {
+				return fmt.Errorf("content type %q requires metadata field %q", req.ContentType, field)
+			}
+		}
+		return nil
+	}
+}
+
+// registerConfiguredContentTypes 根据配置注册各类型的必填元数据字段
+func registerConfiguredContentTypes(fields map[string][]string) {
+	for contentType, required := range fields {
+		RegisterContentTypeValidator(contentType, RequireMetadata(required...))
+	}
+}
+
+// validateContentType 规范化内容类型并执行对应的校验器
+func validateContentType(req *models.CreateContentRequest) error {
+	req.ContentType = strings.ToLower(req.ContentType)
+	if req.ContentType == "" {
+		req.ContentType = defaultContentType
+	}
+	if req.Strength != nil && req.ContentType != passwordContentType {
+		return fmt.Errorf("strength is only allowed for %s content", passwordContentType)
+	}
+	if v, ok := contentTypeValidators[req.ContentType]; ok {
+		return v(req)
+	}
+	return nil
+}
+
+// metadataOrEmpty 旧数据没有元数据时返回空对象
+func metadataOrEmpty(metadata string) string {
+	if metadata == "" || metadata == "null" {
+		return "{}"
+	}
+	return metadata
+}
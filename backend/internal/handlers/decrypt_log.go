@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 解密失败原因
+const (
+	decryptInvalidSignature = "invalid_signature"
+	decryptAccountLocked    = "account_locked"
+	decryptNoAccess         = "no_access"
+	decryptInvalidNonce     = "invalid_nonce"
+	decryptNonceExpired     = "nonce_expired"
+	decryptExpired          = "expired"
+	decryptNotYetAvailable  = "not_yet_available"
+	decryptOutsideWindow    = "outside_access_window"
+)
+
+// recordDecryptAttempt 记录一次解密尝试，失败时 reason 为上面的原因之一
+// 内容不存在时不记录，避免任意 ID 的探测写入无用记录；写入失败只记日志，不影响请求
+func recordDecryptAttempt(db *gorm.DB, c *gin.Context, contentID uint, address string, success bool, reason string) {
+	db = db.WithContext(context.WithoutCancel(c.Request.Context()))
+	var count int64
+	if err := db.Model(&models.EncryptedContent{}).Where("id = ?", contentID).Count(&count).Error; err != nil || count == 0 {
+		return
+	}
+	entry := models.DecryptLog{
+		ContentID:   contentID,
+		UserAddress: address,
+		ClientIP:    c.ClientIP(),
+		Success:     success,
+		Reason:      reason,
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		requestLogger(c).Error("Failed to write decrypt log", "error", err, "content_id", contentID)
+	}
+}
+
+// AccessLogHandler 返回内容的解密历史（含失败的尝试），按时间倒序分页，仅所有者可查看
+func AccessLogHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+	page, pageSize := parsePagination(c)
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	var total int64
+	if err := db.Model(&models.DecryptLog{}).Where("content_id = ?", content.ID).Count(&total).Error; err != nil {
+		serverError(c, err, "Failed to fetch access log")
+		return
+	}
+	var entries []models.DecryptLog
+	if err := db.Where("content_id = ?", content.ID).Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&entries).Error; err != nil {
+		serverError(c, err, "Failed to fetch access log")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     total,
+		"entries":   entries,
+	})
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+)
+
+func TestDecryptAttemptsAreLogged(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice, mallory := newWallet(t), newWallet(t)
+	createUser(t, alice.address)
+	createUser(t, mallory.address)
+	content := seedContent(t, alice.address)
+	path := "/content/" + itoa(content.ID)
+
+	routes := func(as string) http.Handler {
+		r := newRouter(as)
+		r.GET("/content/:id/decrypt-challenge", DecryptChallengeHandler)
+		r.POST("/content/decrypt", DecryptContentHandler)
+		r.GET("/content/:id/access-log", AccessLogHandler)
+		r.DELETE("/content/:id", DeleteContentHandler)
+		return r
+	}
+	challenge := func() string {
+		t.Helper()
+		w := doJSON(t, routes(alice.address), http.MethodGet, path+"/decrypt-challenge", nil)
+		expectStatus(t, w, http.StatusOK)
+		return decodeBody(t, w)["nonce"].(string)
+	}
+	decrypt := func(as testWallet, signer testWallet, nonce string, status int) {
+		t.Helper()
+		w := doJSON(t, routes(as.address), http.MethodPost, "/content/decrypt", models.DecryptContentRequest{
+			ContentID: content.ID,
+			Nonce:     nonce,
+			Signature: signer.sign(t, utils.GenerateDecryptMessage(content.ID, nonce)),
+		})
+		expectStatus(t, w, status)
+	}
+
+	nonce := challenge()
+	decrypt(alice, mallory, nonce, http.StatusUnauthorized) // 签名不是当前地址的
+	decrypt(alice, alice, nonce, http.StatusOK)
+	decrypt(alice, alice, nonce, http.StatusUnauthorized) // nonce 已被消费
+	decrypt(mallory, mallory, nonce, http.StatusNotFound) // 无权访问
+	// 不存在的内容不记录
+	w := doJSON(t, routes(alice.address), http.MethodPost, "/content/decrypt", models.DecryptContentRequest{
+		ContentID: content.ID + 100,
+		Nonce:     nonce,
+		Signature: alice.sign(t, utils.GenerateDecryptMessage(content.ID+100, nonce)),
+	})
+	expectStatus(t, w, http.StatusNotFound)
+
+	want := []struct {
+		address string
+		success bool
+		reason  string
+	}{
+		{mallory.address, false, decryptNoAccess},
+		{alice.address, false, decryptInvalidNonce},
+		{alice.address, true, ""},
+		{alice.address, false, decryptInvalidSignature},
+	}
+	w = doJSON(t, routes(alice.address), http.MethodGet, path+"/access-log", nil)
+	expectStatus(t, w, http.StatusOK)
+	entries := decodeBody(t, w)["entries"].([]interface{})
+	if len(entries) != len(want) {
+		t.Fatalf("%d log entries, want %d: %s", len(entries), len(want), w.Body.String())
+	}
+	for i, e := range entries {
+		entry := e.(map[string]interface{})
+		reason, _ := entry["reason"].(string)
+		if entry["user_address"] != want[i].address || entry["success"] != want[i].success || reason != want[i].reason {
+			t.Errorf("entry %d = %v, want %+v", i, entry, want[i])
+		}
+	}
+	expectStatus(t, doJSON(t, routes(mallory.address), http.MethodGet, path+"/access-log", nil), http.StatusNotFound)
+
+	// 永久删除（先移入回收站再删除）后解密记录仍然保留
+	for i := 0; i < 2; i++ {
+		var current models.EncryptedContent
+		reload(t, &current, content.ID)
+		w := doJSON(t, routes(alice.address), http.MethodDelete, path, models.DeleteContentRequest{
+			Nonce:     current.Nonce,
+			Signature: alice.sign(t, utils.GenerateDeleteMessage(content.ID, current.Nonce)),
+		})
+		expectStatus(t, w, http.StatusOK)
+	}
+	var remaining int64
+	db.Unscoped().Model(&models.EncryptedContent{}).Where("id = ?", content.ID).Count(&remaining)
+	if remaining != 0 {
+		t.Fatal("content was not permanently deleted")
+	}
+	var logs int64
+	db.Model(&models.DecryptLog{}).Where("content_id = ?", content.ID).Count(&logs)
+	if logs != int64(len(want)) {
+		t.Errorf("%d decrypt logs kept after permanent delete, want %d", logs, len(want))
+	}
+}
+
+func TestConcurrentDecrypts(t *testing.T) {
+	setupTest(t)
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	content := seedContent(t, alice.address)
+
+	r := newRouter(alice.address)
+	r.GET("/content/:id", GetContentDetailHandler)
+	r.POST("/content/decrypt", DecryptContentHandler)
+	decryptNonce := func() string {
+		t.Helper()
+		w := doJSON(t, r, http.MethodGet, "/content/"+itoa(content.ID), nil)
+		expectStatus(t, w, http.StatusOK)
+		return decodeBody(t, w)["content"].(map[string]interface{})["decrypt_nonce"].(string)
+	}
+
+	// run 同时提交每个 nonce 对应的解密请求，返回各自的状态码
+	run := func(nonces ...string) []int {
+		requests := make([]models.DecryptContentRequest, len(nonces))
+		for i, nonce := range nonces {
+			requests[i] = models.DecryptContentRequest{
+				ContentID: content.ID,
+				Nonce:     nonce,
+				Signature: alice.sign(t, utils.GenerateDecryptMessage(content.ID, nonce)),
+			}
+		}
+		statuses := make([]int, len(nonces))
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := range requests {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				statuses[i] = doJSON(t, r, http.MethodPost, "/content/decrypt", requests[i]).Code
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+		return statuses
+	}
+
+	t.Run("separate challenges both succeed", func(t *testing.T) {
+		// 两个设备各自打开详情页拿到自己的挑战
+		statuses := run(decryptNonce(), decryptNonce())
+		for i, status := range statuses {
+			if status != http.StatusOK {
+				t.Errorf("decrypt %d status = %d, want 200", i, status)
+			}
+		}
+	})
+
+	t.Run("same challenge succeeds once", func(t *testing.T) {
+		nonce := decryptNonce()
+		ok := 0
+		for _, status := range run(nonce, nonce, nonce) {
+			switch status {
+			case http.StatusOK:
+				ok++
+			case http.StatusUnauthorized:
+			default:
+				t.Errorf("unexpected status %d", status)
+			}
+		}
+		if ok != 1 {
+			t.Errorf("%d decrypts succeeded with one nonce, want 1", ok)
+		}
+	})
+}
+
+func TestDecryptTypedDataChainID(t *testing.T) {
+	setupTest(t, func(c *config.Config) { c.ChainID = 137 })
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	content := seedContent(t, alice.address)
+
+	r := newRouter(alice.address)
+	r.GET("/content/:id/decrypt-challenge", DecryptChallengeHandler)
+	r.POST("/content/decrypt", DecryptContentHandler)
+	w := doJSON(t, r, http.MethodGet, "/content/"+itoa(content.ID)+"/decrypt-challenge", nil)
+	expectStatus(t, w, http.StatusOK)
+	nonce := decodeBody(t, w)["nonce"].(string)
+
+	tests := []struct {
+		name    string
+		chainID uint64
+		status  int
+	}{
+		// 签名本身有效，但域中的链不是本服务配置的链
+		{"other chain", 1, http.StatusUnauthorized},
+		{"configured chain", 137, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodPost, "/content/decrypt", models.DecryptContentRequest{
+				ContentID:     content.ID,
+				Nonce:         nonce,
+				SignatureType: config.SchemeEIP712,
+				ChainID:       tt.chainID,
+				Signature:     alice.signTyped(t, utils.DecryptAuthorizationHash(content.ID, nonce, tt.chainID)),
+			})
+			expectStatus(t, w, tt.status)
+		})
+	}
+
+	var mismatches int64
+	database.GetDB().Model(&models.DecryptLog{}).Where("content_id = ? AND reason = ?", content.ID, decryptInvalidSignature).Count(&mismatches)
+	if mismatches != 1 {
+		t.Errorf("%d failed attempts logged for chain mismatch, want 1", mismatches)
+	}
+}
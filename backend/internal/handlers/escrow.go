@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// titleEscrowWarning 开启托管时返回给客户端的提示，客户端应原样展示给用户
+const titleEscrowWarning = "Title escrow is enabled: the server can now decrypt your encrypted titles. Content bodies remain end-to-end encrypted."
+
+// SetTitleEscrowHandler 开启或关闭标题托管（默认关闭）
+//
+// 开启后服务端保存用 TITLE_ESCROW_KEY 包装的标题密钥，SearchContentHandler 可在服务端解密并搜索加密标题。
+// 这会放弃标题的零知识保护：持有主密钥和数据库的人（包括服务运营者）能够读取该用户的全部标题。
+// 关闭时立即删除托管的密钥。
+func SetTitleEscrowHandler(c *gin.Context) {
+	var req models.TitleEscrowRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	if !requireFreshAuth(c, "title-escrow", userAddress) {
+		return
+	}
+
+	updates := map[string]interface{}{"title_escrow": false, "escrowed_title_key": ""}
+	if *req.Enabled {
+		if !utils.EscrowEnabled() {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Title escrow is not available on this server"})
+			return
+		}
+		if !req.Acknowledge {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Enabling title escrow requires acknowledge=true"})
+			return
+		}
+		titleKey, err := base64.StdEncoding.DecodeString(req.TitleKey)
+		if err != nil || len(titleKey) != 32 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "title_key must be a base64-encoded 32-byte key"})
+			return
+		}
+		sealed, err := utils.SealEscrowedKey(titleKey)
+		if err != nil {
+			serverError(c, err, "Failed to escrow title key")
+			return
+		}
+		updates = map[string]interface{}{"title_escrow": true, "escrowed_title_key": sealed}
+	}
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	result := db.Model(&models.User{}).Where("address = ?", userAddress).Updates(updates)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to update title escrow")
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+	database.MarkWrite(userAddress)
+	recordAudit(db, c, AuditTitleEscrow, userAddress, fmt.Sprintf("enabled=%t", *req.Enabled))
+
+	response := gin.H{"success": true, "title_escrow": *req.Enabled}
+	if *req.Enabled {
+		response["warning"] = titleEscrowWarning
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportContentHandler 以流的方式导出用户的全部加密内容
+// 使用 ?compress=gzip 以 Content-Encoding: gzip 传输，默认不压缩；中途出错时断开连接
+// 可用 ?folder_id= 或 ?tag= 只导出指定文件夹或标签下的内容
+func ExportContentHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	if !requireFreshAuth(c, "export", userAddress) {
+		return
+	}
+
+	compress := c.Query("compress")
+	if compress != "" && compress != "gzip" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Unsupported compression"})
+		return
+	}
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	query, scope, ok := filterContent(c, db, db.Model(&models.EncryptedContent{}).Where("user_address = ?", userAddress), userAddress)
+	if !ok {
+		return
+	}
+	rel, err := loadExportRelations(db, userAddress)
+	if err != nil {
+		serverError(c, err, "Failed to fetch content")
+		return
+	}
+
+	// 导出开销较大：限制单用户频率和全局并发
+	// 过滤条件校验通过后才占用名额，参数错误的请求不会触发冷却
+	release, retryAfter := exportLimiter.acquire(userAddress)
+	if release == nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{Error: "Too many export requests"})
+		return
+	}
+	defer release()
+
+	rows, err := query.Order("id ASC").Rows()
+	if err != nil {
+		serverError(c, err, "Failed to fetch content")
+		return
+	}
+	defer rows.Close()
+
+	detail := "compress=" + compress
+	filename := "vaultseed-export"
+	if scope != "" {
+		detail += " scope=" + scope
+		filename += "-" + scope
+	}
+	recordAudit(db, c, AuditExport, userAddress, detail)
+
+	var w io.Writer = c.Writer
+	var gz *gzip.Writer
+	if compress == "gzip" {
+		// 以 Content-Encoding 传输压缩后的 JSON：浏览器和 curl --compressed 会自动解压，不解压的客户端得到 .json.gz
+		c.Header("Content-Type", "application/json")
+		c.Header("Content-Encoding", "gzip")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		gz = gzip.NewWriter(c.Writer)
+		w = gz
+	} else {
+		c.Header("Content-Type", "application/json")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+	}
+	c.Status(http.StatusOK)
+
+	err = writeExport(w, db, rows, rel)
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		// 响应头已发出，无法再返回错误状态；断开连接而不是写完数组或 gzip 尾部，避免客户端把截断的导出当作完整文件
+		requestLogger(c).Error("Export failed mid-stream", "error", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportRelations 导出时按内容 ID 查找的文件夹名称和标签
+type exportRelations struct {
+	folders map[uint]string
+	tags    map[uint][]string
+}
+
+// loadExportRelations 一次性读取用户的文件夹和内容标签，避免逐行查询
+func loadExportRelations(db *gorm.DB, address string) (*exportRelations, error) {
+	var folders []models.Folder
+	if err := db.Where("owner_address = ?", address).Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	rel := &exportRelations{folders: make(map[uint]string, len(folders)), tags: make(map[uint][]string)}
+	for _, folder := range folders {
+		rel.folders[folder.ID] = folder.Name
+	}
+
+	var rows []struct {
+		ContentID uint
+		Name      string
+	}
+	if err := db.Model(&models.ContentTag{}).
+		Select("content_tags.content_id, tags.name").
+		Joins("JOIN tags ON tags.id = content_tags.tag_id").
+		Where("tags.address = ?", address).
+		Order("tags.name").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		rel.tags[row.ContentID] = append(rel.tags[row.ContentID], row.Name)
+	}
+	return rel, nil
+}
+
+// writeExport 逐行写出 JSON 数组，避免一次性加载全部内容
+// 导出包含导入时恢复内容所需的全部字段，不含归档状态、彩色标签等仅用于整理的信息
+func writeExport(w io.Writer, db *gorm.DB, rows *sql.Rows, rel *exportRelations) error {
+	enc := json.NewEncoder(w)
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	first := true
+	for rows.Next() {
+		var content models.EncryptedContent
+		if err := db.ScanRows(rows, &content); err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		var metadata map[string]string
+		if err := json.Unmarshal([]byte(metadataOrEmpty(content.Metadata)), &metadata); err != nil {
+			return err
+		}
+		item := models.ExportItem{
+			ID:             content.ID,
+			Title:          content.Title,
+			TitleEncrypted: content.TitleEncrypted,
+			EncryptedTitle: content.EncryptedTitle,
+			EncryptedData:  content.EncryptedData,
+			EncryptedKey:   content.EncryptedKey,
+			IV:             content.IV,
+			CreatedAt:      content.CreatedAt,
+			UpdatedAt:      content.UpdatedAt,
+
+			ContentType:       content.ContentType,
+			Metadata:          metadata,
+			Strength:          content.Strength,
+			AccessWindowStart: content.AccessWindowStart,
+			AccessWindowEnd:   content.AccessWindowEnd,
+			AccessWindowTZ:    content.AccessWindowTZ,
+			ExpiresAt:         content.ExpiresAt,
+			AvailableAt:       content.AvailableAt,
+			Tags:              rel.tags[content.ID],
+		}
+		if content.FolderID != nil {
+			item.Folder = rel.folders[*content.FolderID]
+		}
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// exportLimiter 导出限流器，启动时由 Init 根据配置创建
+var exportLimiter = newExportLimiter(time.Minute, 4)
+
+// exportRateLimiter 按用户冷却时间和全局并发数限制导出
+type exportRateLimiter struct {
+	cooldown time.Duration
+	slots    chan struct{}
+
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newExportLimiter(cooldown time.Duration, maxConcurrent int) *exportRateLimiter {
+	return &exportRateLimiter{
+		cooldown: cooldown,
+		slots:    make(chan struct{}, maxConcurrent),
+		last:     make(map[string]time.Time),
+	}
+}
+
+// acquire 申请一次导出，成功时返回释放函数；被限制时返回 nil 和建议的重试等待时间
+func (l *exportRateLimiter) acquire(address string) (func(), time.Duration) {
+	key := strings.ToLower(address)
+	now := time.Now()
+
+	l.mu.Lock()
+	if last, ok := l.last[key]; ok {
+		if wait := l.cooldown - now.Sub(last); wait > 0 {
+			l.mu.Unlock()
+			return nil, wait
+		}
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		l.mu.Unlock()
+		return nil, time.Second
+	}
+	l.last[key] = now
+
+	// 顺带清理已过冷却期的记录
+	for addr, t := range l.last {
+		if now.Sub(t) >= l.cooldown {
+			delete(l.last, addr)
+		}
+	}
+	l.mu.Unlock()
+
+	return func() { <-l.slots }, 0
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/middleware"
+	"vaultseed-backend/internal/models"
+)
+
+// noExportCooldown 测试中连续导出不受冷却时间限制
+func noExportCooldown(c *config.Config) { c.ExportCooldown = 0 }
+
+func TestExportGzipUsesContentEncoding(t *testing.T) {
+	setupTest(t, noExportCooldown)
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	seedContent(t, alice.address)
+	seedContent(t, alice.address)
+
+	r := newRouter(alice.address)
+	r.GET("/export", ExportContentHandler)
+	w := doJSON(t, r, http.MethodGet, "/export?compress=gzip", nil, alice.reauthHeaders(t, "export", time.Now())...)
+	expectStatus(t, w, http.StatusOK)
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", got)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	var items []models.ExportItem
+	if err := json.NewDecoder(gz).Decode(&items); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("exported %d items, want 2", len(items))
+	}
+}
+
+func TestExportAbortsConnectionOnMidStreamError(t *testing.T) {
+	for _, compress := range []string{"", "gzip"} {
+		t.Run("compress="+compress, func(t *testing.T) {
+			setupTest(t, noExportCooldown)
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			seedContent(t, alice.address)
+			broken := seedContent(t, alice.address)
+			// 无法解析的版本号使第二行扫描失败，此时第一行已经写出
+			if err := database.GetDB().Exec("UPDATE encrypted_contents SET version = 'not a number' WHERE id = ?", broken.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			r := newRouter(alice.address)
+			r.Use(middleware.Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))))
+			r.GET("/export", ExportContentHandler)
+			srv := httptest.NewServer(r)
+			defer srv.Close()
+
+			client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/export?compress="+compress, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers := alice.reauthHeaders(t, "export", time.Now())
+			for i := 0; i < len(headers); i += 2 {
+				req.Header.Set(headers[i], headers[i+1])
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return // 响应头尚未发出（仍在缓冲区中）连接就已断开
+			}
+			defer resp.Body.Close()
+
+			var body io.Reader = resp.Body
+			if compress == "gzip" {
+				gz, err := gzip.NewReader(resp.Body)
+				if err != nil {
+					return // 连接在 gzip 头之前就已断开
+				}
+				body = gz
+			}
+			if data, err := io.ReadAll(body); err == nil {
+				t.Fatalf("export completed without error, body %q", data)
+			}
+		})
+	}
+}
+
+func TestExportAlwaysRequiresReauth(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		reauth bool
+		status int
+	}{
+		{"full export without reauth", "/export", false, http.StatusUnauthorized},
+		{"scoped export without reauth", "/export?tag=work", false, http.StatusUnauthorized},
+		{"full export with reauth", "/export", true, http.StatusOK},
+		{"scoped export with reauth", "/export?tag=work", true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// REAUTH_OPERATIONS 未列出 export 时同样要求二次认证
+			setupTest(t, noExportCooldown, func(c *config.Config) { c.ReauthOperations = nil })
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			seedContent(t, alice.address)
+			database.GetDB().Create(&models.Tag{Address: alice.address, Name: "work"})
+
+			var headers []string
+			if tt.reauth {
+				headers = alice.reauthHeaders(t, "export", time.Now())
+			}
+			r := newRouter(alice.address)
+			r.GET("/export", ExportContentHandler)
+			expectStatus(t, doJSON(t, r, http.MethodGet, tt.path, nil, headers...), tt.status)
+		})
+	}
+}
+
+func TestExportCooldownStartsOnlyAfterFiltersResolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"invalid folder_id", "/export?folder_id=abc", http.StatusBadRequest},
+		{"unknown folder", "/export?folder_id=999", http.StatusNotFound},
+		{"unknown tag", "/export?tag=missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) { c.ExportCooldown = time.Hour })
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			seedContent(t, alice.address)
+
+			r := newRouter(alice.address)
+			r.GET("/export", ExportContentHandler)
+			expectStatus(t, doJSON(t, r, http.MethodGet, tt.path, nil, alice.reauthHeaders(t, "export", time.Now())...), tt.status)
+			// 被拒绝的请求不占用冷却时间，随后的导出正常进行，之后才进入冷却
+			expectStatus(t, doJSON(t, r, http.MethodGet, "/export", nil, alice.reauthHeaders(t, "export", time.Now())...), http.StatusOK)
+			expectStatus(t, doJSON(t, r, http.MethodGet, "/export", nil, alice.reauthHeaders(t, "export", time.Now())...), http.StatusTooManyRequests)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scopeUnsafeChars 文件名中不允许出现的字符
+var scopeUnsafeChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// filterContent 内容列表与导出共用的筛选条件：?folder_id=、?label_id= 以及按标签名的 ?tag=
+// 返回追加条件后的查询和描述筛选范围的短字符串（用于导出文件名，未筛选时为空）
+func filterContent(c *gin.Context, db *gorm.DB, query *gorm.DB, userAddress string) (*gorm.DB, string, bool) {
+	var scope []string
+
+	if folderID := c.Query("folder_id"); folderID != "" {
+		id, err := strconv.ParseUint(folderID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid folder_id"})
+			return nil, "", false
+		}
+		var folder models.Folder
+		if err := db.Where("id = ? AND owner_address = ?", id, userAddress).First(&folder).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Folder not found"})
+			} else {
+				serverError(c, err, "Database error")
+			}
+			return nil, "", false
+		}
+		query = query.Where("folder_id = ?", folder.ID)
+		scope = append(scope, "folder-"+strconv.FormatUint(uint64(folder.ID), 10))
+	}
+
+	if labelID := c.Query("label_id"); labelID != "" {
+		id, err := strconv.ParseUint(labelID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid label_id"})
+			return nil, "", false
+		}
+		query = query.Where("label_id = ?", id)
+		scope = append(scope, "label-"+labelID)
+	}
+
+	if name := strings.TrimSpace(c.Query("tag")); name != "" {
+		var tag models.Tag
+		if err := db.Where("address = ? AND name = ?", userAddress, name).First(&tag).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Tag not found"})
+			} else {
+				serverError(c, err, "Database error")
+			}
+			return nil, "", false
+		}
+		query = query.Where("id IN (?)", db.Model(&models.ContentTag{}).Select("content_id").Where("tag_id = ?", tag.ID))
+		if safe := strings.Trim(scopeUnsafeChars.ReplaceAllString(tag.Name, "_"), "_"); safe != "" {
+			scope = append(scope, "tag-"+safe)
+		} else {
+			scope = append(scope, "tag-"+strconv.FormatUint(uint64(tag.ID), 10))
+		}
+	}
+
+	return query, strings.Join(scope, "-"), true
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"net/http"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateFolderHandler 创建文件夹
+func CreateFolderHandler(c *gin.Context) {
+	var req models.CreateFolderRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	folder := models.Folder{OwnerAddress: userAddress, Name: req.Name}
+	if err := db.Create(&folder).Error; err != nil {
+		serverError(c, err, "Failed to create folder")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"id":      folder.ID,
+	})
+}
+
+// ListFoldersHandler 列出用户的文件夹
+func ListFoldersHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var folders []models.Folder
+	if err := db.Where("owner_address = ?", userAddress).Order("name").Find(&folders).Error; err != nil {
+		serverError(c, err, "Failed to fetch folders")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"folders": folders,
+	})
+}
+
+// MoveContentHandler 批量将内容移动到指定文件夹（folder_id 为空表示移到根目录）
+// ?partial=true 时逐条更新并返回每条的结果
+func MoveContentHandler(c *gin.Context) {
+	var req models.MoveContentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	// 目标文件夹必须属于当前用户
+	if req.FolderID != nil {
+		var folder models.Folder
+		if err := db.Where("id = ? AND owner_address = ?", *req.FolderID, userAddress).First(&folder).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Folder not found"})
+			} else {
+				serverError(c, err, "Database error")
+			}
+			return
+		}
+	}
+
+	if partialMode(c) {
+		moveContentPartial(c, db, userAddress, &req)
+		return
+	}
+
+	var owned []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EncryptedContent{}).
+			Where("user_address = ? AND id IN ?", userAddress, req.ContentIDs).
+			Pluck("id", &owned).Error; err != nil {
+			return err
+		}
+		if len(owned) == 0 {
+			return nil
+		}
+		return tx.Model(&models.EncryptedContent{}).
+			Where("user_address = ? AND id IN ?", userAddress, owned).
+			Update("folder_id", req.FolderID).Error
+	})
+	if err != nil {
+		serverError(c, err, "Failed to move content")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	// 不存在或不属于当前用户的 ID 原样返回
+	moved := make(map[uint]bool, len(owned))
+	for _, id := range owned {
+		moved[id] = true
+	}
+	skipped := []uint{}
+	for _, id := range req.ContentIDs {
+		if !moved[id] {
+			skipped = append(skipped, id)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"folder_id": req.FolderID,
+		"moved":     len(owned),
+		"skipped":   skipped,
+	})
+}
+
+// moveContentPartial 逐条移动内容，单条失败不影响其他条目
+func moveContentPartial(c *gin.Context, db *gorm.DB, userAddress string, req *models.MoveContentRequest) {
+	results := make([]batchItemResult, len(req.ContentIDs))
+	moved := 0
+	for i, id := range req.ContentIDs {
+		id := id
+		results[i] = batchItemResult{Index: i, ID: &id}
+		result := db.Model(&models.EncryptedContent{}).
+			Where("id = ? AND user_address = ?", id, userAddress).
+			Update("folder_id", req.FolderID)
+		switch {
+		case result.Error != nil:
+			results[i].Status = batchStatusError
+			results[i].Error = "failed to move content"
+		case result.RowsAffected == 0:
+			results[i].Status = batchStatusSkipped
+			results[i].Error = "content not found"
+		default:
+			results[i].Status = batchStatusOK
+			moved++
+		}
+	}
+	if moved > 0 {
+		database.MarkWrite(userAddress)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"folder_id": req.FolderID,
+		"moved":     moved,
+		"results":   results,
+	})
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"vaultseed-backend/internal/ethrpc"
+	"vaultseed-backend/internal/utils"
+)
+
+// 区块新鲜度校验失败的原因
+var (
+	errBlockMissing  = errors.New("signed message must reference a recent block")
+	errBlockMismatch = errors.New("block hash does not match the chain")
+	errBlockStale    = errors.New("referenced block is too old")
+)
+
+// checkBlockFreshness 校验签名消息引用的区块存在于链上且在最近 N 个区块内
+// 未开启 REQUIRE_BLOCK_FRESHNESS 时直接通过
+func checkBlockFreshness(ctx context.Context, message string, number *uint64, hash string) error {
+	if !cfg.RequireBlockFreshness {
+		return nil
+	}
+	if number == nil || hash == "" {
+		return errBlockMissing
+	}
+	if !strings.Contains(message, utils.BlockReference(*number, hash)) {
+		return errBlockMissing
+	}
+
+	head, err := ethrpc.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if *number > head || head-*number > cfg.BlockFreshnessWindow {
+		return errBlockStale
+	}
+
+	actual, err := ethrpc.BlockHash(ctx, *number)
+	if err != nil {
+		return err
+	}
+	if actual != strings.ToLower(hash) {
+		return errBlockMismatch
+	}
+	return nil
+}
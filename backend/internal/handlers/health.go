@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"vaultseed-backend/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthPingTimeout 健康检查探测数据库的超时时间，应小于负载均衡器的探测超时
+const healthPingTimeout = 2 * time.Second
+
+// HealthHandler 就绪检查：探测主库和只读副本的连接，失败时返回 503，负载均衡器据此摘除实例
+func HealthHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthPingTimeout)
+	defer cancel()
+
+	if err := database.Ping(ctx); err != nil {
+		requestLogger(c).Error("Health check failed", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "degraded", "db": "down", "lockdown": database.LockdownActive()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "db": "up", "lockdown": database.LockdownActive()})
+}
+
+// LivenessHandler 存活检查：只表示进程能处理请求，不依赖数据库，避免数据库故障时编排系统反复重启实例
+func LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"vaultseed-backend/internal/database"
+)
+
+func TestHealthReportsDatabaseState(t *testing.T) {
+	tests := []struct {
+		name     string
+		closeDB  bool
+		path     string
+		status   int
+		wantBody map[string]interface{}
+	}{
+		{"ready", false, "/health", http.StatusOK, map[string]interface{}{"status": "ok", "db": "up"}},
+		{"database down", true, "/health", http.StatusServiceUnavailable, map[string]interface{}{"status": "degraded", "db": "down"}},
+		{"live with database", false, "/health/live", http.StatusOK, map[string]interface{}{"status": "ok"}},
+		{"live without database", true, "/health/live", http.StatusOK, map[string]interface{}{"status": "ok"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			if tt.closeDB {
+				sqlDB, err := database.GetDB().DB()
+				if err != nil {
+					t.Fatal(err)
+				}
+				sqlDB.Close()
+			}
+
+			r := newRouter("")
+			r.GET("/health", HealthHandler)
+			r.GET("/health/live", LivenessHandler)
+			w := doJSON(t, r, http.MethodGet, tt.path, nil)
+			expectStatus(t, w, tt.status)
+			body := decodeBody(t, w)
+			for key, want := range tt.wantBody {
+				if body[key] != want {
+					t.Errorf("%s = %v, want %v", key, body[key], want)
+				}
+			}
+		})
+	}
+}
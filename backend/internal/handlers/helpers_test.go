@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+)
+
+// setupTest 为单个测试准备独立的 sqlite 数据库和配置，configure 可修改默认配置
+// 处理器依赖包级状态（database.DB、cfg），因此本包的测试不能并行执行
+func setupTest(t *testing.T, configure ...func(*config.Config)) *config.Config {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	c := config.Default()
+	c.DatabasePath = filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000"
+	for _, fn := range configure {
+		fn(c)
+	}
+	if err := database.InitDB(c); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	Init(c)
+	if _, err := utils.SetTokenSecret(c.JWTSecret, c.JWTTTL); err != nil {
+		t.Fatalf("SetTokenSecret: %v", err)
+	}
+	t.Cleanup(func() {
+		database.Close()
+		Init(config.Default())
+	})
+	return c
+}
+
+// newRouter 返回测试用的路由，as 非空时模拟 RequireAuth 已认证的地址
+func newRouter(as string) *gin.Engine {
+	r := gin.New()
+	if as != "" {
+		r.Use(func(c *gin.Context) {
+			c.Set("userAddress", as)
+			c.Next()
+		})
+	}
+	return r
+}
+
+// doJSON 发送 JSON 请求，headers 为交替的键和值
+func doJSON(t *testing.T, h http.Handler, method, path string, body interface{}, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// decodeBody 解析 JSON 响应
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+// expectStatus 检查响应状态码
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if w.Code != want {
+		t.Fatalf("status = %d, want %d; body: %s", w.Code, want, w.Body.String())
+	}
+}
+
+// testWallet 测试用的 EOA 钱包
+type testWallet struct {
+	key     *ecdsa.PrivateKey
+	address string
+}
+
+func newWallet(t *testing.T) testWallet {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return testWallet{key: key, address: crypto.PubkeyToAddress(key.PublicKey).Hex()}
+}
+
+// sign 按 personal_sign 签名，V 为 27/28
+func (w testWallet) sign(t *testing.T, message string) string {
+	t.Helper()
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)))
+	sig, err := crypto.Sign(hash, w.key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	sig[64] += 27
+	return hexutil.Encode(sig)
+}
+
+// loginRequest 按 nonce 生成并签名的登录请求
+func (w testWallet) loginRequest(t *testing.T, nonce string) models.LoginRequest {
+	t.Helper()
+	message := utils.GenerateMessageForSigning(w.address, nonce)
+	return models.LoginRequest{Address: w.address, Signature: w.sign(t, message), Message: message, Nonce: nonce}
+}
+
+// signTyped 对 EIP-712 摘要签名（eth_signTypedData_v4），V 为 27/28
+func (w testWallet) signTyped(t *testing.T, digest [32]byte) string {
+	t.Helper()
+	sig, err := crypto.Sign(digest[:], w.key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	sig[64] += 27
+	return hexutil.Encode(sig)
+}
+
+// publicKey 非压缩格式的十六进制公钥
+func (w testWallet) publicKey() string {
+	return hexutil.Encode(crypto.FromECDSAPub(&w.key.PublicKey))
+}
+
+// setClock 将登录 nonce 使用的当前时间固定为 at，测试结束后恢复
+func setClock(t *testing.T, at time.Time) {
+	t.Helper()
+	clock = func() time.Time { return at }
+	t.Cleanup(func() { clock = time.Now })
+}
+
+// randomBase64 n 个随机字节的 base64 编码
+func randomBase64(t *testing.T, n int) string {
+	t.Helper()
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatalf("rand: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// createUser 创建带有效登录 nonce 的用户
+func createUser(t *testing.T, address string) models.User {
+	t.Helper()
+	nonce, err := utils.GenerateNonce()
+	if err != nil {
+		t.Fatalf("GenerateNonce: %v", err)
+	}
+	user := models.User{Address: address, Nonce: nonce, NonceIssuedAt: time.Now()}
+	if err := database.GetDB().Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// seedContent 为 owner 创建一条密文格式合法的内容，mods 可在保存前修改字段
+func seedContent(t *testing.T, owner string, mods ...func(*models.EncryptedContent)) models.EncryptedContent {
+	t.Helper()
+	nonce, err := utils.GenerateNonce()
+	if err != nil {
+		t.Fatalf("GenerateNonce: %v", err)
+	}
+	content := models.EncryptedContent{
+		UserAddress:   owner,
+		Title:         "entry",
+		ContentType:   defaultContentType,
+		Metadata:      "{}",
+		EncryptedData: randomBase64(t, 64),
+		EncryptedKey:  randomBase64(t, 32),
+		IV:            randomBase64(t, 12),
+		Nonce:         nonce,
+		NonceIssuedAt: time.Now(),
+	}
+	for _, mod := range mods {
+		mod(&content)
+	}
+	content.KeyIVHash = utils.HashKeyIV(content.EncryptedKey, content.IV)
+	if err := database.GetDB().Create(&content).Error; err != nil {
+		t.Fatalf("create content: %v", err)
+	}
+	return content
+}
+
+// reload 重新读取记录（含软删除的行）
+func reload(t *testing.T, dest interface{}, id uint) {
+	t.Helper()
+	if err := database.GetDB().Unscoped().First(dest, id).Error; err != nil {
+		t.Fatalf("reload %T %d: %v", dest, id, err)
+	}
+}
+
+// itoa 路径中的 ID
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,615 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ImportPreflightHandler 根据导入清单（条数和总字节数）预估是否超出用户配额
+func ImportPreflightHandler(c *gin.Context) {
+	var req models.ImportPreflightRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	count, _, err := contentUsage(db, userAddress)
+	if err != nil {
+		serverError(c, err, "Failed to compute usage")
+		return
+	}
+	bytes, err := storageUsed(db, userAddress)
+	if err != nil {
+		serverError(c, err, "Failed to compute usage")
+		return
+	}
+
+	// 条数上限可按用户覆盖
+	maxContent := cfg.MaxContentPerUser
+	var user models.User
+	if err := db.Where("address = ?", userAddress).First(&user).Error; err == nil {
+		maxContent = user.EffectiveMaxContent(maxContent)
+	}
+
+	countStatus := checkQuota(count, maxContent, req.ItemCount)
+	bytesStatus := checkQuota(bytes, cfg.MaxStoragePerUser, req.TotalBytes)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"fits":    countStatus.Fits && bytesStatus.Fits,
+		"count":   countStatus,
+		"bytes":   bytesStatus,
+	})
+}
+
+// importError 导入数据不合法，整个导入回滚并返回 400
+type importError struct {
+	msg string
+}
+
+func (e *importError) Error() string { return e.msg }
+
+// ImportContentHandler 以流的方式导入导出文件（JSON 数组），逐条解析并分批写入
+// 标题和密文与已有内容完全相同的条目视为重复（如重试的导入）并跳过，计入 skipped
+// 默认整个导入在一个事务中完成，任何一条不合法都会回滚；
+// ?partial=true 时逐条处理，跳过不合法的条目并返回每条的结果
+func ImportContentHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	// 验证用户存在
+	var user models.User
+	if err := db.Where("address = ?", userAddress).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	// 导入的内容关联当前激活的公钥
+	keyID, err := activeKeyID(db, userAddress)
+	if err != nil {
+		serverError(c, err, "Database error")
+		return
+	}
+	limit, remaining, err := remainingContent(db, &user)
+	if err != nil {
+		serverError(c, err, "Failed to check quota")
+		return
+	}
+	storage, err := remainingStorage(db, userAddress)
+	if err != nil {
+		serverError(c, err, "Failed to check quota")
+		return
+	}
+
+	imp := &importer{userAddress: userAddress, keyID: keyID, partial: partialMode(c), limit: limit, remaining: remaining, storage: storage}
+	dec := json.NewDecoder(c.Request.Body)
+	if imp.partial {
+		// 中途出错时仍写入已解析的条目
+		if err = imp.run(db, dec); err != nil {
+			imp.flush(db)
+		}
+	} else {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return imp.run(tx, dec)
+		})
+	}
+	if imp.imported > 0 {
+		database.MarkWrite(userAddress)
+	}
+	if err != nil && (!imp.partial || imp.imported == 0) {
+		status, msg := importErrorStatus(err)
+		c.JSON(status, models.ErrorResponse{Error: msg})
+		return
+	}
+	recordAudit(db, c, AuditImport, userAddress, fmt.Sprintf("imported=%d skipped=%d", imp.imported, imp.skipped))
+
+	if !imp.partial {
+		response := gin.H{
+			"success":  true,
+			"imported": imp.imported,
+			"skipped":  imp.skipped,
+		}
+		if len(imp.warnings) > 0 {
+			response["warnings"] = imp.warnings
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// partial 模式下已写入的条目不会回滚，即使后续数据无法解析也返回每条结果
+	sort.Slice(imp.results, func(i, j int) bool { return imp.results[i].Index < imp.results[j].Index })
+	status, response := http.StatusOK, gin.H{
+		"success":  err == nil,
+		"imported": imp.imported,
+		"skipped":  imp.skipped,
+		"failed":   imp.failed,
+		"results":  imp.results,
+	}
+	if len(imp.warnings) > 0 {
+		response["warnings"] = imp.warnings
+	}
+	if err != nil {
+		var msg string
+		status, msg = importErrorStatus(err)
+		response["error"] = msg
+	}
+	c.JSON(status, response)
+}
+
+// ImportContentStreamHandler 与 ImportContentHandler 相同，但以 SSE 返回进度
+// 每写入一批发送 progress 事件 {processed, total, errors}，结束时发送 summary 或 error 事件
+// total 由客户端通过 ?total= 提供（可选），服务端无法预先得知数组长度
+func ImportContentStreamHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	var total *int
+	if t := c.Query("total"); t != "" {
+		n, err := strconv.Atoi(t)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid total"})
+			return
+		}
+		total = &n
+	}
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	// 验证用户存在
+	var user models.User
+	if err := db.Where("address = ?", userAddress).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	// 导入的内容关联当前激活的公钥
+	keyID, err := activeKeyID(db, userAddress)
+	if err != nil {
+		serverError(c, err, "Database error")
+		return
+	}
+	limit, remaining, err := remainingContent(db, &user)
+	if err != nil {
+		serverError(c, err, "Failed to check quota")
+		return
+	}
+	storage, err := remainingStorage(db, userAddress)
+	if err != nil {
+		serverError(c, err, "Failed to check quota")
+		return
+	}
+
+	// 开始返回进度后仍需继续读取请求体
+	if err := http.NewResponseController(c.Writer).EnableFullDuplex(); err != nil {
+		serverError(c, err, "Streaming not supported")
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	imp := &importer{userAddress: userAddress, keyID: keyID, partial: partialMode(c), limit: limit, remaining: remaining, storage: storage}
+	imp.onBatch = func(imp *importer) {
+		c.SSEvent("progress", gin.H{"processed": imp.processed, "total": total, "skipped": imp.skipped, "errors": imp.failed})
+		c.Writer.Flush()
+	}
+
+	dec := json.NewDecoder(c.Request.Body)
+	if imp.partial {
+		if err = imp.run(db, dec); err != nil {
+			imp.flush(db)
+		}
+	} else {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return imp.run(tx, dec)
+		})
+	}
+	if imp.imported > 0 && (imp.partial || err == nil) {
+		database.MarkWrite(userAddress)
+		recordAudit(db, c, AuditImport, userAddress, fmt.Sprintf("imported=%d skipped=%d", imp.imported, imp.skipped))
+	}
+
+	if err != nil {
+		_, msg := importErrorStatus(err)
+		event := gin.H{"error": msg}
+		if imp.partial {
+			event["imported"] = imp.imported
+		}
+		c.SSEvent("error", event)
+		return
+	}
+
+	summary := gin.H{"imported": imp.imported, "skipped": imp.skipped, "errors": imp.failed}
+	if len(imp.warnings) > 0 {
+		summary["warnings"] = imp.warnings
+	}
+	if imp.partial {
+		sort.Slice(imp.results, func(i, j int) bool { return imp.results[i].Index < imp.results[j].Index })
+		summary["results"] = imp.results
+	}
+	c.SSEvent("summary", summary)
+}
+
+// activeKeyID 用户当前激活的公钥 ID，未注册公钥时为 nil
+func activeKeyID(db *gorm.DB, address string) (*uint, error) {
+	var key models.UserKey
+	err := db.Where("address = ? AND active = ?", address, true).Order("id DESC").First(&key).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key.ID, nil
+}
+
+// importErrorStatus 按错误类型返回 413、403、400 或 500 及对应消息
+func importErrorStatus(err error) (int, string) {
+	var maxErr *http.MaxBytesError
+	var invalid *importError
+	var quota *quotaExceededError
+	var storage *storageExceededError
+	switch {
+	case errors.As(err, &maxErr):
+		return http.StatusRequestEntityTooLarge, "Request body too large"
+	case errors.As(err, &quota):
+		return http.StatusForbidden, quota.Error()
+	case errors.As(err, &storage):
+		return http.StatusForbidden, storage.Error()
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest, invalid.msg
+	default:
+		return http.StatusInternalServerError, "Failed to import content"
+	}
+}
+
+// importer 从 JSON 数组流中逐条读取内容，每满 ImportBatchSize 条写入一次
+type importer struct {
+	userAddress string
+	keyID       *uint
+	partial     bool                // 逐条处理，不合法或写入失败的条目记录在 results 中
+	limit       int64               // 内容条数上限，0 表示不限制
+	remaining   int64               // 还能导入的条数，-1 表示不限制
+	storage     int64               // 还能导入的密文字节数，-1 表示不限制
+	onBatch     func(imp *importer) // 每批写入后回调，可为空
+
+	processed int // 已读取的条目数
+	imported  int
+	skipped   int // 与已有内容或本次导入中前面的条目重复
+	failed    int
+	results   []batchItemResult
+	warnings  []string // IV_REUSE_POLICY=warn 时重用 IV 的条目
+
+	seen    map[string]bool // 本次导入中已出现的条目，见 duplicate
+	keyIVs  map[string]bool // 本次导入中已使用的 (encrypted_key, iv)，尚未写入的批次查不到
+	folders map[string]uint // 文件夹名称到 ID，见 folderID
+
+	batch        []models.EncryptedContent
+	batchIndexes []int
+	batchTags    [][]string
+}
+
+// run 读取并写入全部条目；非 partial 模式下遇到不合法条目立即返回 importError
+func (imp *importer) run(tx *gorm.DB, dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return wrapDecodeError(err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return &importError{"Import body must be a JSON array"}
+	}
+
+	for index := 0; dec.More(); index++ {
+		if index >= cfg.ImportMaxItems {
+			return &importError{fmt.Sprintf("Import exceeds the maximum of %d items", cfg.ImportMaxItems)}
+		}
+		imp.processed++
+
+		var item models.ImportItem
+		if err := dec.Decode(&item); err != nil {
+			// 字段类型错误不影响后续解析，partial 模式下只跳过该条
+			var typeErr *json.UnmarshalTypeError
+			if imp.partial && errors.As(err, &typeErr) {
+				imp.fail(index, "invalid item format")
+				continue
+			}
+			return wrapDecodeError(err)
+		}
+		if err := validateImportItem(&item); err != nil {
+			if imp.partial {
+				imp.fail(index, err.Error())
+				continue
+			}
+			return &importError{fmt.Sprintf("Item %d: %s", index, err.Error())}
+		}
+		keyIVHash := utils.HashKeyIV(item.EncryptedKey, item.IV)
+		dup, err := imp.duplicate(tx, &item, keyIVHash)
+		if err != nil {
+			return err
+		}
+		if dup {
+			imp.skipped++
+			if imp.partial {
+				imp.results = append(imp.results, batchItemResult{Index: index, Status: batchStatusSkipped})
+			}
+			continue
+		}
+		if imp.remaining == 0 {
+			if imp.partial {
+				imp.fail(index, "content quota reached")
+				continue
+			}
+			return &quotaExceededError{limit: imp.limit}
+		}
+		if size := int64(len(item.EncryptedData)); imp.storage >= 0 {
+			if size > imp.storage {
+				if imp.partial {
+					imp.fail(index, "storage quota reached")
+					continue
+				}
+				return &storageExceededError{limit: cfg.MaxStoragePerUser}
+			}
+			imp.storage -= size
+		}
+		// 与创建内容相同按 IV_REUSE_POLICY 检测 IV 重用
+		if cfg.IVReusePolicy != "off" {
+			reused := imp.keyIVs[keyIVHash]
+			if !reused {
+				if reused, err = ivReused(tx, imp.userAddress, keyIVHash); err != nil {
+					return err
+				}
+			}
+			if reused && cfg.IVReusePolicy == "reject" {
+				if imp.partial {
+					imp.fail(index, ivReusedMessage)
+					continue
+				}
+				return &importError{fmt.Sprintf("Item %d: %s", index, ivReusedMessage)}
+			}
+			if reused {
+				imp.warnings = append(imp.warnings, fmt.Sprintf("Item %d: %s", index, ivReusedMessage))
+			}
+		}
+		imp.remaining--
+		if imp.keyIVs == nil {
+			imp.keyIVs = make(map[string]bool)
+		}
+		imp.keyIVs[keyIVHash] = true
+
+		folderID, err := imp.folderID(tx, item.Folder)
+		if err != nil {
+			return err
+		}
+		metadata, err := json.Marshal(item.Metadata)
+		if err != nil {
+			return err
+		}
+		nonce, err := utils.GenerateNonce()
+		if err != nil {
+			return err
+		}
+		imp.batch = append(imp.batch, models.EncryptedContent{
+			UserAddress:       imp.userAddress,
+			Title:             item.Title,
+			TitleEncrypted:    item.EncryptedTitle != "",
+			EncryptedTitle:    item.EncryptedTitle,
+			ContentType:       item.ContentType,
+			Metadata:          metadataOrEmpty(string(metadata)),
+			EncryptedData:     item.EncryptedData,
+			EncryptedKey:      item.EncryptedKey,
+			IV:                item.IV,
+			KeyIVHash:         keyIVHash,
+			KeyID:             imp.keyID,
+			FolderID:          folderID,
+			Strength:          item.Strength,
+			AccessWindowStart: item.AccessWindowStart,
+			AccessWindowEnd:   item.AccessWindowEnd,
+			AccessWindowTZ:    item.AccessWindowTZ,
+			ExpiresAt:         item.ExpiresAt,
+			AvailableAt:       item.AvailableAt,
+			Nonce:             nonce,
+			NonceIssuedAt:     time.Now(),
+		})
+		imp.batchIndexes = append(imp.batchIndexes, index)
+		imp.batchTags = append(imp.batchTags, item.Tags)
+		if len(imp.batch) >= cfg.ImportBatchSize {
+			if err := imp.flush(tx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return wrapDecodeError(err)
+	}
+	return imp.flush(tx)
+}
+
+// duplicate 判断条目是否与用户已有的内容（标题、加密标题和密文均相同）或本次导入中前面的条目重复
+// 尚未写入的批次查不到，因此同时用 seen 记录本次导入已出现的条目
+func (imp *importer) duplicate(tx *gorm.DB, item *models.ImportItem, keyIVHash string) (bool, error) {
+	sum := sha256.Sum256([]byte(item.EncryptedData))
+	key := strings.Join([]string{keyIVHash, hex.EncodeToString(sum[:]), item.Title, item.EncryptedTitle}, "\x00")
+	if imp.seen[key] {
+		return true, nil
+	}
+	if imp.seen == nil {
+		imp.seen = make(map[string]bool)
+	}
+	imp.seen[key] = true
+
+	var count int64
+	err := tx.Model(&models.EncryptedContent{}).
+		Where("user_address = ? AND key_iv_hash = ? AND encrypted_data = ? AND title = ? AND encrypted_title = ?",
+			imp.userAddress, keyIVHash, item.EncryptedData, item.Title, item.EncryptedTitle).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// errImportTags 条目已写入但标签写入失败
+var errImportTags = errors.New("failed to save tags")
+
+// flush 写入当前批次；partial 模式下批次写入失败时逐条重试，只有写入失败的条目记为失败
+// partial 模式下每条内容与其标签在同一事务中写入，标签写入失败时该条目整体记为失败
+func (imp *importer) flush(tx *gorm.DB) error {
+	if len(imp.batch) == 0 {
+		return nil
+	}
+	if !imp.partial {
+		if err := saveImported(tx, imp.userAddress, imp.batch, imp.batchTags); err != nil {
+			return err
+		}
+		imp.imported += len(imp.batch)
+	} else if err := tx.Transaction(func(btx *gorm.DB) error {
+		return saveImported(btx, imp.userAddress, imp.batch, imp.batchTags)
+	}); err == nil {
+		imp.imported += len(imp.batch)
+		for i, index := range imp.batchIndexes {
+			id := imp.batch[i].ID
+			imp.results = append(imp.results, batchItemResult{Index: index, ID: &id, Status: batchStatusOK})
+		}
+	} else {
+		for i, index := range imp.batchIndexes {
+			// 回滚后清除批量写入时回填的主键
+			imp.batch[i].ID = 0
+			err := tx.Transaction(func(itx *gorm.DB) error {
+				return saveImported(itx, imp.userAddress, imp.batch[i:i+1], imp.batchTags[i:i+1])
+			})
+			switch {
+			case errors.Is(err, errImportTags):
+				imp.fail(index, errImportTags.Error())
+			case err != nil:
+				imp.fail(index, "failed to save item")
+			default:
+				imp.imported++
+				id := imp.batch[i].ID
+				imp.results = append(imp.results, batchItemResult{Index: index, ID: &id, Status: batchStatusOK})
+			}
+		}
+	}
+	imp.batch = imp.batch[:0]
+	imp.batchIndexes = imp.batchIndexes[:0]
+	imp.batchTags = imp.batchTags[:0]
+	if imp.onBatch != nil {
+		imp.onBatch(imp)
+	}
+	return nil
+}
+
+// saveImported 写入一批内容及各自的标签
+func saveImported(tx *gorm.DB, address string, rows []models.EncryptedContent, tags [][]string) error {
+	if err := tx.Create(&rows).Error; err != nil {
+		return err
+	}
+	for i, names := range tags {
+		if len(names) == 0 {
+			continue
+		}
+		if err := tagContent(tx, address, rows[i].ID, names); err != nil {
+			return fmt.Errorf("%w: %v", errImportTags, err)
+		}
+	}
+	return nil
+}
+
+// folderID 查找用户的同名文件夹，不存在时创建；name 为空时返回 nil（根目录）
+func (imp *importer) folderID(tx *gorm.DB, name string) (*uint, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if id, ok := imp.folders[name]; ok {
+		return &id, nil
+	}
+	folder := models.Folder{OwnerAddress: imp.userAddress, Name: name}
+	if err := tx.Where("owner_address = ? AND name = ?", imp.userAddress, name).Order("id").FirstOrCreate(&folder).Error; err != nil {
+		return nil, err
+	}
+	if imp.folders == nil {
+		imp.folders = make(map[string]uint)
+	}
+	imp.folders[name] = folder.ID
+	return &folder.ID, nil
+}
+
+// fail 记录失败的条目
+func (imp *importer) fail(index int, msg string) {
+	imp.failed++
+	imp.results = append(imp.results, batchItemResult{Index: index, Status: batchStatusError, Error: msg})
+}
+
+// 导入条目的名称长度上限，与 CreateFolderRequest 和 ContentFields.Tags 的校验规则一致
+const (
+	maxFolderNameLength = 100
+	maxTagNameLength    = 50
+)
+
+// validateImportItem 校验单条导入内容，规则与创建内容相同；过期时间可以已经过去，以便原样恢复导出的内容
+// 同时规范化内容类型和标签
+func validateImportItem(item *models.ImportItem) error {
+	switch {
+	case item.EncryptedData == "":
+		return errors.New("encrypted_data is required")
+	case item.EncryptedKey == "":
+		return errors.New("encrypted_key is required")
+	case item.IV == "":
+		return errors.New("iv is required")
+	case item.Title == "" && item.EncryptedTitle == "":
+		return errors.New("title or encrypted_title is required")
+	case encryptedDataTooLarge(item.EncryptedData, cfg.MaxUploadSize):
+		// 导出中可能包含分块上传保存的内容，按分块上传的上限校验
+		return fmt.Errorf("encrypted_data exceeds the maximum size of %d bytes", cfg.MaxUploadSize)
+	case utf8.RuneCountInString(item.Folder) > maxFolderNameLength:
+		return fmt.Errorf("folder must be at most %d characters", maxFolderNameLength)
+	case item.AvailableAt != nil && item.ExpiresAt != nil && !item.AvailableAt.Before(*item.ExpiresAt):
+		return errors.New("available_at must be before expires_at")
+	}
+	if err := utils.ValidateCiphertextFields(item.EncryptedData, item.EncryptedKey, item.IV); err != nil {
+		return err
+	}
+	if err := validateAccessWindow(item.AccessWindowStart, item.AccessWindowEnd, item.AccessWindowTZ); err != nil {
+		return err
+	}
+	req := models.CreateContentRequest{ContentFields: models.ContentFields{ContentType: item.ContentType, Metadata: item.Metadata, Strength: item.Strength}}
+	if err := validateContentType(&req); err != nil {
+		return err
+	}
+	item.ContentType = req.ContentType
+
+	item.Tags = normalizeTags(item.Tags)
+	if len(item.Tags) > cfg.MaxTagsPerContent {
+		return fmt.Errorf("at most %d tags are allowed per content", cfg.MaxTagsPerContent)
+	}
+	for _, tag := range item.Tags {
+		if utf8.RuneCountInString(tag) > maxTagNameLength {
+			return fmt.Errorf("tag must be at most %d characters", maxTagNameLength)
+		}
+	}
+	return nil
+}
+
+// wrapDecodeError 请求体超限的错误原样返回，其余解析错误视为数据不合法
+func wrapDecodeError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return err
+	}
+	return &importError{"Invalid import format"}
+}
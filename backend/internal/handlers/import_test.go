@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	setupTest(t, noExportCooldown)
+	db := database.GetDB()
+	alice, bob := newWallet(t), newWallet(t)
+	createUser(t, alice.address)
+	createUser(t, bob.address)
+
+	folder := models.Folder{OwnerAddress: alice.address, Name: "wallets"}
+	db.Create(&folder)
+	start, end := "09:00", "18:00"
+	expiresAt := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	availableAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	strength := 3
+	original := seedContent(t, alice.address, func(c *models.EncryptedContent) {
+		c.ContentType = passwordContentType
+		c.Metadata = `{"username":"alice"}`
+		c.Strength = &strength
+		c.AccessWindowStart, c.AccessWindowEnd, c.AccessWindowTZ = &start, &end, "Asia/Shanghai"
+		c.ExpiresAt, c.AvailableAt = &expiresAt, &availableAt
+		c.FolderID = &folder.ID
+	})
+	if err := tagContent(db, alice.address, original.ID, []string{"work", "keys"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newRouter(alice.address)
+	r.GET("/export", ExportContentHandler)
+	w := doJSON(t, r, http.MethodGet, "/export", nil, alice.reauthHeaders(t, "export", time.Now())...)
+	expectStatus(t, w, http.StatusOK)
+	var exported []json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &exported); err != nil || len(exported) != 1 {
+		t.Fatalf("export = %s (%v)", w.Body.String(), err)
+	}
+
+	// 导入到另一个账户，该账户还没有同名文件夹和标签
+	var items []models.ImportItem
+	if err := json.Unmarshal(w.Body.Bytes(), &items); err != nil {
+		t.Fatal(err)
+	}
+	r = newRouter(bob.address)
+	r.POST("/import", ImportContentHandler)
+	w = doJSON(t, r, http.MethodPost, "/import", items)
+	expectStatus(t, w, http.StatusOK)
+
+	var imported models.EncryptedContent
+	if err := db.Where("user_address = ?", bob.address).First(&imported).Error; err != nil {
+		t.Fatal(err)
+	}
+	if imported.ContentType != passwordContentType || imported.Metadata != original.Metadata {
+		t.Errorf("content_type/metadata = %q %q", imported.ContentType, imported.Metadata)
+	}
+	if imported.Strength == nil || *imported.Strength != strength {
+		t.Errorf("strength = %v", imported.Strength)
+	}
+	if imported.AccessWindowStart == nil || *imported.AccessWindowStart != start ||
+		imported.AccessWindowEnd == nil || *imported.AccessWindowEnd != end || imported.AccessWindowTZ != "Asia/Shanghai" {
+		t.Errorf("access window = %v %v %q", imported.AccessWindowStart, imported.AccessWindowEnd, imported.AccessWindowTZ)
+	}
+	if imported.ExpiresAt == nil || !imported.ExpiresAt.Equal(expiresAt) || imported.AvailableAt == nil || !imported.AvailableAt.Equal(availableAt) {
+		t.Errorf("expires_at/available_at = %v %v", imported.ExpiresAt, imported.AvailableAt)
+	}
+
+	var bobFolder models.Folder
+	if imported.FolderID == nil || db.First(&bobFolder, *imported.FolderID).Error != nil ||
+		bobFolder.OwnerAddress != bob.address || bobFolder.Name != folder.Name {
+		t.Errorf("folder = %v %+v", imported.FolderID, bobFolder)
+	}
+	tags, err := contentTagNames(db, []uint{imported.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"keys", "work"}; !reflect.DeepEqual(tags[imported.ID], want) {
+		t.Errorf("tags = %v, want %v", tags[imported.ID], want)
+	}
+}
+
+func TestImportChecksIVReuse(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		status  int
+		warning bool
+	}{
+		{"reject", "reject", http.StatusBadRequest, false},
+		{"warn", "warn", http.StatusOK, true},
+		{"off", "off", http.StatusOK, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) { c.IVReusePolicy = tt.policy })
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			existing := seedContent(t, alice.address)
+
+			// 密文不同（不是重复条目），但沿用了已有内容的 encrypted_key 和 iv
+			item := models.ImportItem{Title: "imported", EncryptedData: randomBase64(t, 48), EncryptedKey: existing.EncryptedKey, IV: existing.IV}
+			r := newRouter(alice.address)
+			r.POST("/import", ImportContentHandler)
+			w := doJSON(t, r, http.MethodPost, "/import", []models.ImportItem{item})
+			expectStatus(t, w, tt.status)
+			body := decodeBody(t, w)
+			if _, ok := body["warnings"]; ok != tt.warning {
+				t.Errorf("warnings present = %v, want %v: %s", ok, tt.warning, w.Body.String())
+			}
+			if tt.status == http.StatusBadRequest && !strings.Contains(w.Body.String(), ivReusedMessage) {
+				t.Errorf("unexpected error %s", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("within one import", func(t *testing.T) {
+		setupTest(t, func(c *config.Config) { c.IVReusePolicy = "reject" })
+		alice := newWallet(t)
+		createUser(t, alice.address)
+
+		key, iv := randomBase64(t, 32), randomBase64(t, 12)
+		items := []models.ImportItem{
+			{Title: "first", EncryptedData: randomBase64(t, 48), EncryptedKey: key, IV: iv},
+			{Title: "second", EncryptedData: randomBase64(t, 48), EncryptedKey: key, IV: iv},
+		}
+		r := newRouter(alice.address)
+		r.POST("/import", ImportContentHandler)
+		expectStatus(t, doJSON(t, r, http.MethodPost, "/import", items), http.StatusBadRequest)
+
+		var count int64
+		database.GetDB().Model(&models.EncryptedContent{}).Where("key_iv_hash = ?", utils.HashKeyIV(key, iv)).Count(&count)
+		if count != 0 {
+			t.Errorf("%d rows written by rejected import", count)
+		}
+	})
+}
+
+func TestImportValidatesRestoredFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*models.ImportItem)
+	}{
+		{"invalid access window", func(i *models.ImportItem) { s := "25:00"; i.AccessWindowStart, i.AccessWindowEnd = &s, &s }},
+		{"strength on a note", func(i *models.ImportItem) { s := 2; i.Strength = &s }},
+		{"available after expiry", func(i *models.ImportItem) {
+			now := time.Now()
+			later := now.Add(time.Hour)
+			i.ExpiresAt, i.AvailableAt = &now, &later
+		}},
+		{"too many tags", func(i *models.ImportItem) {
+			for n := 0; n <= 20; n++ {
+				i.Tags = append(i.Tags, "tag"+itoa(uint(n)))
+			}
+		}},
+		{"folder name too long", func(i *models.ImportItem) { i.Folder = strings.Repeat("f", 101) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			alice := newWallet(t)
+			createUser(t, alice.address)
+
+			item := models.ImportItem{Title: "imported", EncryptedData: randomBase64(t, 48), EncryptedKey: randomBase64(t, 32), IV: randomBase64(t, 12)}
+			tt.modify(&item)
+			r := newRouter(alice.address)
+			r.POST("/import", ImportContentHandler)
+			expectStatus(t, doJSON(t, r, http.MethodPost, "/import", []models.ImportItem{item}), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestPartialImportIsolatesFailingItems(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice := newWallet(t)
+	createUser(t, alice.address)
+
+	// 标题为 poison 的内容和名为 broken 的标签写入失败，同批次的其他条目不受影响
+	db.Callback().Create().Before("gorm:create").Register("test:fail_rows", func(tx *gorm.DB) {
+		switch rows := tx.Statement.Dest.(type) {
+		case *[]models.EncryptedContent:
+			for _, row := range *rows {
+				if row.Title == "poison" {
+					tx.AddError(errors.New("insert failed"))
+				}
+			}
+		case *models.Tag:
+			if rows.Name == "broken" {
+				tx.AddError(errors.New("insert failed"))
+			}
+		}
+	})
+
+	item := func(title string, tags ...string) models.ImportItem {
+		return models.ImportItem{Title: title, EncryptedData: randomBase64(t, 48), EncryptedKey: randomBase64(t, 32), IV: randomBase64(t, 12), Tags: tags}
+	}
+	r := newRouter(alice.address)
+	r.POST("/import", ImportContentHandler)
+	w := doJSON(t, r, http.MethodPost, "/import?partial=true", []models.ImportItem{
+		item("first", "work"),
+		item("poison"),
+		item("tagged", "broken"),
+		item("last"),
+	})
+	expectStatus(t, w, http.StatusOK)
+	body := decodeBody(t, w)
+	if body["imported"] != float64(2) || body["failed"] != float64(2) {
+		t.Fatalf("response = %s", w.Body.String())
+	}
+	want := []struct {
+		status, error string
+	}{
+		{batchStatusOK, ""},
+		{batchStatusError, "failed to save item"},
+		{batchStatusError, "failed to save tags"},
+		{batchStatusOK, ""},
+	}
+	results := body["results"].([]interface{})
+	for i, w := range want {
+		result := results[i].(map[string]interface{})
+		if result["status"] != w.status || (w.error != "" && result["error"] != w.error) {
+			t.Errorf("result %d = %v, want %s %q", i, result, w.status, w.error)
+		}
+	}
+
+	var titles []string
+	db.Model(&models.EncryptedContent{}).Where("user_address = ?", alice.address).Order("id").Pluck("title", &titles)
+	if !reflect.DeepEqual(titles, []string{"first", "last"}) {
+		t.Errorf("stored titles = %v", titles)
+	}
+	firstID := uint(results[0].(map[string]interface{})["id"].(float64))
+	tags, err := contentTagNames(db, []uint{firstID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags[firstID], []string{"work"}) {
+		t.Errorf("tags of the retried item = %v", tags[firstID])
+	}
+}
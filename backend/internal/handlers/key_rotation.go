@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// contentIV 轮换时需要的内容字段，iv 用于重新计算 key_iv_hash
+type contentIV struct {
+	ID uint
+	IV string
+}
+
+// keySetMismatchError 提交的内容 ID 与应重新包装的内容不一致
+type keySetMismatchError struct {
+	field      string // 对应的请求字段：keys 或 recipient_keys
+	missing    []uint // 应重新包装但未提交新密钥的内容
+	unexpected []uint // 提交了但不需要重新包装的内容
+}
+
+func (e *keySetMismatchError) Error() string {
+	if e.field == "recipient_keys" {
+		return "recipient_keys must cover exactly the content shared with the user"
+	}
+	return "keys must cover exactly the content owned by the user"
+}
+
+// errKeyPreviouslyUsed 新公钥与当前或历史公钥相同
+var errKeyPreviouslyUsed = errors.New("public key previously used")
+
+// RotateKeyHandler 轮换加密公钥：客户端先用旧私钥解开对称密钥、再用新公钥重新包装，与新公钥一起提交
+// 新公钥是任意 secp256k1 公钥，不要求由地址推导，由地址对包含新公钥和当前登录 nonce 的消息签名授权；不能是该地址用过的公钥
+// 服务端在一个事务中替换公钥和全部 encrypted_key：keys 须恰好覆盖用户的全部内容（含回收站），
+// recipient_keys 须恰好覆盖共享给该用户的内容；历史版本的密钥仍由旧公钥包装，一并删除
+// 用户共享出去的接收者记录和分享链接包装的是内容的对称密钥，轮换不改变对称密钥，因此保持有效
+func RotateKeyHandler(c *gin.Context) {
+	var req models.RotateKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	// 公钥由其推导的地址标识，压缩与非压缩写法视为同一公钥
+	fingerprint, err := utils.PublicKeyAddress(req.PublicKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid public key"})
+		return
+	}
+
+	rewrapped, ok := rewrappedKeys(c, req.Keys, "Key")
+	if !ok {
+		return
+	}
+	recipientKeys, ok := rewrappedKeys(c, req.RecipientKeys, "Recipient key")
+	if !ok {
+		return
+	}
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("address = ?", userAddress).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	if accountLocked(&user) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: lockedMessage(&user)})
+		return
+	}
+
+	// 验证 nonce（防重放）
+	if user.Nonce != req.Nonce {
+		detectNonceReuse(db, c, user.Address, req.Nonce, signedBy(c, utils.GenerateKeyRotationMessage(user.Address, req.PublicKey, req.Nonce), req.Signature, user.Address))
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	}
+
+	message := utils.GenerateKeyRotationMessage(user.Address, req.PublicKey, req.Nonce)
+	if err := verifySignature(c.Request.Context(), "rotate-key", message, req.Signature, user.Address, 0); err != nil {
+		rejectSignature(c, err, http.StatusUnauthorized, "Invalid signature")
+		return
+	}
+	if !requireFreshAuth(c, "rotate-key", user.Address) {
+		return
+	}
+
+	newNonce, err := utils.GenerateNonce()
+	if err != nil {
+		serverError(c, err, "Failed to generate nonce")
+		return
+	}
+
+	var key *models.UserKey
+	var purgedRevisions int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 仅当 nonce 仍为本次使用的值时才继续，并发提交同一签名只有一个能成功
+		now := time.Now()
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND nonce = ?", user.ID, req.Nonce).
+			Updates(map[string]interface{}{"nonce": newNonce, "nonce_issued_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errNonceConsumed
+		}
+
+		var history []string
+		if err := tx.Model(&models.UserKey{}).Where("address = ?", user.Address).Pluck("public_key", &history).Error; err != nil {
+			return err
+		}
+		for _, old := range append(history, user.PublicKey) {
+			if oldFingerprint, err := utils.PublicKeyAddress(old); err == nil && oldFingerprint == fingerprint {
+				return errKeyPreviouslyUsed
+			}
+		}
+
+		var rows []contentIV
+		if err := tx.Unscoped().Model(&models.EncryptedContent{}).
+			Where("user_address = ?", user.Address).Select("id, iv").Find(&rows).Error; err != nil {
+			return err
+		}
+		contentIDs := make([]uint, len(rows))
+		for i, row := range rows {
+			contentIDs[i] = row.ID
+		}
+		if err := checkKeySet("keys", contentIDs, rewrapped); err != nil {
+			return err
+		}
+		var sharedIDs []uint
+		if err := tx.Model(&models.ContentRecipient{}).
+			Where("LOWER(recipient_address) = ?", strings.ToLower(user.Address)).Pluck("content_id", &sharedIDs).Error; err != nil {
+			return err
+		}
+		if err := checkKeySet("recipient_keys", sharedIDs, recipientKeys); err != nil {
+			return err
+		}
+
+		var err error
+		key, err = activatePublicKey(tx, &user, req.PublicKey, req.Label)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			encryptedKey := rewrapped[row.ID]
+			if err := tx.Unscoped().Model(&models.EncryptedContent{}).Where("id = ?", row.ID).
+				Updates(map[string]interface{}{
+					"encrypted_key": encryptedKey,
+					"key_id":        key.ID,
+					"key_iv_hash":   utils.HashKeyIV(encryptedKey, row.IV),
+				}).Error; err != nil {
+				return err
+			}
+		}
+		for contentID, encryptedKey := range recipientKeys {
+			if err := tx.Model(&models.ContentRecipient{}).
+				Where("content_id = ? AND LOWER(recipient_address) = ?", contentID, strings.ToLower(user.Address)).
+				Update("encrypted_key", encryptedKey).Error; err != nil {
+				return err
+			}
+		}
+
+		// 历史版本的 encrypted_key 仍由旧公钥包装，恢复后新私钥无法解开
+		if len(contentIDs) > 0 {
+			result := tx.Where("content_id IN ?", contentIDs).Delete(&models.ContentRevision{})
+			if result.Error != nil {
+				return result.Error
+			}
+			purgedRevisions = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		var mismatch *keySetMismatchError
+		switch {
+		case errors.Is(err, errNonceConsumed):
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		case errors.Is(err, errKeyPreviouslyUsed):
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Public key was already used by this address; rotate to a new key"})
+		case errors.As(err, &mismatch):
+			prefix := "content"
+			if mismatch.field == "recipient_keys" {
+				prefix = "recipient_content"
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":                         mismatch.Error(),
+				"missing_" + prefix + "_ids":    mismatch.missing,
+				"unexpected_" + prefix + "_ids": mismatch.unexpected,
+			})
+		default:
+			serverError(c, err, "Failed to rotate key")
+		}
+		return
+	}
+	markNonceUsed(db, user.Address, req.Nonce)
+	database.MarkWrite(user.Address)
+	recordAudit(db, c, AuditKeyRotation, user.Address, fmt.Sprintf("key_id=%d rewrapped=%d recipient_keys=%d purged_revisions=%d",
+		key.ID, len(rewrapped), len(recipientKeys), purgedRevisions))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":                  true,
+		"key_id":                   key.ID,
+		"rewrapped":                len(rewrapped),
+		"rewrapped_recipient_keys": len(recipientKeys),
+		"purged_revisions":         purgedRevisions,
+		"nonce":                    newNonce,
+	})
+}
+
+// rewrappedKeys 校验提交的新密钥并按内容 ID 建立索引，同一内容重复或格式不合法时返回 400
+// label 用于错误信息中标明是哪一组密钥
+func rewrappedKeys(c *gin.Context, keys []models.RewrappedKey, label string) (map[uint]string, bool) {
+	rewrapped := make(map[uint]string, len(keys))
+	for i, k := range keys {
+		if _, dup := rewrapped[k.ContentID]; dup {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("Duplicate content_id %d", k.ContentID)})
+			return nil, false
+		}
+		if err := utils.ValidateEncryptedKey(k.EncryptedKey); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("%s %d: %s", label, i, err.Error())})
+			return nil, false
+		}
+		rewrapped[k.ContentID] = k.EncryptedKey
+	}
+	return rewrapped, true
+}
+
+// checkKeySet 检查提交的新密钥是否恰好覆盖 ids 中的全部内容，field 为对应的请求字段
+func checkKeySet(field string, ids []uint, rewrapped map[uint]string) error {
+	owned := make(map[uint]bool, len(ids))
+	mismatch := &keySetMismatchError{field: field, missing: []uint{}, unexpected: []uint{}}
+	for _, id := range ids {
+		owned[id] = true
+		if _, ok := rewrapped[id]; !ok {
+			mismatch.missing = append(mismatch.missing, id)
+		}
+	}
+	for id := range rewrapped {
+		if !owned[id] {
+			mismatch.unexpected = append(mismatch.unexpected, id)
+		}
+	}
+	if len(mismatch.missing) == 0 && len(mismatch.unexpected) == 0 {
+		return nil
+	}
+	sort.Slice(mismatch.missing, func(i, j int) bool { return mismatch.missing[i] < mismatch.missing[j] })
+	sort.Slice(mismatch.unexpected, func(i, j int) bool { return mismatch.unexpected[i] < mismatch.unexpected[j] })
+	return mismatch
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// rotationFixture alice 已注册钱包公钥，拥有一条带历史版本的内容，bob 共享了一条内容给她
+type rotationFixture struct {
+	alice, bob testWallet
+	user       models.User
+	oldKey     *models.UserKey
+	own        models.EncryptedContent
+	shared     models.EncryptedContent
+}
+
+func newRotationFixture(t *testing.T) *rotationFixture {
+	t.Helper()
+	setupTest(t)
+	db := database.GetDB()
+	f := &rotationFixture{alice: newWallet(t), bob: newWallet(t)}
+	f.user = createUser(t, f.alice.address)
+	createUser(t, f.bob.address)
+	var err error
+	if f.oldKey, err = activatePublicKey(db, &f.user, f.alice.publicKey(), ""); err != nil {
+		t.Fatal(err)
+	}
+	f.own = seedContent(t, f.alice.address, func(c *models.EncryptedContent) {
+		c.KeyID = &f.oldKey.ID
+		c.Version = 2
+	})
+	db.Create(&models.ContentRevision{ContentID: f.own.ID, Version: 1, EncryptedData: randomBase64(t, 64), EncryptedKey: randomBase64(t, 32), IV: randomBase64(t, 12), KeyID: &f.oldKey.ID})
+	f.shared = seedContent(t, f.bob.address)
+	db.Create(&models.ContentRecipient{ContentID: f.shared.ID, OwnerAddress: f.bob.address, RecipientAddress: f.alice.address, EncryptedKey: randomBase64(t, 32)})
+	return f
+}
+
+// request 轮换到 publicKey 的请求，由 signer 签名
+func (f *rotationFixture) request(t *testing.T, publicKey string, signer testWallet) models.RotateKeyRequest {
+	return models.RotateKeyRequest{
+		PublicKey:     publicKey,
+		Nonce:         f.user.Nonce,
+		Signature:     signer.sign(t, utils.GenerateKeyRotationMessage(f.alice.address, publicKey, f.user.Nonce)),
+		Keys:          []models.RewrappedKey{{ContentID: f.own.ID, EncryptedKey: randomBase64(t, 32)}},
+		RecipientKeys: []models.RewrappedKey{{ContentID: f.shared.ID, EncryptedKey: randomBase64(t, 32)}},
+	}
+}
+
+func (f *rotationFixture) rotate(t *testing.T, req models.RotateKeyRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	r := newRouter(f.alice.address)
+	r.POST("/rotate-key", RotateKeyHandler)
+	return doJSON(t, r, http.MethodPost, "/rotate-key", req)
+}
+
+func TestRotateKeyToIndependentKey(t *testing.T) {
+	f := newRotationFixture(t)
+	db := database.GetDB()
+
+	// 新加密密钥与钱包地址无关，由 alice 的签名授权
+	encryptionKey := newWallet(t)
+	req := f.request(t, encryptionKey.publicKey(), f.alice)
+	w := f.rotate(t, req)
+	expectStatus(t, w, http.StatusOK)
+	body := decodeBody(t, w)
+	if body["purged_revisions"] != float64(1) || body["rewrapped_recipient_keys"] != float64(1) {
+		t.Errorf("response = %v", body)
+	}
+
+	var own models.EncryptedContent
+	reload(t, &own, f.own.ID)
+	if own.EncryptedKey != req.Keys[0].EncryptedKey || own.KeyID == nil || *own.KeyID == f.oldKey.ID {
+		t.Errorf("content key = %s (key_id %v), want rewrapped under the new key", own.EncryptedKey, own.KeyID)
+	}
+	var revisions int64
+	db.Model(&models.ContentRevision{}).Where("content_id = ?", f.own.ID).Count(&revisions)
+	if revisions != 0 {
+		t.Errorf("%d revisions wrapped under the old key remain", revisions)
+	}
+	var recipient models.ContentRecipient
+	db.Where("content_id = ?", f.shared.ID).First(&recipient)
+	if recipient.EncryptedKey != req.RecipientKeys[0].EncryptedKey {
+		t.Errorf("recipient key was not rewrapped")
+	}
+	var old models.UserKey
+	db.First(&old, f.oldKey.ID)
+	if old.Active {
+		t.Errorf("old key still active")
+	}
+}
+
+func TestRotateKeyRejections(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, f *rotationFixture) models.RotateKeyRequest
+		status  int
+		field   string // 期望响应中非空的字段
+	}{
+		{"current key", func(t *testing.T, f *rotationFixture) models.RotateKeyRequest {
+			return f.request(t, f.alice.publicKey(), f.alice)
+		}, http.StatusBadRequest, ""},
+		{"current key in compressed form", func(t *testing.T, f *rotationFixture) models.RotateKeyRequest {
+			return f.request(t, hexutil.Encode(crypto.CompressPubkey(&f.alice.key.PublicKey)), f.alice)
+		}, http.StatusBadRequest, ""},
+		{"previously used key", func(t *testing.T, f *rotationFixture) models.RotateKeyRequest {
+			retired := newWallet(t)
+			key := models.UserKey{Address: f.alice.address, PublicKey: retired.publicKey(), Active: true}
+			database.GetDB().Create(&key)
+			database.GetDB().Model(&key).Update("active", false)
+			return f.request(t, retired.publicKey(), f.alice)
+		}, http.StatusBadRequest, ""},
+		{"not a secp256k1 key", func(t *testing.T, f *rotationFixture) models.RotateKeyRequest {
+			return f.request(t, "0x04"+randomBase64(t, 8), f.alice)
+		}, http.StatusBadRequest, ""},
+		{"signed by another address", func(t *testing.T, f *rotationFixture) models.RotateKeyRequest {
+			return f.request(t, newWallet(t).publicKey(), f.bob)
+		}, http.StatusUnauthorized, ""},
+		{"missing recipient keys", func(t *testing.T, f *rotationFixture) models.RotateKeyRequest {
+			req := f.request(t, newWallet(t).publicKey(), f.alice)
+			req.RecipientKeys = nil
+			return req
+		}, http.StatusBadRequest, "missing_recipient_content_ids"},
+		{"missing content keys", func(t *testing.T, f *rotationFixture) models.RotateKeyRequest {
+			req := f.request(t, newWallet(t).publicKey(), f.alice)
+			req.Keys = []models.RewrappedKey{}
+			return req
+		}, http.StatusBadRequest, "missing_content_ids"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRotationFixture(t)
+			w := f.rotate(t, tt.prepare(t, f))
+			expectStatus(t, w, tt.status)
+			if tt.field != "" {
+				if ids, _ := decodeBody(t, w)[tt.field].([]interface{}); len(ids) == 0 {
+					t.Errorf("%s empty in %s", tt.field, w.Body.String())
+				}
+			}
+
+			// 被拒绝的轮换不做任何修改
+			var own models.EncryptedContent
+			reload(t, &own, f.own.ID)
+			var revisions int64
+			database.GetDB().Model(&models.ContentRevision{}).Where("content_id = ?", f.own.ID).Count(&revisions)
+			if own.EncryptedKey != f.own.EncryptedKey || revisions != 1 {
+				t.Errorf("rejected rotation modified content (revisions %d)", revisions)
+			}
+		})
+	}
+}
+
+func TestRestoreRevisionRejectsInactiveKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		active bool
+		status int
+	}{
+		{"active key", true, http.StatusOK},
+		{"retired key", false, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			db := database.GetDB()
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			key := models.UserKey{Address: alice.address, PublicKey: newWallet(t).publicKey(), Active: true}
+			db.Create(&key)
+			if !tt.active {
+				// default:true 不接受创建时的 false，创建后再更新
+				db.Model(&key).Update("active", false)
+			}
+			content := seedContent(t, alice.address, func(c *models.EncryptedContent) { c.Version = 2 })
+			db.Create(&models.ContentRevision{ContentID: content.ID, Version: 1, EncryptedData: randomBase64(t, 64), EncryptedKey: randomBase64(t, 32), IV: randomBase64(t, 12), KeyID: &key.ID})
+
+			r := newRouter(alice.address)
+			r.POST("/content/:id/versions/:version/restore", RestoreRevisionHandler)
+			w := doJSON(t, r, http.MethodPost, "/content/"+itoa(content.ID)+"/versions/1/restore", models.RestoreRevisionRequest{
+				Nonce:     content.Nonce,
+				Signature: alice.sign(t, utils.GenerateRestoreRevisionMessage(content.ID, 1, content.Nonce)),
+			})
+			expectStatus(t, w, tt.status)
+
+			var after models.EncryptedContent
+			reload(t, &after, content.ID)
+			if restored := after.Version == 3; restored != (tt.status == http.StatusOK) {
+				t.Errorf("version = %d after restore with status %d", after.Version, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateLabelHandler 创建带颜色的标签
+func CreateLabelHandler(c *gin.Context) {
+	var req models.CreateLabelRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	label := models.Label{Address: userAddress, Name: req.Name, Color: req.Color}
+	if err := db.Create(&label).Error; err != nil {
+		serverError(c, err, "Failed to create label")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"label":   label.Summary(),
+	})
+}
+
+// ListLabelsHandler 列出用户的标签
+func ListLabelsHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var labels []models.Label
+	if err := db.Where("address = ?", userAddress).Order("name").Find(&labels).Error; err != nil {
+		serverError(c, err, "Failed to fetch labels")
+		return
+	}
+
+	response := make([]models.LabelSummary, len(labels))
+	for i, label := range labels {
+		response[i] = *label.Summary()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"labels":  response,
+	})
+}
+
+// DeleteLabelHandler 删除标签，并清除引用它的内容上的 label_id
+func DeleteLabelHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var label models.Label
+	if err := db.Where("id = ? AND address = ?", c.Param("label_id"), userAddress).First(&label).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Label not found"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.EncryptedContent{}).
+			Where("user_address = ? AND label_id = ?", userAddress, label.ID).
+			Update("label_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&label).Error
+	})
+	if err != nil {
+		serverError(c, err, "Failed to delete label")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// SetContentLabelHandler 为内容设置标签（label_id 为空表示清除）
+func SetContentLabelHandler(c *gin.Context) {
+	var req models.SetContentLabelRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	// 标签必须属于当前用户
+	var label *models.Label
+	if req.LabelID != nil {
+		var found models.Label
+		if err := db.Where("id = ? AND address = ?", *req.LabelID, userAddress).First(&found).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Label not found"})
+			} else {
+				serverError(c, err, "Database error")
+			}
+			return
+		}
+		label = &found
+	}
+
+	result := db.Model(&models.EncryptedContent{}).
+		Where("id = ? AND user_address = ?", c.Param("id"), userAddress).
+		Update("label_id", req.LabelID)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to update label")
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"label":   label.Summary(),
+	})
+}
+
+// userLabels 按 ID 索引用户的所有标签
+func userLabels(db *gorm.DB, address string) (map[uint]*models.LabelSummary, error) {
+	var labels []models.Label
+	if err := db.Where("address = ?", address).Find(&labels).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.LabelSummary, len(labels))
+	for _, label := range labels {
+		byID[label.ID] = label.Summary()
+	}
+	return byID, nil
+}
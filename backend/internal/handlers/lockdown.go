@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminLockdownHandler 查询紧急锁定状态
+func AdminLockdownHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"active":  database.LockdownActive(),
+	})
+}
+
+// AdminSetLockdownHandler 开启或解除紧急锁定
+// 锁定期间解密、内容读取和分享链接接口均返回 503，登录、管理员接口和健康检查不受影响
+func AdminSetLockdownHandler(c *gin.Context) {
+	var req models.SetLockdownRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	adminAddress := c.GetString("adminAddress")
+
+	if err := database.SetLockdown(*req.Active); err != nil {
+		serverError(c, err, "Failed to update lockdown")
+		return
+	}
+
+	db := database.GetDB().WithContext(c.Request.Context())
+	detail := fmt.Sprintf("active=%t", *req.Active)
+	if req.Reason != "" {
+		detail += fmt.Sprintf(" reason=%q", req.Reason)
+	}
+	recordAudit(db, c, AuditLockdown, adminAddress, detail)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"active":  *req.Active,
+	})
+}
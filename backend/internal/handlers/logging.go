@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestLogger 带当前请求 ID（middleware.Logger 设置）的日志记录器
+func requestLogger(c *gin.Context) *slog.Logger {
+	return slog.Default().With("request_id", c.GetString("requestID"))
+}
+
+// serverError 记录内部错误并返回 500，err 只写入日志，不返回给客户端
+func serverError(c *gin.Context, err error, message string) {
+	requestLogger(c).Error(message, "error", err, "path", c.Request.URL.Path)
+	c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: message})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+	"vaultseed-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// clock 登录 nonce 签发和过期判断使用的当前时间，测试中可替换
+var clock = time.Now
+
+// errNonceConsumed 以 nonce 为条件的更新未命中，说明 nonce 已被并发请求使用
+var errNonceConsumed = errors.New("nonce already consumed")
+
+// nonceState nonce 的时效状态
+type nonceState int
+
+const (
+	nonceValid nonceState = iota
+	nonceInGrace
+	nonceExpired
+)
+
+// checkNonceAge 根据签发时间判断 nonce 是否有效、处于宽限期或已过期
+func checkNonceAge(issuedAt time.Time, now time.Time, ttl time.Duration) nonceState {
+	age := now.Sub(issuedAt)
+	switch {
+	case age <= ttl:
+		return nonceValid
+	case age <= ttl+cfg.DecryptNonceGrace:
+		return nonceInGrace
+	default:
+		return nonceExpired
+	}
+}
+
+// nonceTTLFor 查询用户的解密 nonce 有效期，查询失败或未覆盖时使用 DECRYPT_NONCE_TTL
+func nonceTTLFor(db *gorm.DB, address string) time.Duration {
+	var user models.User
+	if err := db.Select("nonce_ttl_seconds").Where("address = ?", address).First(&user).Error; err != nil {
+		return cfg.DecryptNonceTTL
+	}
+	return user.EffectiveNonceTTL(cfg.DecryptNonceTTL)
+}
+
+// loginNonceTTL 用户的登录 nonce 有效期，按用户覆盖的 nonce 有效期同样适用于登录，未覆盖时使用 LOGIN_NONCE_TTL
+func loginNonceTTL(user *models.User) time.Duration {
+	return user.EffectiveNonceTTL(cfg.LoginNonceTTL)
+}
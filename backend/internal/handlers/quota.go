@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// contentUsage 统计用户已有的内容条数和密文字节数，回收站中的内容同样计入
+func contentUsage(db *gorm.DB, address string) (count, bytes int64, err error) {
+	var usage struct {
+		Count int64
+		Bytes int64
+	}
+	err = db.Unscoped().Model(&models.EncryptedContent{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(LENGTH(encrypted_data)), 0) AS bytes").
+		Where("user_address = ?", address).
+		Scan(&usage).Error
+	return usage.Count, usage.Bytes, err
+}
+
+// quotaExceededError 内容条数已达用户上限
+type quotaExceededError struct {
+	limit int64
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("Content quota reached: at most %d entries are allowed", e.limit)
+}
+
+// remainingContent 返回用户的内容条数上限及还能新增的条数，不限制时 remaining 为 -1
+// 与存储配额相同，回收站中的内容在彻底删除前仍占用条数，因此从回收站恢复不需要再检查配额
+func remainingContent(db *gorm.DB, user *models.User) (limit, remaining int64, err error) {
+	limit = user.EffectiveMaxContent(cfg.MaxContentPerUser)
+	if limit <= 0 {
+		return limit, -1, nil
+	}
+	var count int64
+	if err := db.Unscoped().Model(&models.EncryptedContent{}).Where("user_address = ?", user.Address).Count(&count).Error; err != nil {
+		return limit, 0, err
+	}
+	return limit, max(limit-count, 0), nil
+}
+
+// checkContentQuota 新增一条内容前检查条数上限，已达上限时返回 403
+// 计数与写入之间没有加锁，并发创建时可能略微超出上限
+func checkContentQuota(c *gin.Context, db *gorm.DB, user *models.User) bool {
+	limit, remaining, err := remainingContent(db, user)
+	if err != nil {
+		serverError(c, err, "Failed to check quota")
+		return false
+	}
+	if remaining == 0 {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: (&quotaExceededError{limit: limit}).Error()})
+		return false
+	}
+	return true
+}
+
+// quotaStatus 单项配额的使用情况，limit 为 0 表示不限制
+type quotaStatus struct {
+	Used      int64  `json:"used"`
+	Limit     int64  `json:"limit"`
+	Remaining *int64 `json:"remaining"` // 不限制时为 null
+	Fits      bool   `json:"fits"`
+}
+
+// storageExceededError 密文总字节数将超出 MAX_STORAGE_PER_USER
+type storageExceededError struct {
+	limit int64
+}
+
+func (e *storageExceededError) Error() string {
+	return fmt.Sprintf("Storage quota reached: at most %d bytes of ciphertext are allowed", e.limit)
+}
+
+// remainingStorage 返回还能写入的密文字节数，不限制时为 -1
+// 回收站中的内容在彻底删除前仍占用存储，一并计入
+func remainingStorage(db *gorm.DB, address string) (int64, error) {
+	if cfg.MaxStoragePerUser <= 0 {
+		return -1, nil
+	}
+	used, err := storageUsed(db, address)
+	if err != nil {
+		return 0, err
+	}
+	return max(cfg.MaxStoragePerUser-used, 0), nil
+}
+
+// storageUsed 用户全部内容（含回收站）的密文字节数，与导入预检的 total_bytes 口径一致
+func storageUsed(db *gorm.DB, address string) (int64, error) {
+	var used int64
+	err := db.Unscoped().Model(&models.EncryptedContent{}).
+		Select("COALESCE(SUM(LENGTH(encrypted_data)), 0)").
+		Where("user_address = ?", address).
+		Scan(&used).Error
+	return used, err
+}
+
+// checkStorageQuota 写入前检查密文总字节数，growth 为本次写入增加的字节数（更新时为新旧密文之差），超出时返回 403
+// 与条数上限相同，检查与写入之间没有加锁
+func checkStorageQuota(c *gin.Context, db *gorm.DB, address string, growth int64) bool {
+	if growth <= 0 {
+		return true
+	}
+	remaining, err := remainingStorage(db, address)
+	if err != nil {
+		serverError(c, err, "Failed to check quota")
+		return false
+	}
+	if remaining >= 0 && growth > remaining {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: (&storageExceededError{limit: cfg.MaxStoragePerUser}).Error()})
+		return false
+	}
+	return true
+}
+
+// checkQuota 判断再增加 incoming 后是否仍在配额内
+func checkQuota(used, limit, incoming int64) quotaStatus {
+	status := quotaStatus{Used: used, Limit: limit, Fits: true}
+	if limit > 0 {
+		remaining := limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		status.Remaining = &remaining
+		status.Fits = incoming <= remaining
+	}
+	return status
+}
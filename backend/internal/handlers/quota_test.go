@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// storageLimit 已有一条 seedContent（88 个字符的密文）时，再写入 64 个字符恰好用满配额
+const storageLimit = 88 + 64
+
+func withStorageLimit(c *config.Config) { c.MaxStoragePerUser = storageLimit }
+
+func createRequest(t *testing.T, dataBytes int) models.CreateContentRequest {
+	return models.CreateContentRequest{
+		ContentFields: models.ContentFields{Title: "entry", EncryptedKey: randomBase64(t, 32), IV: randomBase64(t, 12)},
+		EncryptedData: randomBase64(t, dataBytes),
+	}
+}
+
+func TestCreateEnforcesStorageQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		trashed   bool
+		dataBytes int // 48 字节编码后 64 个字符，51 字节为 68 个字符
+		status    int
+	}{
+		{"fits exactly", false, 48, http.StatusOK},
+		{"above limit", false, 51, http.StatusForbidden},
+		{"trash still counts", true, 51, http.StatusForbidden},
+		{"trash counts at limit", true, 48, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, withStorageLimit)
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			seedContent(t, alice.address, func(c *models.EncryptedContent) {
+				if tt.trashed {
+					c.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+				}
+			})
+
+			r := newRouter(alice.address)
+			r.POST("/content", CreateContentHandler)
+			w := doJSON(t, r, http.MethodPost, "/content", createRequest(t, tt.dataBytes))
+			expectStatus(t, w, tt.status)
+			if tt.status == http.StatusForbidden && !strings.Contains(w.Body.String(), "Storage quota") {
+				t.Errorf("unexpected error %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateEnforcesStorageGrowth(t *testing.T) {
+	tests := []struct {
+		name      string
+		dataBytes int
+		status    int
+	}{
+		{"shrink at full quota", 30, http.StatusOK},
+		{"same size at full quota", 64, http.StatusOK},
+		{"grow at full quota", 67, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) { c.MaxStoragePerUser = 88 })
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			content := seedContent(t, alice.address)
+
+			r := newRouter(alice.address)
+			r.PUT("/content/:id", UpdateContentHandler)
+			w := doJSON(t, r, http.MethodPut, "/content/"+itoa(content.ID), models.UpdateContentRequest{
+				EncryptedData: randomBase64(t, tt.dataBytes),
+				EncryptedKey:  randomBase64(t, 32),
+				IV:            randomBase64(t, 12),
+				Version:       content.Version,
+				Nonce:         content.Nonce,
+				Signature:     alice.sign(t, utils.GenerateUpdateMessage(content.ID, content.Nonce)),
+			})
+			expectStatus(t, w, tt.status)
+		})
+	}
+}
+
+func TestImportEnforcesStorageQuota(t *testing.T) {
+	setupTest(t, withStorageLimit)
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	seedContent(t, alice.address)
+
+	item := func(dataBytes int) models.ImportItem {
+		return models.ImportItem{Title: "imported", EncryptedData: randomBase64(t, dataBytes), EncryptedKey: randomBase64(t, 32), IV: randomBase64(t, 12)}
+	}
+	r := newRouter(alice.address)
+	r.POST("/import", ImportContentHandler)
+
+	// 两条合计超出配额，整个导入回滚
+	w := doJSON(t, r, http.MethodPost, "/import", []models.ImportItem{item(24), item(27)})
+	expectStatus(t, w, http.StatusForbidden)
+	var count int64
+	database.GetDB().Model(&models.EncryptedContent{}).Where("user_address = ?", alice.address).Count(&count)
+	if count != 1 {
+		t.Fatalf("%d rows after rejected import, want 1", count)
+	}
+
+	w = doJSON(t, r, http.MethodPost, "/import", []models.ImportItem{item(24), item(21)})
+	expectStatus(t, w, http.StatusOK)
+}
+
+func TestContentQuotaCountsTrash(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		trashed bool
+		status  int
+	}{
+		{"below limit", 2, false, http.StatusOK},
+		{"at limit", 1, false, http.StatusForbidden},
+		// 移入回收站不释放条数，否则可以先删除、再创建、最后恢复，持有两倍配额
+		{"trash at limit", 1, true, http.StatusForbidden},
+		{"trash below limit", 2, true, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) { c.MaxContentPerUser = tt.limit })
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			seedContent(t, alice.address, func(c *models.EncryptedContent) {
+				if tt.trashed {
+					c.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+				}
+			})
+
+			r := newRouter(alice.address)
+			r.POST("/content", CreateContentHandler)
+			r.POST("/import", ImportContentHandler)
+			expectStatus(t, doJSON(t, r, http.MethodPost, "/content", createRequest(t, 48)), tt.status)
+			if tt.status == http.StatusForbidden {
+				item := models.ImportItem{Title: "imported", EncryptedData: randomBase64(t, 48), EncryptedKey: randomBase64(t, 32), IV: randomBase64(t, 12)}
+				expectStatus(t, doJSON(t, r, http.MethodPost, "/import", []models.ImportItem{item}), http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestTransferEnforcesRecipientQuota(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxCount int64
+		maxBytes int64
+		status   int
+	}{
+		// bob 已有一条，alice 转入一条正常内容和一条回收站中的内容
+		{"fits exactly", 3, 3 * 88, http.StatusOK},
+		{"count above limit", 2, 0, http.StatusForbidden},
+		{"storage above limit", 0, 3*88 - 1, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) {
+				c.MaxContentPerUser = tt.maxCount
+				c.MaxStoragePerUser = tt.maxBytes
+			})
+			alice, bob := newWallet(t), newWallet(t)
+			user := createUser(t, alice.address)
+			createUser(t, bob.address)
+			seedContent(t, bob.address)
+			seedContent(t, alice.address)
+			seedContent(t, alice.address, func(c *models.EncryptedContent) {
+				c.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			})
+
+			message := utils.GenerateTransferMessage(alice.address, bob.address, user.Nonce)
+			r := newRouter(alice.address)
+			r.POST("/transfer", TransferContentHandler)
+			w := doJSON(t, r, http.MethodPost, "/transfer", models.TransferContentRequest{
+				NewAddress:       bob.address,
+				Nonce:            user.Nonce,
+				CurrentSignature: alice.sign(t, message),
+				NewSignature:     bob.sign(t, message),
+			})
+			expectStatus(t, w, tt.status)
+
+			var moved int64
+			database.GetDB().Unscoped().Model(&models.EncryptedContent{}).Where("user_address = ?", bob.address).Count(&moved)
+			want := int64(1)
+			if tt.status == http.StatusOK {
+				want = 3
+			}
+			if moved != want {
+				t.Errorf("bob owns %d rows, want %d", moved, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// 二次认证签名通过以下请求头提交
+const (
+	reauthMessageHeader   = "X-Reauth-Message"
+	reauthSignatureHeader = "X-Reauth-Signature"
+)
+
+// reauthClockSkew 允许客户端签发时间略早于服务器时间
+const reauthClockSkew = 30 * time.Second
+
+// ReauthChallengeHandler 签发带时间戳的二次认证消息，签名后在敏感操作请求头中提交
+// ?operation= 指定要执行的操作，签名不能用于其他操作
+func ReauthChallengeHandler(c *gin.Context) {
+	address := c.Query("address")
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid address"})
+		return
+	}
+	operation := c.Query("operation")
+	if !config.ReauthOperationKnown(operation) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Unknown operation"})
+		return
+	}
+
+	nonce, err := utils.GenerateNonce()
+	if err != nil {
+		serverError(c, err, "Failed to generate nonce")
+		return
+	}
+
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	c.JSON(http.StatusOK, gin.H{
+		"message":    utils.GenerateReauthMessage(address, operation, issuedAt, nonce),
+		"issued_at":  issuedAt,
+		"expires_at": issuedAt.Add(cfg.ReauthWindow),
+	})
+}
+
+// requireFreshAuth 按配置要求敏感操作附带近期签发的二次认证签名
+// 未配置该操作时直接放行；消息须为该操作签发，校验失败时返回 401，每条消息只能使用一次
+func requireFreshAuth(c *gin.Context, operation, address string) bool {
+	if !cfg.RequiresReauth(operation) {
+		return true
+	}
+
+	message := c.GetHeader(reauthMessageHeader)
+	signature := c.GetHeader(reauthSignatureHeader)
+	if message == "" || signature == "" {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Re-authentication required"})
+		return false
+	}
+
+	signed, signedOperation, issuedAt, nonce, err := utils.ParseReauthMessage(message)
+	now := time.Now()
+	if err != nil ||
+		!strings.EqualFold(signed, address) ||
+		signedOperation != operation ||
+		issuedAt.After(now.Add(reauthClockSkew)) ||
+		now.Sub(issuedAt) > cfg.ReauthWindow {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Re-authentication required"})
+		return false
+	}
+	if err := verifySignature(c.Request.Context(), "reauth", message, signature, address, 0); err != nil {
+		rejectSignature(c, err, http.StatusUnauthorized, "Re-authentication required")
+		return false
+	}
+
+	// 消费消息中的 nonce，防止窗口期内重放
+	used := models.UsedNonce{NonceHash: hashNonce("reauth:" + nonce), Address: address}
+	result := database.GetDB().WithContext(c.Request.Context()).
+		Clauses(clause.OnConflict{DoNothing: true}).Create(&used)
+	if result.Error != nil {
+		serverError(c, result.Error, "Database error")
+		return false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Re-authentication required"})
+		return false
+	}
+	return true
+}
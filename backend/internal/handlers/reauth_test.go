@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// reauthHeaders 为 operation 签发并签名的二次认证请求头
+func (w testWallet) reauthHeaders(t *testing.T, operation string, issuedAt time.Time) []string {
+	t.Helper()
+	nonce, err := utils.GenerateNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := utils.GenerateReauthMessage(w.address, operation, issuedAt, nonce)
+	return []string{reauthMessageHeader, message, reauthSignatureHeader, w.sign(t, message)}
+}
+
+func TestRequireFreshAuthBindsOperation(t *testing.T) {
+	setupTest(t, func(c *config.Config) { c.ReauthOperations = []string{"export", "transfer"} })
+	alice, mallory := newWallet(t), newWallet(t)
+
+	r := newRouter(alice.address)
+	r.POST("/op/:op", func(c *gin.Context) {
+		if requireFreshAuth(c, c.Param("op"), alice.address) {
+			c.Status(http.StatusOK)
+		}
+	})
+
+	now := time.Now().UTC().Truncate(time.Second)
+	replayed := alice.reauthHeaders(t, "export", now)
+	tests := []struct {
+		name    string
+		op      string
+		headers []string
+		status  int
+	}{
+		{"matching operation", "export", replayed, http.StatusOK},
+		{"replayed message", "export", replayed, http.StatusUnauthorized},
+		{"signed for another operation", "transfer", alice.reauthHeaders(t, "export", now), http.StatusUnauthorized},
+		{"other signer", "transfer", mallory.reauthHeaders(t, "transfer", now), http.StatusUnauthorized},
+		{"expired", "transfer", alice.reauthHeaders(t, "transfer", now.Add(-time.Hour)), http.StatusUnauthorized},
+		{"missing headers", "transfer", nil, http.StatusUnauthorized},
+		{"operation not configured", "rotate-key", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, doJSON(t, r, http.MethodPost, "/op/"+tt.op, nil, tt.headers...), tt.status)
+		})
+	}
+}
+
+func TestReauthChallengeRequiresKnownOperation(t *testing.T) {
+	setupTest(t)
+	alice := newWallet(t)
+	r := newRouter("")
+	r.GET("/reauth-challenge", ReauthChallengeHandler)
+
+	expectStatus(t, doJSON(t, r, http.MethodGet, "/reauth-challenge?address="+alice.address+"&operation=launch", nil), http.StatusBadRequest)
+
+	w := doJSON(t, r, http.MethodGet, "/reauth-challenge?address="+alice.address+"&operation=transfer", nil)
+	expectStatus(t, w, http.StatusOK)
+	address, operation, _, _, err := utils.ParseReauthMessage(decodeBody(t, w)["message"].(string))
+	if err != nil || address != alice.address || operation != "transfer" {
+		t.Fatalf("parsed %s %s %v", address, operation, err)
+	}
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errRecipientLimit = errors.New("recipient limit reached")
+
+// AddRecipientHandler 为内容添加指定接收者，接收者须为已注册公钥的用户
+// 接收者已存在时更新其加密密钥（result 为 updated），不会产生重复记录
+func AddRecipientHandler(c *gin.Context) {
+	var req models.AddRecipientRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	addRecipient(c, req.Address, req.EncryptedKey)
+}
+
+// ShareContentHandler 将内容分享给指定用户，与 AddRecipientHandler 行为相同，请求字段为 recipient_address
+func ShareContentHandler(c *gin.Context) {
+	var req models.ShareContentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	addRecipient(c, req.RecipientAddress, req.EncryptedKey)
+}
+
+// addRecipient 校验并保存接收者及为其包装的密钥
+func addRecipient(c *gin.Context, address, encryptedKey string) {
+	userAddress := c.GetString("userAddress")
+
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid recipient address"})
+		return
+	}
+	if strings.EqualFold(address, userAddress) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Cannot add yourself as a recipient"})
+		return
+	}
+	if err := utils.ValidateEncryptedKey(encryptedKey); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	// 验证内容归属
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	if content.Expired(time.Now()) {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Content expired"})
+		return
+	}
+
+	// 接收者必须已注册公钥，否则无法为其包装密钥
+	var recipient models.User
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(address)).First(&recipient).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Recipient is not registered"})
+		} else {
+			serverError(c, err, "Database error")
+		}
+		return
+	}
+	if recipient.PublicKey == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Recipient is not registered"})
+		return
+	}
+
+	entry := models.ContentRecipient{
+		ContentID:        content.ID,
+		OwnerAddress:     userAddress,
+		RecipientAddress: recipient.Address,
+		EncryptedKey:     encryptedKey,
+	}
+	// 同一接收者重复添加时更新已有记录的密钥，(content_id, recipient_address) 唯一
+	updated := false
+	var count int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing models.ContentRecipient
+		err := tx.Where("content_id = ? AND recipient_address = ?", content.ID, recipient.Address).First(&existing).Error
+		if err == nil {
+			updated = true
+			if err := tx.Model(&existing).Update("encrypted_key", encryptedKey).Error; err != nil {
+				return err
+			}
+			entry = existing
+			return tx.Model(&models.ContentRecipient{}).Where("content_id = ?", content.ID).Count(&count).Error
+		}
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err := tx.Model(&models.ContentRecipient{}).Where("content_id = ?", content.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(cfg.MaxRecipientsPerContent) {
+			return errRecipientLimit
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	switch {
+	case errors.Is(err, errRecipientLimit):
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: fmt.Sprintf("Content already has the maximum of %d recipients", cfg.MaxRecipientsPerContent)})
+		return
+	case err != nil:
+		serverError(c, err, "Failed to add recipient")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	result := "created"
+	if updated {
+		result = "updated"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"result":     result,
+		"recipient":  entry,
+		"recipients": count,
+	})
+}
+
+// ListRecipientsHandler 列出内容的全部接收者
+func ListRecipientsHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	var recipients []models.ContentRecipient
+	if err := db.Where("content_id = ?", content.ID).Order("created_at ASC").Find(&recipients).Error; err != nil {
+		serverError(c, err, "Failed to fetch recipients")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"recipients": recipients,
+		"max":        cfg.MaxRecipientsPerContent,
+	})
+}
+
+// RemoveRecipientHandler 移除内容的指定接收者
+func RemoveRecipientHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	result := db.Where("content_id = ? AND owner_address = ? AND LOWER(recipient_address) = ?",
+		c.Param("id"), userAddress, strings.ToLower(c.Param("address"))).
+		Delete(&models.ContentRecipient{})
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to remove recipient")
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Recipient not found"})
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// findAccessibleContent 查询当前用户拥有的内容，或共享给当前用户的内容
+// 共享给当前用户时同时返回接收者记录，其中的 EncryptedKey 为用接收者公钥重新包装的密钥
+func findAccessibleContent(db *gorm.DB, id interface{}, userAddress string) (*models.EncryptedContent, *models.ContentRecipient, error) {
+	var content models.EncryptedContent
+	err := db.Where("id = ? AND user_address = ?", id, userAddress).First(&content).Error
+	if err == nil {
+		return &content, nil, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, nil, err
+	}
+
+	var recipient models.ContentRecipient
+	if err := db.Where("content_id = ? AND LOWER(recipient_address) = ?", id, strings.ToLower(userAddress)).First(&recipient).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := db.First(&content, recipient.ContentID).Error; err != nil {
+		return nil, nil, err
+	}
+	return &content, &recipient, nil
+}
+
+// sharedContents 查询共享给当前用户且未被所有者归档的内容，q 非空时按明文标题筛选
+func sharedContents(db *gorm.DB, userAddress, q string) ([]models.EncryptedContent, error) {
+	query := db.Where("archived = ? AND id IN (?)", false,
+		db.Model(&models.ContentRecipient{}).Select("content_id").Where("LOWER(recipient_address) = ?", strings.ToLower(userAddress)))
+	if q != "" {
+		query = query.Where("LOWER(title) LIKE ? ESCAPE '\\'", likePattern(q))
+	}
+	var contents []models.EncryptedContent
+	err := query.Order("created_at DESC").Find(&contents).Error
+	return contents, err
+}
+
+// revokedShares 正文密钥被替换时撤销的分享数量
+type revokedShares struct {
+	Recipients int64 `json:"recipients"`
+	ShareLinks int64 `json:"share_links"`
+}
+
+// revokeSharesOnKeyChange 对称密钥被替换后，接收者和分享链接持有的旧密钥无法解密新正文：
+// 在同一事务中移除接收者、停用分享链接，由所有者重新分享；oldKey 与 newKey 相同（同一对称密钥）时不做处理
+func revokeSharesOnKeyChange(tx *gorm.DB, contentID uint, oldKey, newKey string) (revokedShares, error) {
+	var revoked revokedShares
+	if oldKey == newKey {
+		return revoked, nil
+	}
+	result := tx.Where("content_id = ?", contentID).Delete(&models.ContentRecipient{})
+	if result.Error != nil {
+		return revoked, result.Error
+	}
+	revoked.Recipients = result.RowsAffected
+	result = tx.Model(&models.ShareLink{}).Where("content_id = ? AND active = ?", contentID, true).Update("active", false)
+	if result.Error != nil {
+		return revoked, result.Error
+	}
+	revoked.ShareLinks = result.RowsAffected
+	return revoked, nil
+}
+
+// sharesRevokedWarning 撤销了分享时返回给客户端的提示
+func sharesRevokedWarning(revoked revokedShares) string {
+	return fmt.Sprintf("The content key changed: %d recipients and %d share links were revoked and must be shared again", revoked.Recipients, revoked.ShareLinks)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+)
+
+func TestReshareToSameRecipientUpdatesKey(t *testing.T) {
+	// 上限为 1：再次分享给同一接收者是更新而不是新增，不受上限影响
+	setupTest(t, func(c *config.Config) { c.MaxRecipientsPerContent = 1 })
+	db := database.GetDB()
+	alice, bob := newWallet(t), newWallet(t)
+	createUser(t, alice.address)
+	createUser(t, bob.address)
+	if err := db.Model(&models.User{}).Where("address = ?", bob.address).Update("public_key", bob.publicKey()).Error; err != nil {
+		t.Fatal(err)
+	}
+	content := seedContent(t, alice.address)
+
+	r := newRouter(alice.address)
+	r.POST("/content/:id/recipients", AddRecipientHandler)
+	share := func(address, key string) map[string]interface{} {
+		t.Helper()
+		w := doJSON(t, r, http.MethodPost, "/content/"+itoa(content.ID)+"/recipients", models.AddRecipientRequest{Address: address, EncryptedKey: key})
+		expectStatus(t, w, http.StatusOK)
+		return decodeBody(t, w)
+	}
+
+	first := share(bob.address, randomBase64(t, 32))
+	if first["result"] != "created" {
+		t.Fatalf("first share result = %v, want created", first["result"])
+	}
+	// 地址大小写不同仍视为同一接收者
+	newKey := randomBase64(t, 32)
+	second := share(strings.ToLower(bob.address), newKey)
+	if second["result"] != "updated" || second["recipients"] != float64(1) {
+		t.Fatalf("re-share result = %v, recipients = %v, want updated and 1", second["result"], second["recipients"])
+	}
+
+	var rows []models.ContentRecipient
+	if err := db.Where("content_id = ?", content.ID).Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("%d recipient rows, want 1", len(rows))
+	}
+	if rows[0].EncryptedKey != newKey || rows[0].RecipientAddress != bob.address {
+		t.Errorf("recipient row = %+v, want key %q for %s", rows[0], newKey, bob.address)
+	}
+}
+
+func TestSharedContentAccess(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice, bob, mallory := newWallet(t), newWallet(t), newWallet(t)
+	for _, w := range []testWallet{alice, bob, mallory} {
+		createUser(t, w.address)
+	}
+	if err := db.Model(&models.User{}).Where("address = ?", bob.address).Update("public_key", bob.publicKey()).Error; err != nil {
+		t.Fatal(err)
+	}
+	content := seedContent(t, alice.address)
+	path := "/content/" + itoa(content.ID)
+
+	routes := func(as string) http.Handler {
+		r := newRouter(as)
+		r.GET("/users/:address/public-key", PublicKeyHandler)
+		r.GET("/content", ListContentHandler)
+		r.POST("/content/:id/recipients", AddRecipientHandler)
+		r.POST("/content/:id/share", ShareContentHandler)
+		r.PUT("/content/:id", UpdateContentHandler)
+		r.DELETE("/content/:id", DeleteContentHandler)
+		r.GET("/content/:id/decrypt-challenge", DecryptChallengeHandler)
+		r.POST("/content/decrypt", DecryptContentHandler)
+		return r
+	}
+
+	// 取得接收者公钥；未注册公钥的地址返回 404
+	w := doJSON(t, routes(""), http.MethodGet, "/users/"+bob.address+"/public-key", nil)
+	expectStatus(t, w, http.StatusOK)
+	if decodeBody(t, w)["public_key"] != bob.publicKey() {
+		t.Fatalf("public key = %v", decodeBody(t, w)["public_key"])
+	}
+	expectStatus(t, doJSON(t, routes(""), http.MethodGet, "/users/"+mallory.address+"/public-key", nil), http.StatusNotFound)
+
+	// 只有所有者能添加接收者，接收者须已注册公钥
+	bobKey := randomBase64(t, 32)
+	expectStatus(t, doJSON(t, routes(mallory.address), http.MethodPost, path+"/recipients", models.AddRecipientRequest{Address: bob.address, EncryptedKey: bobKey}), http.StatusNotFound)
+	expectStatus(t, doJSON(t, routes(alice.address), http.MethodPost, path+"/recipients", models.AddRecipientRequest{Address: mallory.address, EncryptedKey: bobKey}), http.StatusBadRequest)
+	expectStatus(t, doJSON(t, routes(mallory.address), http.MethodPost, path+"/share", models.ShareContentRequest{RecipientAddress: bob.address, EncryptedKey: bobKey}), http.StatusNotFound)
+	expectStatus(t, doJSON(t, routes(alice.address), http.MethodPost, path+"/share", models.ShareContentRequest{RecipientAddress: bob.address, EncryptedKey: bobKey}), http.StatusOK)
+
+	// 接收者的列表中带有只读标记和所有者地址
+	w = doJSON(t, routes(bob.address), http.MethodGet, "/content", nil)
+	expectStatus(t, w, http.StatusOK)
+	items := decodeBody(t, w)["contents"].([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("recipient lists %d items, want 1", len(items))
+	}
+	item := items[0].(map[string]interface{})
+	if item["shared"] != true || item["owner_address"] != alice.address {
+		t.Errorf("shared item = %v, want shared by %s", item, alice.address)
+	}
+
+	// 接收者不能修改或删除共享内容
+	expectStatus(t, doJSON(t, routes(bob.address), http.MethodPut, path, models.UpdateContentRequest{
+		EncryptedData: randomBase64(t, 64),
+		EncryptedKey:  randomBase64(t, 32),
+		IV:            randomBase64(t, 12),
+		Version:       content.Version,
+		Nonce:         content.Nonce,
+		Signature:     bob.sign(t, utils.GenerateUpdateMessage(content.ID, content.Nonce)),
+	}), http.StatusNotFound)
+	expectStatus(t, doJSON(t, routes(bob.address), http.MethodDelete, path, models.DeleteContentRequest{
+		Nonce:     content.Nonce,
+		Signature: bob.sign(t, utils.GenerateDeleteMessage(content.ID, content.Nonce)),
+	}), http.StatusNotFound)
+
+	// 接收者解密时拿到的是为其重新包装的密钥；无关用户拿不到解密挑战
+	expectStatus(t, doJSON(t, routes(mallory.address), http.MethodGet, path+"/decrypt-challenge", nil), http.StatusNotFound)
+	w = doJSON(t, routes(bob.address), http.MethodGet, path+"/decrypt-challenge", nil)
+	expectStatus(t, w, http.StatusOK)
+	nonce := decodeBody(t, w)["nonce"].(string)
+	w = doJSON(t, routes(bob.address), http.MethodPost, "/content/decrypt", models.DecryptContentRequest{
+		ContentID: content.ID,
+		Nonce:     nonce,
+		Signature: bob.sign(t, utils.GenerateDecryptMessage(content.ID, nonce)),
+	})
+	expectStatus(t, w, http.StatusOK)
+	body := decodeBody(t, w)
+	if body["encrypted_key"] != bobKey || body["shared"] != true {
+		t.Errorf("recipient decrypt returned key %v shared %v, want the re-wrapped key", body["encrypted_key"], body["shared"])
+	}
+
+	var stored models.EncryptedContent
+	reload(t, &stored, content.ID)
+	if stored.DeletedAt.Valid || stored.EncryptedData != content.EncryptedData {
+		t.Error("recipient modified shared content")
+	}
+}
+
+func TestAddRecipientValidatesEncryptedKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   func(address, key string) interface{}
+		key    string
+		status int
+	}{
+		{"recipients valid key", "/recipients", recipientBody, "", http.StatusOK},
+		{"recipients malformed key", "/recipients", recipientBody, "not a key!", http.StatusBadRequest},
+		{"share valid key", "/share", shareBody, "", http.StatusOK},
+		{"share malformed key", "/share", shareBody, "not a key!", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			db := database.GetDB()
+			alice, bob := newWallet(t), newWallet(t)
+			createUser(t, alice.address)
+			createUser(t, bob.address)
+			db.Model(&models.User{}).Where("address = ?", bob.address).Update("public_key", bob.publicKey())
+			content := seedContent(t, alice.address)
+
+			key := tt.key
+			if key == "" {
+				key = randomBase64(t, 32)
+			}
+			r := newRouter(alice.address)
+			r.POST("/content/:id/recipients", AddRecipientHandler)
+			r.POST("/content/:id/share", ShareContentHandler)
+			expectStatus(t, doJSON(t, r, http.MethodPost, "/content/"+itoa(content.ID)+tt.path, tt.body(bob.address, key)), tt.status)
+
+			var count int64
+			db.Model(&models.ContentRecipient{}).Where("content_id = ?", content.ID).Count(&count)
+			if saved := count == 1; saved != (tt.status == http.StatusOK) {
+				t.Errorf("%d recipient rows after status %d", count, tt.status)
+			}
+		})
+	}
+}
+
+func recipientBody(address, key string) interface{} {
+	return models.AddRecipientRequest{Address: address, EncryptedKey: key}
+}
+
+func shareBody(address, key string) interface{} {
+	return models.ShareContentRequest{RecipientAddress: address, EncryptedKey: key}
+}
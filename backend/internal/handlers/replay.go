@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// hashNonce 计算 nonce 的哈希，避免明文保存
+func hashNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// markNonceUsed 记录已消费的 nonce
+func markNonceUsed(db *gorm.DB, address, nonce string) {
+	used := models.UsedNonce{NonceHash: hashNonce(nonce), Address: address}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&used).Error; err != nil {
+		log.Println("Failed to record used nonce:", err)
+	}
+}
+
+// detectNonceReuse 在 nonce 不匹配时检查是否为已使用过的 nonce
+// 是重放且 signed 确认签名确实由 address 对旧 nonce 的消息签出时，写入审计日志、累加计数，达到阈值后告警并按配置锁定账户
+// 只提交旧 nonce 而没有对应签名的请求不计数，否则任何人都能用公开的地址把账户锁住
+func detectNonceReuse(db *gorm.DB, c *gin.Context, address, nonce string, signed func() bool) {
+	var count int64
+	if err := db.Model(&models.UsedNonce{}).Where("nonce_hash = ? AND address = ?", hashNonce(nonce), address).Count(&count).Error; err != nil || count == 0 {
+		return
+	}
+	if !signed() {
+		return
+	}
+
+	recordAudit(db, c, AuditNonceReuse, address, "stale nonce submitted")
+
+	var user models.User
+	if err := db.Where("address = ?", address).First(&user).Error; err != nil {
+		return
+	}
+	user.NonceReuseCount++
+	updates := map[string]interface{}{"nonce_reuse_count": user.NonceReuseCount}
+
+	if user.NonceReuseCount >= cfg.NonceReuseAlertThreshold {
+		if cfg.NonceReuseLockout > 0 {
+			lockedUntil := time.Now().Add(cfg.NonceReuseLockout)
+			updates["locked_until"] = lockedUntil
+		}
+		webhook.SendAsync(AuditNonceReuse, map[string]interface{}{
+			"address":   address,
+			"count":     user.NonceReuseCount,
+			"client_ip": c.ClientIP(),
+			"locked":    cfg.NonceReuseLockout > 0,
+		})
+	}
+
+	if err := db.Model(&user).Updates(updates).Error; err != nil {
+		requestLogger(c).Error("Failed to update nonce reuse counter", "error", err)
+	}
+}
+
+// signedBy 返回 detectNonceReuse 使用的签名校验：message 为按旧 nonce 生成的消息
+func signedBy(c *gin.Context, message, signature, address string) func() bool {
+	return func() bool {
+		_, ok := signatureScheme(c.Request.Context(), message, signature, address, 0)
+		return ok
+	}
+}
+
+// accountLocked 判断账户是否处于锁定期
+func accountLocked(user *models.User) bool {
+	return user.LockedUntil != nil && time.Now().Before(*user.LockedUntil)
+}
+
+// lockedMessage 锁定提示
+func lockedMessage(user *models.User) string {
+	return fmt.Sprintf("Account temporarily locked until %s", user.LockedUntil.UTC().Format(time.RFC3339))
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+)
+
+func TestNonceReuseCountsOnlySignedReplays(t *testing.T) {
+	setupTest(t, func(c *config.Config) {
+		c.NonceReuseAlertThreshold = 2
+		c.NonceReuseLockout = time.Hour
+	})
+	db := database.GetDB()
+	alice, mallory := newWallet(t), newWallet(t)
+	user := createUser(t, alice.address)
+	markNonceUsed(db, alice.address, "stale-nonce")
+
+	r := newRouter("")
+	r.POST("/reset-nonce", ResetNonceHandler)
+	r.POST("/login", LoginHandler)
+	staleMessage := utils.GenerateNonceResetMessage(alice.address, "stale-nonce")
+
+	tests := []struct {
+		name      string
+		signer    testWallet
+		wantCount int
+		wantLock  bool
+	}{
+		{"forged signature", mallory, 0, false},
+		{"forged signature again", mallory, 0, false},
+		{"signed replay", alice, 1, false},
+		{"signed replay at threshold", alice, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, r, http.MethodPost, "/reset-nonce", models.ResetNonceRequest{
+				Address:   alice.address,
+				Nonce:     "stale-nonce",
+				Signature: tt.signer.sign(t, staleMessage),
+			})
+			if w.Code != http.StatusUnauthorized && w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want rejection", w.Code)
+			}
+			var got models.User
+			reload(t, &got, user.ID)
+			if got.NonceReuseCount != tt.wantCount || accountLocked(&got) != tt.wantLock {
+				t.Fatalf("count %d locked %v, want %d %v", got.NonceReuseCount, accountLocked(&got), tt.wantCount, tt.wantLock)
+			}
+		})
+	}
+
+	// 锁定期满后登录成功，计数清零
+	db.Model(&models.User{}).Where("id = ?", user.ID).Update("locked_until", nil)
+	w := doJSON(t, r, http.MethodPost, "/login", alice.loginRequest(t, user.Nonce))
+	expectStatus(t, w, http.StatusOK)
+	var got models.User
+	reload(t, &got, user.ID)
+	if got.NonceReuseCount != 0 {
+		t.Errorf("nonce_reuse_count after login = %d, want 0", got.NonceReuseCount)
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// saveRevision 在正文被替换前保存当前版本的快照，并只保留最近 MaxContentRevisions 个版本
+// 须在事务中调用
+func saveRevision(tx *gorm.DB, content *models.EncryptedContent) error {
+	if cfg.MaxContentRevisions == 0 {
+		return nil
+	}
+	revision := models.ContentRevision{
+		ContentID:     content.ID,
+		Version:       content.Version,
+		Title:         content.Title,
+		EncryptedData: content.EncryptedData,
+		EncryptedKey:  content.EncryptedKey,
+		IV:            content.IV,
+		KeyID:         content.KeyID,
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+	return tx.Where("content_id = ? AND version <= ?", content.ID, content.Version-cfg.MaxContentRevisions).
+		Delete(&models.ContentRevision{}).Error
+}
+
+// ListRevisionsHandler 列出内容保留的历史版本（仍为密文），按版本号倒序
+func ListRevisionsHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	var revisions []models.ContentRevision
+	if err := db.Where("content_id = ?", content.ID).Order("version DESC").Find(&revisions).Error; err != nil {
+		serverError(c, err, "Failed to fetch revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"current_version": content.Version,
+		"revisions":       revisions,
+		"max":             cfg.MaxContentRevisions,
+	})
+}
+
+// RestoreRevisionHandler 将历史版本恢复为当前版本
+// 恢复本身也是一次更新：当前正文先保存为历史版本，版本号加一，需要所有者对 nonce 签名
+func RestoreRevisionHandler(c *gin.Context) {
+	var req models.RestoreRevisionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid version"})
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	if content.Expired(time.Now()) {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Content expired"})
+		return
+	}
+
+	var revision models.ContentRevision
+	if err := db.Where("content_id = ? AND version = ?", content.ID, version).First(&revision).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Version not found"})
+		} else {
+			serverError(c, err, "Failed to fetch revision")
+		}
+		return
+	}
+
+	// 历史版本的密钥由已停用的公钥包装时，恢复后当前私钥无法解开
+	if revision.KeyID != nil {
+		var active int64
+		if err := db.Model(&models.UserKey{}).Where("id = ? AND address = ? AND active = ?", *revision.KeyID, userAddress, true).Count(&active).Error; err != nil {
+			serverError(c, err, "Database error")
+			return
+		}
+		if active == 0 {
+			c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Version was encrypted for a key that is no longer active"})
+			return
+		}
+	}
+
+	// 验证 nonce（防重放）
+	if content.Nonce != req.Nonce {
+		detectNonceReuse(db, c, userAddress, req.Nonce, signedBy(c, utils.GenerateRestoreRevisionMessage(content.ID, version, req.Nonce), req.Signature, content.UserAddress))
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	}
+	if checkNonceAge(content.NonceIssuedAt, time.Now(), nonceTTLFor(db, userAddress)) == nonceExpired {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Nonce expired"})
+		return
+	}
+
+	message := utils.GenerateRestoreRevisionMessage(content.ID, version, req.Nonce)
+	if err := verifySignature(c.Request.Context(), "restore-version", message, req.Signature, content.UserAddress, 0); err != nil {
+		rejectSignature(c, err, http.StatusForbidden, "Signature does not match the content owner")
+		return
+	}
+	if !checkStorageQuota(c, db, userAddress, int64(len(revision.EncryptedData)-len(content.EncryptedData))) {
+		return
+	}
+
+	newNonce, err := utils.GenerateNonce()
+	if err != nil {
+		serverError(c, err, "Failed to generate nonce")
+		return
+	}
+
+	updates := map[string]interface{}{
+		"encrypted_data":  revision.EncryptedData,
+		"encrypted_key":   revision.EncryptedKey,
+		"iv":              revision.IV,
+		"key_iv_hash":     utils.HashKeyIV(revision.EncryptedKey, revision.IV),
+		"key_id":          revision.KeyID,
+		"version":         content.Version + 1,
+		"nonce":           newNonce,
+		"nonce_issued_at": time.Now(),
+	}
+	if !content.TitleEncrypted && revision.Title != "" {
+		updates["title"] = revision.Title
+	}
+
+	var revoked revokedShares
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// 以旧 nonce 为条件更新，并发请求中只有一个能成功
+		result := tx.Model(&models.EncryptedContent{}).
+			Where("id = ? AND nonce = ?", content.ID, req.Nonce).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errNonceConsumed
+		}
+		if revoked, err = revokeSharesOnKeyChange(tx, content.ID, content.EncryptedKey, revision.EncryptedKey); err != nil {
+			return err
+		}
+		return saveRevision(tx, &content)
+	})
+	switch {
+	case err == errNonceConsumed:
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid nonce"})
+		return
+	case err != nil:
+		serverError(c, err, "Failed to restore version")
+		return
+	}
+	database.MarkWrite(userAddress)
+	markNonceUsed(db, userAddress, req.Nonce)
+	recordAudit(db, c, AuditContentRevert, userAddress, fmt.Sprintf("content_id=%d version=%d", content.ID, version))
+
+	response := gin.H{
+		"success":        true,
+		"id":             content.ID,
+		"version":        content.Version + 1,
+		"restored_from":  version,
+		"revoked_shares": revoked,
+	}
+	if revoked.Recipients+revoked.ShareLinks > 0 {
+		response["warnings"] = []string{sharesRevokedWarning(revoked)}
+	}
+	c.JSON(http.StatusOK, response)
+}
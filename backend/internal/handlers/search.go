@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSearchQueryLength 搜索词的最大字符数
+const maxSearchQueryLength = 100
+
+// likeEscaper 转义 LIKE 通配符，使搜索词按字面匹配
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern 构造不区分大小写的包含匹配模式，需配合 ESCAPE '\' 使用
+func likePattern(q string) string {
+	return "%" + likeEscaper.Replace(strings.ToLower(q)) + "%"
+}
+
+// SearchContentHandler 按标题搜索内容（?q=，不区分大小写）
+// 默认只搜索明文标题；用户开启标题托管时，服务端用托管密钥解密加密标题后一并搜索，并在结果中返回解密后的标题
+func SearchContentHandler(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" || utf8.RuneCountInString(q) > maxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "q must be 1-100 characters"})
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Select("id", "title_escrow", "escrowed_title_key").Where("address = ?", userAddress).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	// 托管密钥无法解开（例如主密钥已更换）时退回只搜索明文标题
+	var titleKey []byte
+	if user.TitleEscrow && utils.EscrowEnabled() {
+		key, err := utils.OpenEscrowedKey(user.EscrowedTitleKey)
+		if err != nil {
+			requestLogger(c).Warn("Failed to open escrowed title key", "error", err)
+		} else {
+			titleKey = key
+		}
+	}
+
+	query := db.Where("user_address = ?", userAddress)
+	if titleKey != nil {
+		query = query.Where("(title_encrypted = ? AND LOWER(title) LIKE ? ESCAPE '\\') OR title_encrypted = ?", false, likePattern(q), true)
+	} else {
+		query = query.Where("title_encrypted = ? AND LOWER(title) LIKE ? ESCAPE '\\'", false, likePattern(q))
+	}
+	var contents []models.EncryptedContent
+	if err := query.Order("created_at DESC").Find(&contents).Error; err != nil {
+		serverError(c, err, "Failed to search content")
+		return
+	}
+
+	contentIDs := make([]uint, len(contents))
+	for i, content := range contents {
+		contentIDs[i] = content.ID
+	}
+	tags, err := contentTagNames(db, contentIDs)
+	if err != nil {
+		serverError(c, err, "Failed to fetch tags")
+		return
+	}
+
+	needle := strings.ToLower(q)
+	now := time.Now()
+	results := []models.ContentResponse{}
+	undecryptable := 0
+	for _, content := range contents {
+		item := models.ContentResponse{
+			ID:             content.ID,
+			Title:          content.Title,
+			TitleEncrypted: content.TitleEncrypted,
+			EncryptedTitle: content.EncryptedTitle,
+			ContentType:    content.ContentType,
+			KeyID:          content.KeyID,
+			FolderID:       content.FolderID,
+			Archived:       content.Archived,
+			Tags:           tags[content.ID],
+			Status:         content.Status(now),
+			CreatedAt:      content.CreatedAt,
+		}
+		if content.TitleEncrypted {
+			title, err := utils.DecryptTitle(titleKey, content.EncryptedTitle)
+			if err != nil {
+				undecryptable++
+				continue
+			}
+			if !strings.Contains(strings.ToLower(title), needle) {
+				continue
+			}
+			item.Title = title
+			item.TitleDecrypted = true
+		}
+		if item.Tags == nil {
+			item.Tags = []string{}
+		}
+		results = append(results, item)
+	}
+
+	response := gin.H{
+		"success":                   true,
+		"results":                   results,
+		"encrypted_titles_searched": titleKey != nil,
+	}
+	if undecryptable > 0 {
+		response["undecryptable_titles"] = undecryptable
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/models"
+)
+
+func TestListContentTitleFilter(t *testing.T) {
+	setupTest(t)
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	base := time.Now().Add(-time.Hour)
+	for i, title := range []string{"Ledger backup", "ledger spare", "Exchange 2FA", "100% cold_wallet", "Trezor"} {
+		created := base.Add(time.Duration(i) * time.Minute)
+		seedContent(t, alice.address, func(c *models.EncryptedContent) {
+			c.Title = title
+			c.CreatedAt = created
+		})
+	}
+
+	tests := []struct {
+		name   string
+		query  url.Values
+		titles []string
+		total  int // 分页时的匹配总数，-1 表示未分页
+	}{
+		{"empty q returns everything", url.Values{}, []string{"Trezor", "100% cold_wallet", "Exchange 2FA", "ledger spare", "Ledger backup"}, -1},
+		{"case-insensitive match", url.Values{"q": {"LEDGER"}}, []string{"ledger spare", "Ledger backup"}, -1},
+		{"no match", url.Values{"q": {"mnemonic"}}, []string{}, -1},
+		{"percent is literal", url.Values{"q": {"%"}}, []string{"100% cold_wallet"}, -1},
+		{"underscore is literal", url.Values{"q": {"d_w"}}, []string{"100% cold_wallet"}, -1},
+		{"quote is bound as a parameter", url.Values{"q": {"' OR '1'='1"}}, []string{}, -1},
+		{"first page of matches", url.Values{"q": {"ledger"}, "page_size": {"1"}}, []string{"ledger spare"}, 2},
+		{"second page of matches", url.Values{"q": {"ledger"}, "page": {"2"}, "page_size": {"1"}}, []string{"Ledger backup"}, 2},
+		{"page past the end", url.Values{"q": {"ledger"}, "page": {"3"}, "page_size": {"1"}}, []string{}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRouter(alice.address)
+			r.GET("/content", ListContentHandler)
+			w := doJSON(t, r, http.MethodGet, "/content?"+tt.query.Encode(), nil)
+			expectStatus(t, w, http.StatusOK)
+
+			body := decodeBody(t, w)
+			var titles []string
+			for _, item := range body["contents"].([]interface{}) {
+				titles = append(titles, item.(map[string]interface{})["title"].(string))
+			}
+			if len(titles) != len(tt.titles) {
+				t.Fatalf("titles = %q, want %q", titles, tt.titles)
+			}
+			for i := range titles {
+				if titles[i] != tt.titles[i] {
+					t.Fatalf("titles = %q, want %q", titles, tt.titles)
+				}
+			}
+			total, paged := body["total"].(float64)
+			if tt.total < 0 && paged {
+				t.Errorf("unexpected pagination fields in %s", w.Body.String())
+			}
+			if tt.total >= 0 && int(total) != tt.total {
+				t.Errorf("total = %v, want %d", body["total"], tt.total)
+			}
+		})
+	}
+
+	// 查询参数绑定后表仍然完好
+	r := newRouter(alice.address)
+	r.GET("/content", ListContentHandler)
+	w := doJSON(t, r, http.MethodGet, "/content?q="+url.QueryEscape("'; DROP TABLE encrypted_contents; --"), nil)
+	expectStatus(t, w, http.StatusOK)
+	w = doJSON(t, r, http.MethodGet, "/content", nil)
+	expectStatus(t, w, http.StatusOK)
+	if n := len(decodeBody(t, w)["contents"].([]interface{})); n != 5 {
+		t.Errorf("listed %d items after injection attempt, want 5", n)
+	}
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// shareTokenPrefixLen 管理视图中展示的 token 前缀长度
+const shareTokenPrefixLen = 8
+
+// CreateShareLinkHandler 为内容创建分享链接
+func CreateShareLinkHandler(c *gin.Context) {
+	var req models.CreateShareLinkRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := utils.ValidateEncryptedKey(req.EncryptedKey); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	// 验证内容归属
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	if content.Expired(time.Now()) {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Content expired"})
+		return
+	}
+
+	token, err := utils.GenerateNonce()
+	if err != nil {
+		serverError(c, err, "Failed to generate token")
+		return
+	}
+
+	link := models.ShareLink{
+		Token:        token,
+		ContentID:    content.ID,
+		OwnerAddress: userAddress,
+		EncryptedKey: req.EncryptedKey,
+		Active:       true,
+	}
+	if req.ExpiresInSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInSeconds) * time.Second)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := db.Create(&link).Error; err != nil {
+		serverError(c, err, "Failed to create share link")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"token":      link.Token,
+		"expires_at": link.ExpiresAt,
+	})
+}
+
+// GetSharedContentHandler 通过分享链接获取内容（无需登录）
+func GetSharedContentHandler(c *gin.Context) {
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	link, content, ok := findAvailableShare(c, db)
+	if !ok {
+		return
+	}
+
+	// 记录访问次数
+	db.Model(link).UpdateColumn("views", gorm.Expr("views + 1"))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"title":           content.Title,
+		"title_encrypted": content.TitleEncrypted,
+		"encrypted_title": content.EncryptedTitle,
+		"encrypted_data":  content.EncryptedData,
+		"encrypted_key":   link.EncryptedKey,
+		"iv":              content.IV,
+		"created_at":      content.CreatedAt,
+	})
+}
+
+// 分享二维码尺寸（像素）
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 1024
+)
+
+// SharedContentQRHandler 生成分享链接的 PNG 二维码，不计入访问次数
+// 可用 ?size= 指定边长（像素）
+func SharedContentQRHandler(c *gin.Context) {
+	size := defaultQRSize
+	if s := c.Query("size"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < minQRSize || n > maxQRSize {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("size must be between %d and %d", minQRSize, maxQRSize)})
+			return
+		}
+		size = n
+	}
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	link, _, ok := findAvailableShare(c, db)
+	if !ok {
+		return
+	}
+
+	target, ok := shareURL(c, link.Token)
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "SHARE_URL_BASE is not configured"})
+		return
+	}
+	png, err := qrcode.Encode(target, qrcode.Medium, size)
+	if err != nil {
+		serverError(c, err, "Failed to generate QR code")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// shareURL 分享链接的完整地址，token 追加在 SHARE_URL_BASE 之后
+// 未配置时仅在非 release 模式下回退到本服务的分享接口；请求头中的 Host 可由客户端任意指定，
+// release 模式下不据此生成链接，返回 false
+func shareURL(c *gin.Context, token string) (string, bool) {
+	if cfg.ShareURLBase != "" {
+		return strings.TrimSuffix(cfg.ShareURLBase, "/") + "/" + url.PathEscape(token), true
+	}
+	if cfg.GinMode == gin.ReleaseMode {
+		return "", false
+	}
+	scheme, host := "http", c.Request.Host
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	// X-Forwarded-* 只采信来自 TRUSTED_PROXIES 的请求
+	if fromTrustedProxy(c) {
+		if c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+			host = forwarded
+		}
+	}
+	return scheme + "://" + host + "/api/content/shared/" + url.PathEscape(token), true
+}
+
+// fromTrustedProxy 请求的 TCP 对端是否属于 TRUSTED_PROXIES
+func fromTrustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if trusted := net.ParseIP(proxy); trusted != nil {
+			if trusted.Equal(ip) {
+				return true
+			}
+		} else if _, network, err := net.ParseCIDR(proxy); err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// findAvailableShare 查找 token 对应且仍可访问的分享链接及其内容
+// 不可访问时写入错误响应并返回 false
+func findAvailableShare(c *gin.Context, db *gorm.DB) (*models.ShareLink, *models.EncryptedContent, bool) {
+	var link models.ShareLink
+	if err := db.Where("token = ?", c.Param("token")).First(&link).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Share link not found"})
+		} else {
+			serverError(c, err, "Failed to fetch share link")
+		}
+		return nil, nil, false
+	}
+
+	if !link.Active || (link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt)) {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Share link is no longer available"})
+		return nil, nil, false
+	}
+
+	// 内容已删除、已转移或已过期时，分享链接随之失效
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", link.ContentID, link.OwnerAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusGone, models.ErrorResponse{Error: "Share link is no longer available"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return nil, nil, false
+	}
+	if content.Expired(time.Now()) {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Share link is no longer available"})
+		return nil, nil, false
+	}
+	if content.Scheduled(time.Now()) {
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "Not yet available"})
+		return nil, nil, false
+	}
+	return &link, &content, true
+}
+
+// ListShareLinksHandler 列出内容当前有效的分享链接
+func ListShareLinksHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	// 验证内容归属
+	var content models.EncryptedContent
+	if err := db.Where("id = ? AND user_address = ?", c.Param("id"), userAddress).First(&content).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found"})
+		} else {
+			serverError(c, err, "Failed to fetch content")
+		}
+		return
+	}
+
+	var links []models.ShareLink
+	if err := activeShareLinks(db).Where("content_id = ?", content.ID).Order("created_at DESC").Find(&links).Error; err != nil {
+		serverError(c, err, "Failed to fetch share links")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"shares":  toShareLinkResponses(links, false),
+	})
+}
+
+// RevokeShareLinkHandler 撤销分享链接
+func RevokeShareLinkHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+	revokeShareLink(c, userAddress, "token = ? AND owner_address = ?", c.Param("token"), userAddress)
+}
+
+// RevokeShareLinkByIDHandler 按列表返回的 id 撤销分享链接（列表只展示 token 前缀）
+func RevokeShareLinkByIDHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+	revokeShareLink(c, userAddress, "id = ? AND owner_address = ?", c.Param("share_id"), userAddress)
+}
+
+// AdminRevokeShareLinkHandler 管理员按 id 撤销任意用户的分享链接
+func AdminRevokeShareLinkHandler(c *gin.Context) {
+	revokeShareLink(c, c.GetString("adminAddress"), "id = ?", c.Param("id"))
+}
+
+// revokeShareLink 撤销匹配条件的分享链接并记录审计日志，actor 为执行撤销的地址
+func revokeShareLink(c *gin.Context, actor string, where string, args ...interface{}) {
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	result := db.Model(&models.ShareLink{}).Where(where, args...).Update("active", false)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to revoke share link")
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Share link not found"})
+		return
+	}
+	database.MarkWrite(actor)
+	recordAudit(db, c, AuditShareRevoke, actor, "")
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// AdminListShareLinksHandler 管理员查看所有用户的有效分享链接
+func AdminListShareLinksHandler(c *gin.Context) {
+	page, pageSize := parsePagination(c)
+
+	db := database.GetReadDB().WithContext(c.Request.Context())
+
+	query := activeShareLinks(db)
+	if owner := c.Query("owner"); owner != "" {
+		query = query.Where("owner_address = ?", owner)
+	}
+
+	var links []models.ShareLink
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&links).Error; err != nil {
+		serverError(c, err, "Failed to fetch share links")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"page":    page,
+		"shares":  toShareLinkResponses(links, true),
+	})
+}
+
+// activeShareLinks 有效（未撤销且未过期）的分享链接查询
+func activeShareLinks(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.ShareLink{}).
+		Where("active = ?", true).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
+
+// toShareLinkResponses 转换为管理视图，只暴露 token 前缀，撤销时使用 id
+func toShareLinkResponses(links []models.ShareLink, withOwner bool) []models.ShareLinkResponse {
+	response := make([]models.ShareLinkResponse, len(links))
+	for i, link := range links {
+		prefix := link.Token
+		if len(prefix) > shareTokenPrefixLen {
+			prefix = prefix[:shareTokenPrefixLen]
+		}
+		response[i] = models.ShareLinkResponse{
+			ID:          link.ID,
+			TokenPrefix: prefix,
+			ContentID:   link.ContentID,
+			ExpiresAt:   link.ExpiresAt,
+			Views:       link.Views,
+			CreatedAt:   link.CreatedAt,
+		}
+		if withOwner {
+			response[i].OwnerAddress = link.OwnerAddress
+		}
+	}
+	return response
+}
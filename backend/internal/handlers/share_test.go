@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRevokeShareLinkByID(t *testing.T) {
+	setupTest(t)
+	db := database.GetDB()
+	alice, mallory := newWallet(t), newWallet(t)
+	createUser(t, alice.address)
+	content := seedContent(t, alice.address)
+
+	newLink := func(token string) models.ShareLink {
+		link := models.ShareLink{Token: token, ContentID: content.ID, OwnerAddress: alice.address, EncryptedKey: randomBase64(t, 32), Active: true}
+		if err := db.Create(&link).Error; err != nil {
+			t.Fatal(err)
+		}
+		return link
+	}
+	first, second := newLink("first-token-0123456789"), newLink("second-token-0123456789")
+
+	routes := func(as string) http.Handler {
+		r := newRouter(as)
+		r.GET("/content/:id/shares", ListShareLinksHandler)
+		r.DELETE("/content/shares/:token", RevokeShareLinkHandler)
+		r.DELETE("/content/shares/by-id/:share_id", RevokeShareLinkByIDHandler)
+		r.DELETE("/admin/shares/:id", func(c *gin.Context) {
+			c.Set("adminAddress", as)
+			AdminRevokeShareLinkHandler(c)
+		})
+		return r
+	}
+
+	// 列表返回可用于撤销的 id
+	w := doJSON(t, routes(alice.address), http.MethodGet, "/content/"+itoa(content.ID)+"/shares", nil)
+	expectStatus(t, w, http.StatusOK)
+	shares := decodeBody(t, w)["shares"].([]interface{})
+	ids := map[float64]bool{}
+	for _, s := range shares {
+		ids[s.(map[string]interface{})["id"].(float64)] = true
+	}
+	if !ids[float64(first.ID)] || !ids[float64(second.ID)] {
+		t.Fatalf("listing ids = %v, want %d and %d", ids, first.ID, second.ID)
+	}
+
+	tests := []struct {
+		name   string
+		as     string
+		path   string
+		status int
+	}{
+		{"other user", mallory.address, "/content/shares/by-id/" + itoa(first.ID), http.StatusNotFound},
+		{"owner", alice.address, "/content/shares/by-id/" + itoa(first.ID), http.StatusOK},
+		{"unknown id", alice.address, "/content/shares/by-id/999", http.StatusNotFound},
+		{"admin", mallory.address, "/admin/shares/" + itoa(second.ID), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doJSON(t, routes(tt.as), http.MethodDelete, tt.path, nil)
+			expectStatus(t, w, tt.status)
+		})
+	}
+
+	for _, link := range []models.ShareLink{first, second} {
+		reload(t, &link, link.ID)
+		if link.Active {
+			t.Errorf("share %d still active", link.ID)
+		}
+	}
+}
+
+func TestShareAndContentExpiryAreIndependent(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name          string
+		linkExpired   bool
+		linkRevoked   bool
+		contentExpiry *time.Time
+		shareStatus   int
+		ownerStatus   int
+	}{
+		{"both live", false, false, nil, http.StatusOK, http.StatusOK},
+		{"link expired", true, false, nil, http.StatusGone, http.StatusOK},
+		{"link revoked", false, true, nil, http.StatusGone, http.StatusOK},
+		{"content expired", false, false, &past, http.StatusGone, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			content := seedContent(t, alice.address, func(c *models.EncryptedContent) { c.ExpiresAt = tt.contentExpiry })
+			link := models.ShareLink{Token: "token-" + alice.address, ContentID: content.ID, OwnerAddress: alice.address, EncryptedKey: randomBase64(t, 32), Active: true}
+			if tt.linkExpired {
+				link.ExpiresAt = &past
+			}
+			if err := database.GetDB().Create(&link).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.linkRevoked {
+				database.GetDB().Model(&link).Update("active", false)
+			}
+
+			r := newRouter(alice.address)
+			r.GET("/shared/:token", GetSharedContentHandler)
+			r.GET("/content/:id", GetContentDetailHandler)
+			expectStatus(t, doJSON(t, r, http.MethodGet, "/shared/"+link.Token, nil), tt.shareStatus)
+			// 分享链接失效不影响所有者访问自己的内容
+			expectStatus(t, doJSON(t, r, http.MethodGet, "/content/"+itoa(content.ID), nil), tt.ownerStatus)
+
+			var stored models.EncryptedContent
+			reload(t, &stored, content.ID)
+			if stored.DeletedAt.Valid {
+				t.Errorf("content was deleted")
+			}
+		})
+	}
+}
+
+func TestCreateShareLinkValidatesEncryptedKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		status int
+	}{
+		{"base64 key", "", http.StatusOK},
+		{"malformed key", "not a key!", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t)
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			content := seedContent(t, alice.address)
+
+			key := tt.key
+			if key == "" {
+				key = randomBase64(t, 32)
+			}
+			r := newRouter(alice.address)
+			r.POST("/content/:id/shares", CreateShareLinkHandler)
+			expectStatus(t, doJSON(t, r, http.MethodPost, "/content/"+itoa(content.ID)+"/shares", models.CreateShareLinkRequest{EncryptedKey: key}), tt.status)
+
+			var count int64
+			database.GetDB().Model(&models.ShareLink{}).Where("content_id = ?", content.ID).Count(&count)
+			if created := count == 1; created != (tt.status == http.StatusOK) {
+				t.Errorf("%d share links after status %d", count, tt.status)
+			}
+		})
+	}
+}
+
+func TestShareURLIgnoresClientSuppliedHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		base       string
+		mode       string
+		remoteAddr string
+		want       string
+		ok         bool
+	}{
+		{"configured base", "https://vault.example.com/s/", gin.ReleaseMode, "203.0.113.9:4000", "https://vault.example.com/s/tok", true},
+		{"release without base", "", gin.ReleaseMode, "203.0.113.9:4000", "", false},
+		{"direct client forwarded headers ignored", "", gin.TestMode, "203.0.113.9:4000", "http://api.local/api/content/shared/tok", true},
+		{"trusted proxy forwarded headers", "", gin.TestMode, "10.0.0.2:4000", "https://vault.example.com/api/content/shared/tok", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, func(c *config.Config) {
+				c.ShareURLBase = tt.base
+				c.GinMode = tt.mode
+				c.TrustedProxies = []string{"10.0.0.0/8"}
+			})
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/content/shared/tok/qr", nil)
+			c.Request.Host = "api.local"
+			c.Request.RemoteAddr = tt.remoteAddr
+			c.Request.Header.Set("X-Forwarded-Proto", "https")
+			c.Request.Header.Set("X-Forwarded-Host", "vault.example.com")
+
+			got, ok := shareURL(c, "tok")
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("shareURL = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/ethrpc"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/gin-gonic/gin"
+)
+
+// errSignatureInvalid 签名无法通过任何方案校验
+var errSignatureInvalid = errors.New("invalid signature")
+
+// schemeNotAcceptedError 签名本身有效，但所用方案未被该操作接受
+type schemeNotAcceptedError struct {
+	operation string
+	scheme    string
+}
+
+func (e *schemeNotAcceptedError) Error() string {
+	return fmt.Sprintf("Signature scheme %s is not accepted for %s", e.scheme, e.operation)
+}
+
+// verifySignature 校验 personal_sign 签名，并检查所用方案是否被 operation 接受（SIGNATURE_SCHEMES）
+// ECDSA 恢复失败且开启 ALLOW_CONTRACT_SIGNATURES 时，按 EIP-1271 询问地址上的合约钱包；未配置 RPC 时跳过
+func verifySignature(ctx context.Context, operation, message, signature, address string, chainID uint64) error {
+	scheme, ok := signatureScheme(ctx, message, signature, address, chainID)
+	if !ok {
+		return errSignatureInvalid
+	}
+	if !cfg.AcceptsSignatureScheme(operation, scheme) {
+		return &schemeNotAcceptedError{operation: operation, scheme: scheme}
+	}
+	return nil
+}
+
+// verifyTypedSignature 校验对 EIP-712 摘要的签名，规则同 verifySignature；合约钱包按 EIP-1271 校验同一摘要
+func verifyTypedSignature(ctx context.Context, operation string, digest [32]byte, signature, address string) error {
+	scheme := config.SchemeEIP712
+	if !utils.VerifyTypedDataSignature(digest, signature, address) {
+		if !cfg.AllowContractSignatures || !ethrpc.Enabled() {
+			return errSignatureInvalid
+		}
+		sig, err := hexutil.Decode(signature)
+		if err != nil {
+			return errSignatureInvalid
+		}
+		ok, err := ethrpc.IsValidSignature(ctx, address, digest, sig)
+		if err != nil {
+			log.Println("EIP-1271 signature check failed:", err)
+		}
+		if !ok {
+			return errSignatureInvalid
+		}
+		scheme = config.SchemeEIP1271
+	}
+	if !cfg.AcceptsSignatureScheme(operation, scheme) {
+		return &schemeNotAcceptedError{operation: operation, scheme: scheme}
+	}
+	return nil
+}
+
+// signatureScheme 返回签名通过校验所用的方案
+func signatureScheme(ctx context.Context, message, signature, address string, chainID uint64) (string, bool) {
+	if utils.VerifyEthereumSignatureWithChainID(message, signature, address, chainID) {
+		return config.SchemePersonalSign, true
+	}
+	if !cfg.AllowContractSignatures || !ethrpc.Enabled() {
+		return "", false
+	}
+
+	hash, sig, err := utils.PersonalMessageHash(message, signature)
+	if err != nil {
+		return "", false
+	}
+	ok, err := ethrpc.IsValidSignature(ctx, address, hash, sig)
+	if err != nil {
+		log.Println("EIP-1271 signature check failed:", err)
+		return "", false
+	}
+	return config.SchemeEIP1271, ok
+}
+
+// rejectSignature 返回签名校验失败的响应：方案不被接受时返回 403 及原因，否则按调用方给定的状态码和消息返回
+func rejectSignature(c *gin.Context, err error, status int, message string) {
+	var schemeErr *schemeNotAcceptedError
+	if errors.As(err, &schemeErr) {
+		requestLogger(c).Warn("Signature scheme not accepted", "operation", schemeErr.operation, "scheme", schemeErr.scheme)
+		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: schemeErr.Error()})
+		return
+	}
+	requestLogger(c).Warn("Signature rejected", "error", err, "path", c.Request.URL.Path)
+	c.JSON(status, models.ErrorResponse{Error: message})
+}
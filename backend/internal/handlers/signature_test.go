@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/ethrpc"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+)
+
+func TestCanceledRequestAbortsRPCVerification(t *testing.T) {
+	setupTest(t, func(c *config.Config) { c.AllowContractSignatures = true })
+
+	// RPC 节点在测试结束前一直不响应
+	release := make(chan struct{})
+	rpc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer rpc.Close()
+	defer close(release)
+	ethrpc.Configure(rpc.URL)
+	defer ethrpc.Configure("")
+
+	alice, mallory := newWallet(t), newWallet(t)
+	user := createUser(t, alice.address)
+
+	// ECDSA 校验失败后转入 EIP-1271 查询
+	body := models.ResetNonceRequest{
+		Address:   alice.address,
+		Nonce:     user.Nonce,
+		Signature: mallory.sign(t, utils.GenerateNonceResetMessage(alice.address, user.Nonce)),
+	}
+	r := newRouter("")
+	r.POST("/reset-nonce", ResetNonceHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	done := make(chan int, 1)
+	start := time.Now()
+	go func() {
+		w := doJSON(t, requestWithContext(r, ctx), http.MethodPost, "/reset-nonce", body)
+		done <- w.Code
+	}()
+
+	select {
+	case code := <-done:
+		if code == http.StatusOK {
+			t.Fatalf("forged signature accepted")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("handler returned after %v", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler kept waiting on the RPC call after the request was canceled")
+	}
+}
+
+// requestWithContext 让经过 h 的请求使用 ctx
+func requestWithContext(h http.Handler, ctx context.Context) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+	"vaultseed-backend/internal/utils"
+)
+
+// checkSIWELogin 按业务规则校验已解析的 SIWE 登录消息，通过时返回空字符串，否则返回错误信息
+// domain 必须是本服务配置的 SIWE_DOMAIN（防止其他站点诱导签名后转发过来），nonce 必须是当前签发的登录 nonce
+func checkSIWELogin(m *utils.SIWEMessage, address, nonce string, chainID uint64, now time.Time) string {
+	if m.Domain != cfg.SIWEDomain {
+		return "SIWE domain mismatch"
+	}
+	if !strings.EqualFold(m.Address, address) {
+		return "SIWE address mismatch"
+	}
+	if m.Nonce != nonce {
+		return "Message does not match the issued nonce"
+	}
+	if chainID != 0 && chainID != m.ChainID {
+		return "SIWE chain ID mismatch"
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return "SIWE message expired"
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return "SIWE message not yet valid"
+	}
+	return ""
+}
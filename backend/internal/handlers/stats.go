@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/siem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// processStart 进程启动时间，用于计算运行时长
+var processStart = time.Now()
+
+// AdminStatsHandler 返回系统汇总统计，供不使用 Prometheus 的运营面板拉取
+// 不含回收站中的内容；24 小时内的登录数来自审计日志
+func AdminStatsHandler(c *gin.Context) {
+	db := database.GetReadDB().WithContext(c.Request.Context())
+	since := time.Now().Add(-24 * time.Hour)
+
+	var users int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		serverError(c, err, "Failed to collect stats")
+		return
+	}
+
+	var content struct {
+		Count   int64
+		Bytes   int64
+		Created int64
+	}
+	if err := db.Model(&models.EncryptedContent{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(LENGTH(encrypted_data)), 0) AS bytes, "+
+			"COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0) AS created", since).
+		Scan(&content).Error; err != nil {
+		serverError(c, err, "Failed to collect stats")
+		return
+	}
+
+	var logins int64
+	if err := db.Model(&models.AuditLog{}).Where("event = ? AND created_at >= ?", AuditLogin, since).Count(&logins).Error; err != nil {
+		serverError(c, err, "Failed to collect stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":             true,
+		"total_users":         users,
+		"total_content":       content.Count,
+		"total_bytes":         content.Bytes,
+		"content_created_24h": content.Created,
+		"logins_24h":          logins,
+		"db_backend":          database.Backend(),
+		"uptime_seconds":      int64(time.Since(processStart).Seconds()),
+		"siem_dropped_events": siem.Dropped(),
+	})
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ContentSummaryHandler 返回用户内容的汇总信息：总数、各类型数量以及弱密码数量
+// 强度评分由客户端计算，服务端只做统计
+func ContentSummaryHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var rows []struct {
+		ContentType string
+		Count       int64
+	}
+	if err := db.Model(&models.EncryptedContent{}).Select("content_type, COUNT(*) AS count").
+		Where("user_address = ?", userAddress).
+		Group("content_type").Scan(&rows).Error; err != nil {
+		serverError(c, err, "Failed to fetch summary")
+		return
+	}
+	var total int64
+	byType := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		contentType := r.ContentType
+		if contentType == "" {
+			contentType = defaultContentType
+		}
+		byType[contentType] += r.Count
+		total += r.Count
+	}
+
+	var weak, unscored int64
+	passwords := db.Model(&models.EncryptedContent{}).
+		Where("user_address = ? AND content_type = ?", userAddress, passwordContentType)
+	if err := passwords.Session(&gorm.Session{}).Where("strength <= ?", weakPasswordStrength).Count(&weak).Error; err != nil {
+		serverError(c, err, "Failed to fetch summary")
+		return
+	}
+	if err := passwords.Session(&gorm.Session{}).Where("strength IS NULL").Count(&unscored).Error; err != nil {
+		serverError(c, err, "Failed to fetch summary")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"summary": gin.H{
+			"total":                total,
+			"by_type":              byType,
+			"weak_passwords":       weak,
+			"unscored_passwords":   unscored,
+			"weak_strength_cutoff": weakPasswordStrength,
+		},
+	})
+}
+
+// CountContentHandler 只返回用户内容的条数（不含回收站），不加载任何记录
+func CountContentHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetReadDBFor(userAddress).WithContext(c.Request.Context())
+
+	var count int64
+	if err := db.Model(&models.EncryptedContent{}).Where("user_address = ?", userAddress).Count(&count).Error; err != nil {
+		serverError(c, err, "Failed to count content")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"count":   count,
+	})
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// normalizeTags 去除首尾空白并去重，保留原有顺序
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+// ensureTags 查找用户的同名标签，不存在时创建
+func ensureTags(tx *gorm.DB, address string, names []string) ([]models.Tag, error) {
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tag := models.Tag{Address: address, Name: name}
+		if err := tx.Where("address = ? AND name = ?", address, name).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// tagContent 为单条内容关联标签（标签不存在时创建），须在事务中调用
+func tagContent(tx *gorm.DB, address string, contentID uint, names []string) error {
+	tags, err := ensureTags(tx, address, names)
+	if err != nil {
+		return err
+	}
+	rows := make([]models.ContentTag, len(tags))
+	for i, tag := range tags {
+		rows[i] = models.ContentTag{ContentID: contentID, TagID: tag.ID}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// contentTagNames 查询多条内容各自的标签名称，按名称排序
+func contentTagNames(db *gorm.DB, contentIDs []uint) (map[uint][]string, error) {
+	byContent := make(map[uint][]string, len(contentIDs))
+	if len(contentIDs) == 0 {
+		return byContent, nil
+	}
+	var rows []struct {
+		ContentID uint
+		Name      string
+	}
+	if err := db.Model(&models.ContentTag{}).
+		Select("content_tags.content_id, tags.name").
+		Joins("JOIN tags ON tags.id = content_tags.tag_id").
+		Where("content_tags.content_id IN ?", contentIDs).
+		Order("tags.name").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		byContent[row.ContentID] = append(byContent[row.ContentID], row.Name)
+	}
+	return byContent, nil
+}
+
+// BulkTagHandler 批量为内容添加（mode=add，默认）或移除（mode=remove）标签
+// 在一个事务中处理全部条目；不存在的内容以及添加后会超过单条标签上限的内容被跳过
+func BulkTagHandler(c *gin.Context) {
+	var req models.BulkTagRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = "add"
+	}
+	names := normalizeTags(req.Tags)
+	if len(names) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "At least one tag is required"})
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	results := make([]batchItemResult, len(req.ContentIDs))
+	updated := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var owned []uint
+		if err := tx.Model(&models.EncryptedContent{}).
+			Where("user_address = ? AND id IN ?", userAddress, req.ContentIDs).
+			Pluck("id", &owned).Error; err != nil {
+			return err
+		}
+		ownedSet := make(map[uint]bool, len(owned))
+		for _, id := range owned {
+			ownedSet[id] = true
+		}
+
+		var tags []models.Tag
+		var err error
+		if mode == "add" {
+			tags, err = ensureTags(tx, userAddress, names)
+		} else {
+			err = tx.Where("address = ? AND name IN ?", userAddress, names).Find(&tags).Error
+		}
+		if err != nil {
+			return err
+		}
+		tagIDs := make([]uint, len(tags))
+		for i, tag := range tags {
+			tagIDs[i] = tag.ID
+		}
+
+		// 各内容已有的标签
+		var existing []models.ContentTag
+		if len(owned) > 0 {
+			if err := tx.Where("content_id IN ?", owned).Find(&existing).Error; err != nil {
+				return err
+			}
+		}
+		current := make(map[uint]map[uint]bool, len(owned))
+		for _, ct := range existing {
+			if current[ct.ContentID] == nil {
+				current[ct.ContentID] = make(map[uint]bool)
+			}
+			current[ct.ContentID][ct.TagID] = true
+		}
+
+		done := make(map[uint]bool, len(req.ContentIDs))
+		for i, id := range req.ContentIDs {
+			id := id
+			results[i] = batchItemResult{Index: i, ID: &id, Status: batchStatusOK}
+			if !ownedSet[id] {
+				results[i].Status = batchStatusSkipped
+				results[i].Error = "content not found"
+				continue
+			}
+			if done[id] {
+				continue
+			}
+			done[id] = true
+
+			if mode == "remove" {
+				if len(tagIDs) == 0 {
+					continue
+				}
+				if err := tx.Where("content_id = ? AND tag_id IN ?", id, tagIDs).Delete(&models.ContentTag{}).Error; err != nil {
+					return err
+				}
+				updated++
+				continue
+			}
+
+			var rows []models.ContentTag
+			for _, tagID := range tagIDs {
+				if !current[id][tagID] {
+					rows = append(rows, models.ContentTag{ContentID: id, TagID: tagID})
+				}
+			}
+			if len(current[id])+len(rows) > cfg.MaxTagsPerContent {
+				results[i].Status = batchStatusSkipped
+				results[i].Error = "tag limit exceeded"
+				continue
+			}
+			if len(rows) > 0 {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+					return err
+				}
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		serverError(c, err, "Failed to update tags")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	skipped := []batchItemResult{}
+	for _, r := range results {
+		if r.Status == batchStatusSkipped {
+			skipped = append(skipped, r)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"mode":    mode,
+		"tags":    names,
+		"updated": updated,
+		"skipped": skipped,
+	})
+}
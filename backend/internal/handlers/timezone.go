@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseLocation 解析 ?tz= 参数（IANA 名称），未提供时使用 UTC
+// 时区无效时写入 400 响应并返回 false
+func responseLocation(c *gin.Context) (*time.Location, bool) {
+	name := c.Query("tz")
+	if name == "" {
+		return time.UTC, true
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid timezone"})
+		return nil, false
+	}
+	return loc, true
+}
+
+// inLocation 返回转换到 loc 的时间副本，nil 保持为 nil
+func inLocation(t *time.Time, loc *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+	converted := t.In(loc)
+	return &converted
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trashItem 回收站列表中的条目，不含密文
+type trashItem struct {
+	ID             uint      `json:"id"`
+	Title          string    `json:"title"`
+	TitleEncrypted bool      `json:"title_encrypted"`
+	EncryptedTitle string    `json:"encrypted_title,omitempty"`
+	ContentType    string    `json:"content_type"`
+	FolderID       *uint     `json:"folder_id"`
+	Nonce          string    `json:"nonce"` // 永久删除时需签名的 nonce
+	CreatedAt      time.Time `json:"created_at"`
+	DeletedAt      time.Time `json:"deleted_at"`
+}
+
+// ListTrashHandler 列出当前用户回收站中的内容，最近删除的在前
+// 回收站读主库，返回的 nonce 用于签名永久删除，过期的 nonce 会被刷新
+func ListTrashHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var contents []models.EncryptedContent
+	if err := db.Unscoped().Where("user_address = ? AND deleted_at IS NOT NULL", userAddress).
+		Order("deleted_at DESC").Find(&contents).Error; err != nil {
+		serverError(c, err, "Failed to fetch trash")
+		return
+	}
+
+	// 列表返回的 nonce 用于永久删除，已过期的先刷新，否则长期留在回收站的内容无法永久删除
+	ttl := nonceTTLFor(db, userAddress)
+	items := make([]trashItem, len(contents))
+	for i := range contents {
+		content := &contents[i]
+		if err := refreshContentNonce(db, content, ttl); err != nil {
+			serverError(c, err, "Failed to refresh nonce")
+			return
+		}
+		items[i] = trashItem{
+			ID:             content.ID,
+			Title:          content.Title,
+			TitleEncrypted: content.TitleEncrypted,
+			EncryptedTitle: content.EncryptedTitle,
+			ContentType:    content.ContentType,
+			FolderID:       content.FolderID,
+			Nonce:          content.Nonce,
+			CreatedAt:      content.CreatedAt,
+			DeletedAt:      content.DeletedAt.Time,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"items":   items,
+		"total":   len(items),
+	})
+}
+
+// RestoreContentHandler 将回收站中的内容恢复到原位置
+// 回收站中的内容仍计入条数和存储配额，恢复不会使用量增加，因此不再检查配额
+func RestoreContentHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	result := db.Unscoped().Model(&models.EncryptedContent{}).
+		Where("id = ? AND user_address = ? AND deleted_at IS NOT NULL", c.Param("id"), userAddress).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		serverError(c, result.Error, "Failed to restore content")
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Content not found in trash"})
+		return
+	}
+	database.MarkWrite(userAddress)
+	recordAudit(db, c, AuditContentRestore, userAddress, fmt.Sprintf("content_id=%s", c.Param("id")))
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
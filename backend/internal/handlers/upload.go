@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sha256Hex 计算字符串的 SHA-256（小写十六进制）
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadUpload 查询当前用户未过期的分块上传，失败时已写入响应
+func loadUpload(c *gin.Context, db *gorm.DB, userAddress string) (*models.Upload, bool) {
+	var upload models.Upload
+	if err := db.Where("id = ? AND user_address = ?", c.Param("upload_id"), userAddress).First(&upload).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Upload not found"})
+		} else {
+			serverError(c, err, "Failed to fetch upload")
+		}
+		return nil, false
+	}
+	if time.Since(upload.CreatedAt) > cfg.UploadTTL {
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Upload expired"})
+		return nil, false
+	}
+	return &upload, true
+}
+
+// errUploadGone 分块上传已被并发请求完成或放弃
+var errUploadGone = errors.New("upload no longer exists")
+
+// deleteUpload 删除分块上传及其全部分块；上传已不存在时返回 errUploadGone，完成上传的事务随之回滚
+func deleteUpload(tx *gorm.DB, id uint) error {
+	if err := tx.Where("upload_id = ?", id).Delete(&models.UploadChunk{}).Error; err != nil {
+		return err
+	}
+	result := tx.Delete(&models.Upload{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errUploadGone
+	}
+	return nil
+}
+
+// maxUploadEncodedSize MAX_UPLOAD_SIZE 字节的密文按 base64 编码后的长度，用于限制暂存的分块总量
+func maxUploadEncodedSize() int64 {
+	return (cfg.MaxUploadSize + 2) / 3 * 4
+}
+
+// StartUploadHandler 开始分块上传，声明分块总数和完整密文的 SHA-256
+// 分块总数乘以单块上限不得超过 MAX_UPLOAD_SIZE 对应的编码长度，每个用户同时进行的上传数受 MAX_CONCURRENT_UPLOADS 限制
+func StartUploadHandler(c *gin.Context) {
+	var req models.StartUploadRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.TotalChunks > cfg.MaxUploadChunks {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("At most %d chunks are allowed per upload", cfg.MaxUploadChunks)})
+		return
+	}
+	// 未完成的分块在过期前一直占用存储，开始时就按最坏情况限制总量
+	if int64(req.TotalChunks)*cfg.MaxUploadChunkSize > maxUploadEncodedSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{Error: fmt.Sprintf("total_chunks × %d exceeds the maximum upload size of %d bytes", cfg.MaxUploadChunkSize, cfg.MaxUploadSize)})
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var active int64
+	if err := db.Model(&models.Upload{}).Where("user_address = ? AND created_at > ?", userAddress, time.Now().Add(-cfg.UploadTTL)).Count(&active).Error; err != nil {
+		serverError(c, err, "Failed to count uploads")
+		return
+	}
+	if active >= int64(cfg.MaxConcurrentUploads) {
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{Error: fmt.Sprintf("At most %d uploads may be in progress at once; complete or abort one first", cfg.MaxConcurrentUploads)})
+		return
+	}
+
+	upload := models.Upload{
+		UserAddress: userAddress,
+		TotalChunks: req.TotalChunks,
+		SHA256:      strings.ToLower(req.SHA256),
+	}
+	if err := db.Create(&upload).Error; err != nil {
+		serverError(c, err, "Failed to start upload")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"upload_id":    upload.ID,
+		"total_chunks": upload.TotalChunks,
+		"expires_at":   upload.CreatedAt.Add(cfg.UploadTTL),
+	})
+}
+
+// UploadChunkHandler 接收一个分块并校验其 SHA-256；同一序号重复上传时覆盖，便于断点续传
+func UploadChunkHandler(c *gin.Context) {
+	var req models.UploadChunkRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	upload, ok := loadUpload(c, db, userAddress)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 || index >= upload.TotalChunks {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid chunk index"})
+		return
+	}
+	if int64(len(req.Data)) > cfg.MaxUploadChunkSize {
+		c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{Error: fmt.Sprintf("Chunk data exceeds the maximum of %d bytes", cfg.MaxUploadChunkSize)})
+		return
+	}
+	if sha256Hex(req.Data) != strings.ToLower(req.SHA256) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Chunk hash mismatch"})
+		return
+	}
+
+	chunk := models.UploadChunk{
+		UploadID:   upload.ID,
+		ChunkIndex: index,
+		Data:       req.Data,
+		SHA256:     strings.ToLower(req.SHA256),
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "upload_id"}, {Name: "chunk_index"}},
+		DoUpdates: clause.AssignmentColumns([]string{"data", "sha256", "created_at"}),
+	}).Create(&chunk).Error; err != nil {
+		serverError(c, err, "Failed to save chunk")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"index":   index,
+	})
+}
+
+// UploadStatusHandler 列出已接收和尚缺的分块序号，客户端据此续传
+func UploadStatusHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	upload, ok := loadUpload(c, db, userAddress)
+	if !ok {
+		return
+	}
+
+	var received []int
+	if err := db.Model(&models.UploadChunk{}).Where("upload_id = ?", upload.ID).
+		Order("chunk_index").Pluck("chunk_index", &received).Error; err != nil {
+		serverError(c, err, "Failed to fetch chunks")
+		return
+	}
+	missing := make([]int, 0, upload.TotalChunks-len(received))
+	next := 0
+	for i := 0; i < upload.TotalChunks; i++ {
+		if next < len(received) && received[next] == i {
+			next++
+			continue
+		}
+		missing = append(missing, i)
+	}
+	if received == nil {
+		received = []int{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"upload_id":    upload.ID,
+		"total_chunks": upload.TotalChunks,
+		"received":     received,
+		"missing":      missing,
+		"complete":     len(missing) == 0,
+		"expires_at":   upload.CreatedAt.Add(cfg.UploadTTL),
+	})
+}
+
+// CompleteUploadHandler 按序拼接全部分块，校验整体 SHA-256 后按创建接口的规则保存内容
+// 拼接后的密文按 MAX_UPLOAD_SIZE 而不是 MAX_ENCRYPTED_DATA_SIZE 限制大小
+func CompleteUploadHandler(c *gin.Context) {
+	var req models.CompleteUploadRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	upload, ok := loadUpload(c, db, userAddress)
+	if !ok {
+		return
+	}
+
+	var chunks []models.UploadChunk
+	if err := db.Where("upload_id = ?", upload.ID).Order("chunk_index").Find(&chunks).Error; err != nil {
+		serverError(c, err, "Failed to fetch chunks")
+		return
+	}
+	if len(chunks) != upload.TotalChunks {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Upload is incomplete",
+			"received": len(chunks),
+			"total":    upload.TotalChunks,
+		})
+		return
+	}
+
+	var data strings.Builder
+	for _, chunk := range chunks {
+		data.WriteString(chunk.Data)
+	}
+	encryptedData := data.String()
+	if sha256Hex(encryptedData) != upload.SHA256 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Upload hash mismatch"})
+		return
+	}
+
+	create := models.CreateContentRequest{ContentFields: req.ContentFields, EncryptedData: encryptedData}
+	createContent(c, &create, cfg.MaxUploadSize, func(tx *gorm.DB) error {
+		return deleteUpload(tx, upload.ID)
+	})
+}
+
+// AbortUploadHandler 放弃分块上传并删除已接收的分块
+func AbortUploadHandler(c *gin.Context) {
+	userAddress := c.GetString("userAddress")
+
+	db := database.GetDB().WithContext(c.Request.Context())
+
+	var upload models.Upload
+	if err := db.Where("id = ? AND user_address = ?", c.Param("upload_id"), userAddress).First(&upload).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Upload not found"})
+		} else {
+			serverError(c, err, "Failed to fetch upload")
+		}
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error { return deleteUpload(tx, upload.ID) })
+	switch {
+	case err == errUploadGone:
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Upload not found"})
+		return
+	case err != nil:
+		serverError(c, err, "Failed to delete upload")
+		return
+	}
+	database.MarkWrite(userAddress)
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"vaultseed-backend/internal/config"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// withUploadLimits 直接提交上限 64 字节，分块上传上限 256 字节（base64 编码后 344 字节），每块最多 86 字节
+func withUploadLimits(c *config.Config) {
+	c.MaxEncryptedDataSize = 64
+	c.MaxUploadSize = 256
+	c.MaxUploadChunkSize = 86
+	c.MaxConcurrentUploads = 2
+}
+
+func uploadRoutes(as string) *gin.Engine {
+	r := newRouter(as)
+	r.POST("/uploads", StartUploadHandler)
+	r.PUT("/uploads/:upload_id/chunks/:index", UploadChunkHandler)
+	r.POST("/uploads/:upload_id/complete", CompleteUploadHandler)
+	r.DELETE("/uploads/:upload_id", AbortUploadHandler)
+	return r
+}
+
+// startUpload 开始上传，成功时返回 upload_id
+func startUpload(t *testing.T, r http.Handler, totalChunks int, data string, status int) string {
+	t.Helper()
+	w := doJSON(t, r, http.MethodPost, "/uploads", models.StartUploadRequest{TotalChunks: totalChunks, SHA256: sha256Hex(data)})
+	expectStatus(t, w, status)
+	if status != http.StatusOK {
+		return ""
+	}
+	return itoa(uint(decodeBody(t, w)["upload_id"].(float64)))
+}
+
+func TestUploadSizeLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		dataBytes int
+		status    int
+	}{
+		{"above the direct limit", 200, http.StatusOK},
+		{"at the upload limit", 256, http.StatusOK},
+		{"above the upload limit", 258, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTest(t, withUploadLimits)
+			alice := newWallet(t)
+			createUser(t, alice.address)
+			r := uploadRoutes(alice.address)
+
+			data := randomBase64(t, tt.dataBytes)
+			var chunks []string
+			for rest := data; rest != ""; {
+				n := min(len(rest), 86)
+				chunks = append(chunks, rest[:n])
+				rest = rest[n:]
+			}
+			id := startUpload(t, r, len(chunks), data, http.StatusOK)
+			for i, chunk := range chunks {
+				w := doJSON(t, r, http.MethodPut, "/uploads/"+id+"/chunks/"+itoa(uint(i)), models.UploadChunkRequest{Data: chunk, SHA256: sha256Hex(chunk)})
+				expectStatus(t, w, http.StatusOK)
+			}
+			w := doJSON(t, r, http.MethodPost, "/uploads/"+id+"/complete", models.CompleteUploadRequest{ContentFields: models.ContentFields{
+				Title:        "large entry",
+				EncryptedKey: randomBase64(t, 32),
+				IV:           randomBase64(t, 12),
+			}})
+			expectStatus(t, w, tt.status)
+		})
+	}
+}
+
+func TestStartUploadLimits(t *testing.T) {
+	setupTest(t, withUploadLimits)
+	alice := newWallet(t)
+	createUser(t, alice.address)
+	r := uploadRoutes(alice.address)
+	data := randomBase64(t, 16)
+
+	// 4 × 86 = 344 正好是上限的编码长度，5 块可能暂存超过上限的数据
+	startUpload(t, r, 5, data, http.StatusRequestEntityTooLarge)
+	first := startUpload(t, r, 4, data, http.StatusOK)
+
+	// 单个分块超过 MAX_UPLOAD_CHUNK_SIZE
+	big := randomBase64(t, 66)
+	w := doJSON(t, r, http.MethodPut, "/uploads/"+first+"/chunks/0", models.UploadChunkRequest{Data: big, SHA256: sha256Hex(big)})
+	expectStatus(t, w, http.StatusRequestEntityTooLarge)
+
+	// 同时进行的上传数受限，放弃一个后可以再开始
+	startUpload(t, r, 1, data, http.StatusOK)
+	startUpload(t, r, 1, data, http.StatusTooManyRequests)
+	expectStatus(t, doJSON(t, r, http.MethodDelete, "/uploads/"+first, nil), http.StatusOK)
+	startUpload(t, r, 1, data, http.StatusOK)
+
+	// 限制按用户计算
+	bob := newWallet(t)
+	createUser(t, bob.address)
+	startUpload(t, uploadRoutes(bob.address), 1, data, http.StatusOK)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PublicKeyHandler 返回地址当前注册的公钥，供其他用户为其包装密钥（共享、多设备）
+// 无需登录；从未登录与未注册公钥都返回 404，不暴露地址是否存在
+func PublicKeyHandler(c *gin.Context) {
+	address := c.Param("address")
+	if !common.IsHexAddress(address) {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid address"})
+		return
+	}
+
+	db := database.GetReadDB().WithContext(c.Request.Context())
+
+	var user models.User
+	if err := db.Where("LOWER(address) = ?", strings.ToLower(address)).First(&user).Error; err != nil && err != gorm.ErrRecordNotFound {
+		serverError(c, err, "Database error")
+		return
+	}
+	if user.PublicKey == "" {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Public key not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"address":    user.Address,
+		"public_key": user.PublicKey,
+	})
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+)
+
+// challengeCleanupInterval 清理过期解密挑战的间隔
+const challengeCleanupInterval = time.Hour
+
+// StartChallengeCleanup 后台定期删除已过宽限期仍未使用的解密挑战
+func StartChallengeCleanup(ctx context.Context, grace time.Duration) {
+	go func() {
+		ticker := time.NewTicker(challengeCleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := database.GetDB().WithContext(ctx).Where("expires_at < ?", time.Now().Add(-grace)).
+				Delete(&models.DecryptChallenge{}).Error; err != nil {
+				log.Println("Decrypt challenge cleanup failed:", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+var (
+	usersGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vaultseed_users",
+		Help: "Registered users.",
+	})
+	contentGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vaultseed_content_rows",
+		Help: "Stored content rows, excluding soft-deleted ones.",
+	})
+)
+
+// StartMetricsRefresher 启动时及之后每隔 interval 统计用户数和内容数，更新 /metrics 中的仪表
+func StartMetricsRefresher(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := refreshGauges(ctx, database.GetReadDB()); err != nil {
+				log.Println("Metrics refresh failed:", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// refreshGauges 查询用户和内容的总数
+func refreshGauges(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+	var users, contents int64
+	if err := db.Model(&models.User{}).Count(&users).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.EncryptedContent{}).Count(&contents).Error; err != nil {
+		return err
+	}
+	usersGauge.Set(float64(users))
+	contentGauge.Set(float64(contents))
+	return nil
+}
@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// rotationBatchSize 每批检查的用户数
+const rotationBatchSize = 500
+
+// StartNonceRotation 后台定期轮换超过 maxAge 未更新的登录 nonce，使泄漏的旧 nonce 失效
+// 轮换明细以 Debug 级别记录，DEBUG=true 时输出
+func StartNonceRotation(ctx context.Context, interval, maxAge time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			rotated, err := rotateStaleNonces(ctx, database.GetDB(), time.Now().Add(-maxAge))
+			if err != nil {
+				log.Println("Nonce rotation failed:", err)
+			} else if rotated > 0 {
+				slog.Debug("Nonce rotation finished", "rotated", rotated)
+			}
+		}
+	}()
+}
+
+// rotateStaleNonces 轮换签发时间早于 cutoff 的登录 nonce
+// 更新条件同时匹配旧 nonce 和签发时间：期间被登录或 /nonce 重新签发的 nonce 不会被覆盖
+func rotateStaleNonces(ctx context.Context, db *gorm.DB, cutoff time.Time) (int, error) {
+	db = db.WithContext(ctx)
+	rotated := 0
+	var lastID uint
+	for {
+		var users []models.User
+		if err := db.Select("id", "address", "nonce").
+			Where("id > ? AND nonce_issued_at < ?", lastID, cutoff).
+			Order("id").Limit(rotationBatchSize).Find(&users).Error; err != nil {
+			return rotated, err
+		}
+		if len(users) == 0 {
+			return rotated, nil
+		}
+
+		for _, user := range users {
+			lastID = user.ID
+			nonce, err := utils.GenerateNonce()
+			if err != nil {
+				return rotated, err
+			}
+			result := db.Model(&models.User{}).
+				Where("id = ? AND nonce = ? AND nonce_issued_at < ?", user.ID, user.Nonce, cutoff).
+				Updates(map[string]interface{}{"nonce": nonce, "nonce_issued_at": time.Now()})
+			if result.Error != nil {
+				return rotated, result.Error
+			}
+			if result.RowsAffected > 0 {
+				rotated++
+				slog.Debug("Nonce rotation: rotated stale nonce", "address", user.Address)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// uploadCleanupInterval 清理过期分块上传的间隔
+const uploadCleanupInterval = time.Hour
+
+// StartUploadCleanup 后台定期删除超过 ttl 仍未完成的分块上传及其分块
+func StartUploadCleanup(ctx context.Context, ttl time.Duration) {
+	go func() {
+		ticker := time.NewTicker(uploadCleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := deleteStaleUploads(ctx, database.GetDB(), time.Now().Add(-ttl)); err != nil {
+				log.Println("Upload cleanup failed:", err)
+			}
+		}
+	}()
+}
+
+// deleteStaleUploads 删除创建时间早于 cutoff 的分块上传
+func deleteStaleUploads(ctx context.Context, db *gorm.DB, cutoff time.Time) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("upload_id IN (SELECT id FROM uploads WHERE created_at < ?)", cutoff).
+			Delete(&models.UploadChunk{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("created_at < ?", cutoff).Delete(&models.Upload{}).Error
+	})
+}
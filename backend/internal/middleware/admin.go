@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAdmin 仅允许管理员地址访问，须在 RequireAuth 之后使用
+func RequireAdmin(admins []string) gin.HandlerFunc {
+	adminSet := toAddressSet(admins)
+	return func(c *gin.Context) {
+		userAddress := c.GetString("userAddress")
+
+		if _, ok := adminSet[strings.ToLower(userAddress)]; !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{Error: "Admin access required"})
+			return
+		}
+
+		c.Set("adminAddress", userAddress)
+		c.Next()
+	}
+}
+
+// toAddressSet 将地址列表转换为小写集合
+func toAddressSet(addresses []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(addresses))
+	for _, addr := range addresses {
+		set[strings.ToLower(addr)] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"vaultseed-backend/internal/models"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireAuth 校验 Authorization 头中的访问令牌，通过后将地址写入 "userAddress"
+// 旧版 address:nonce 令牌不再接受：其中的 nonce 可通过 GET /api/auth/nonce 公开获取，任何知道地址的人都能伪造
+// 收到旧格式时记录警告并提示客户端重新登录，便于统计仍未升级的客户端
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		claims, err := utils.ParseToken(header)
+		if err != nil {
+			if address, ok := legacyTokenAddress(header); ok {
+				slog.Warn("Rejected legacy address:nonce token", "request_id", c.GetString("requestID"), "address", address, "path", c.Request.URL.Path)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Legacy token format is no longer supported; please log in again"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or expired token"})
+			return
+		}
+
+		c.Set("userAddress", claims.Address)
+		c.Next()
+	}
+}
+
+// legacyTokenAddress 识别旧版登录返回的 address:nonce 令牌（可带 Bearer 前缀），返回其中的地址
+func legacyTokenAddress(header string) (string, bool) {
+	raw := strings.TrimSpace(header)
+	if len(raw) > 7 && strings.EqualFold(raw[:7], "Bearer ") {
+		raw = strings.TrimSpace(raw[7:])
+	}
+	address, nonce, ok := strings.Cut(raw, ":")
+	if !ok || !common.IsHexAddress(address) || len(nonce) != 64 {
+		return "", false
+	}
+	return address, true
+}
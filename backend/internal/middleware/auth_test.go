@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"vaultseed-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAuthTokenFormats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if _, err := utils.SetTokenSecret("test-secret", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	address := "0x52908400098527886E0F7030069857D2E4169EE7"
+	token, _, err := utils.IssueToken(address, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := address + ":" + strings.Repeat("ab", 32)
+
+	r := gin.New()
+	r.GET("/", RequireAuth(), func(c *gin.Context) { c.String(http.StatusOK, c.GetString("userAddress")) })
+
+	tests := []struct {
+		name     string
+		header   string
+		status   int
+		contains string
+	}{
+		{"jwt", "Bearer " + token, http.StatusOK, address},
+		{"legacy", legacy, http.StatusUnauthorized, "Legacy token format"},
+		{"legacy with bearer", "Bearer " + legacy, http.StatusUnauthorized, "Legacy token format"},
+		{"garbage", "Bearer nope", http.StatusUnauthorized, "Invalid or expired token"},
+		{"missing", "", http.StatusUnauthorized, "Invalid or expired token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tt.header)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.contains) {
+				t.Fatalf("got %d %s, want %d containing %q", w.Code, w.Body.String(), tt.status, tt.contains)
+			}
+		})
+	}
+}
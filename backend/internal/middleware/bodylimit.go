@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// originalBodyKey 保存未经限制的原始请求体，供后续的 MaxBodySize 替换上限
+const originalBodyKey = "originalBody"
+
+// MaxBodySize 限制请求体大小，超出部分在绑定时返回错误（bindJSON 返回 413）
+// 同一请求多次使用时以最后一次为准：路由组设置默认上限，单个路由可以放宽或收紧
+func MaxBodySize(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body != nil {
+			body := c.Request.Body
+			if original, ok := c.Get(originalBodyKey); ok {
+				body = original.(io.ReadCloser)
+			} else {
+				c.Set(originalBodyKey, body)
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, body, limit)
+		}
+		c.Next()
+	}
+}
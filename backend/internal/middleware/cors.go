@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS 只允许 allowedOrigins 中的来源跨域访问；allowedOrigins 为空时允许所有来源（仅适合开发环境）
+func CORS(allowedOrigins []string) gin.HandlerFunc {
+	config := cors.DefaultConfig()
+	if len(allowedOrigins) > 0 {
+		config.AllowOrigins = allowedOrigins
+	} else {
+		config.AllowAllOrigins = true
+	}
+	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Reauth-Message", "X-Reauth-Signature", RequestIDHeader}
+	config.ExposeHeaders = []string{RequestIDHeader}
+	return cors.New(config)
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const allowed = "https://app.example.com"
+	tests := []struct {
+		name        string
+		origins     []string
+		origin      string
+		method      string
+		status      int
+		allowOrigin string // 期望的 Access-Control-Allow-Origin，为空表示不应返回
+	}{
+		{"allowed origin", []string{allowed}, allowed, http.MethodGet, http.StatusOK, allowed},
+		{"allowed origin preflight", []string{allowed}, allowed, http.MethodOptions, http.StatusNoContent, allowed},
+		{"other origin", []string{allowed}, "https://evil.example.com", http.MethodGet, http.StatusForbidden, ""},
+		{"other origin preflight", []string{allowed}, "https://evil.example.com", http.MethodOptions, http.StatusForbidden, ""},
+		{"no origins configured", nil, "https://anything.example.com", http.MethodGet, http.StatusOK, "*"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(CORS(tt.origins))
+			r.GET("/api/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(tt.method, "/api/health", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+				req.Header.Set("Access-Control-Request-Headers", "Authorization, X-Reauth-Signature")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+			if tt.method == http.MethodOptions && tt.allowOrigin != "" {
+				headers := strings.ToLower(w.Header().Get("Access-Control-Allow-Headers"))
+				if !strings.Contains(headers, "x-reauth-signature") || !strings.Contains(headers, "authorization") {
+					t.Errorf("Access-Control-Allow-Headers = %q", headers)
+				}
+			}
+		})
+	}
+}
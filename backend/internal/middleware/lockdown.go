@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"vaultseed-backend/internal/database"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BlockDuringLockdown 紧急锁定期间拒绝请求，返回 503
+func BlockDuringLockdown() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if database.LockdownActive() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Service locked down"})
+			return
+		}
+		c.Next()
+	}
+}
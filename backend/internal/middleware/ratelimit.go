@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"vaultseed-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitStore 保存限流状态；默认实现在进程内存中，多实例部署可替换为共享存储（如 Redis）
+type RateLimitStore interface {
+	// Take 为 key 消耗一次配额，被拒绝时返回 false 及建议的重试等待时间
+	Take(key string, now time.Time) (bool, time.Duration)
+}
+
+// RateLimit 按客户端 IP 限流，超出时返回 429 并带 Retry-After 头；store 为 nil 时不限流
+// 客户端 IP 由 gin 的 ClientIP 给出，只有来自受信任代理（SetTrustedProxies）的请求才采信 X-Forwarded-For
+func RateLimit(store RateLimitStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if store == nil {
+			c.Next()
+			return
+		}
+		ok, retryAfter := store.Take(c.ClientIP(), time.Now())
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, models.ErrorResponse{Error: "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// tokenBucket 单个 key 的令牌桶
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// MemoryRateLimitStore 进程内的令牌桶限流：每个 key 最多积攒 limit 个令牌，每 window 补满
+type MemoryRateLimitStore struct {
+	capacity float64
+	perSec   float64
+	window   time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore 创建每 window 允许 limit 次请求的内存限流存储
+func NewMemoryRateLimitStore(limit int, window time.Duration) *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		capacity: float64(limit),
+		perSec:   float64(limit) / window.Seconds(),
+		window:   window,
+		buckets:  make(map[string]*tokenBucket),
+	}
+}
+
+// Take 实现 RateLimitStore
+func (s *MemoryRateLimitStore) Take(key string, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 每个窗口清理一次已补满的桶，避免记录随来访 IP 无限增长
+	if now.Sub(s.lastSweep) >= s.window {
+		for k, b := range s.buckets {
+			if now.Sub(b.last) >= s.window {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: s.capacity, last: now}
+		s.buckets[key] = b
+	} else {
+		b.tokens = math.Min(s.capacity, b.tokens+now.Sub(b.last).Seconds()*s.perSec)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / s.perSec * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		secondStatus   int // 换一个 X-Forwarded-For 后的第二次请求
+	}{
+		{"no trusted proxies", nil, "203.0.113.7:4321", http.StatusTooManyRequests},
+		{"untrusted peer", []string{"10.0.0.0/8"}, "203.0.113.7:4321", http.StatusTooManyRequests},
+		{"trusted proxy", []string{"10.0.0.0/8"}, "10.0.0.2:4321", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.trustedProxies); err != nil {
+				t.Fatal(err)
+			}
+			r.Use(RateLimit(NewMemoryRateLimitStore(1, time.Hour)))
+			r.GET("/nonce", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			send := func(forwardedFor string) int {
+				req := httptest.NewRequest(http.MethodGet, "/nonce", nil)
+				req.RemoteAddr = tt.remoteAddr
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+			if got := send("198.51.100.1"); got != http.StatusOK {
+				t.Fatalf("first request status = %d, want 200", got)
+			}
+			if got := send("198.51.100.2"); got != tt.secondStatus {
+				t.Errorf("request with a new X-Forwarded-For status = %d, want %d", got, tt.secondStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery 捕获处理函数中的 panic，记录日志并返回 500（响应已开始写出时只中止后续处理）
+// http.ErrAbortHandler 原样向上抛出，由 net/http 直接断开连接：流式响应中途失败时客户端能感知到截断，而不是收到看似完整的 200
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			logger.Error("panic recovered",
+				"request_id", c.GetString("requestID"),
+				"path", c.Request.URL.Path,
+				"error", err,
+				"stack", string(debug.Stack()),
+			)
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+		c.Next()
+	}
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // User 用户模型
@@ -12,65 +14,520 @@ type User struct {
 	Nonce     string    `json:"nonce" gorm:"not null"` // 用于防重放攻击
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	NonceIssuedAt   time.Time  `json:"nonce_issued_at"`             // 登录 nonce 签发时间
+	NonceReuseCount int        `json:"-" gorm:"not null;default:0"` // 检测到的 nonce 重放次数
+	LockedUntil     *time.Time `json:"-"`                           // 因重放被临时锁定的截止时间
+
+	// 最近一次登录时用户签名的原始消息（非机密），仅在管理员用户信息中展示，用于争议处理
+	LastSignedMessage string     `json:"-" gorm:"type:text"`
+	LastLoginAt       *time.Time `json:"-"`
+
+	// 标题托管（可选，默认关闭）：开启后服务端保存用主密钥包装的标题密钥，可在服务端搜索加密标题。
+	// 这是以便利换取零知识的折衷，开启的用户其标题对服务端不再保密
+	TitleEscrow      bool   `json:"-" gorm:"not null;default:false"`
+	EscrowedTitleKey string `json:"-" gorm:"type:text"`
+
+	// 按用户覆盖的会话策略（秒），为空时使用全局配置
+	NonceTTLSeconds *int `json:"-"` // 覆盖 DECRYPT_NONCE_TTL 和 LOGIN_NONCE_TTL
+	TokenTTLSeconds *int `json:"-"` // 覆盖 JWT_TTL
+
+	// 按用户覆盖的内容条数上限，为空时使用 MAX_CONTENT_PER_USER，0 表示不限制
+	MaxContent *int64 `json:"-"`
+}
+
+// EffectiveNonceTTL 用户的解密和登录 nonce 有效期，未覆盖时返回 fallback
+func (u *User) EffectiveNonceTTL(fallback time.Duration) time.Duration {
+	if u.NonceTTLSeconds == nil {
+		return fallback
+	}
+	return time.Duration(*u.NonceTTLSeconds) * time.Second
+}
+
+// EffectiveTokenTTL 用户的访问令牌有效期，未覆盖时返回 fallback
+func (u *User) EffectiveTokenTTL(fallback time.Duration) time.Duration {
+	if u.TokenTTLSeconds == nil {
+		return fallback
+	}
+	return time.Duration(*u.TokenTTLSeconds) * time.Second
+}
+
+// EffectiveMaxContent 用户的内容条数上限（0 表示不限制），未覆盖时返回 fallback
+func (u *User) EffectiveMaxContent(fallback int64) int64 {
+	if u.MaxContent == nil {
+		return fallback
+	}
+	return *u.MaxContent
+}
+
+// UserKey 用户公钥历史，每次注册新公钥都会保留一条记录
+type UserKey struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Address   string    `json:"address" gorm:"index;not null"`
+	PublicKey string    `json:"public_key" gorm:"type:text;not null"`
+	Label     string    `json:"label"`
+	Active    bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // EncryptedContent 加密内容模型
 type EncryptedContent struct {
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	UserAddress       string         `json:"user_address" gorm:"index;not null"`
+	Title             string         `json:"title" gorm:"not null"`                         // 明文标题，启用标题加密时为空
+	TitleEncrypted    bool           `json:"title_encrypted" gorm:"not null;default:false"` // 标题是否由客户端加密
+	EncryptedTitle    string         `json:"encrypted_title" gorm:"type:text"`              // 客户端加密的标题
+	ContentType       string         `json:"content_type" gorm:"index"`                     // 内容类型，如 note、password、file
+	Metadata          string         `json:"metadata" gorm:"type:text"`                     // 类型相关的（加密）元数据，JSON 对象
+	EncryptedData     string         `json:"encrypted_data" gorm:"type:text;not null"`      // 加密后的正文
+	EncryptedKey      string         `json:"encrypted_key" gorm:"type:text;not null"`       // 使用用户公钥加密的对称密钥
+	IV                string         `json:"iv" gorm:"type:text;not null"`                  // 初始化向量
+	KeyIVHash         string         `json:"-" gorm:"index"`                                // (encrypted_key, iv) 的哈希，用于检测 IV 重用
+	KeyID             *uint          `json:"key_id" gorm:"index"`                           // 加密 encrypted_key 所用的公钥
+	Version           int            `json:"version" gorm:"not null;default:1"`             // 正文版本号，每次更新或恢复历史版本时加一
+	FolderID          *uint          `json:"folder_id" gorm:"index"`                        // 所在文件夹，为空表示根目录
+	LabelID           *uint          `json:"label_id" gorm:"index"`                         // 可选的彩色标签（每条内容最多一个）
+	Strength          *int           `json:"strength"`                                      // 客户端计算的密码强度（0–4），仅 password 类型
+	Archived          bool           `json:"archived" gorm:"index;not null;default:false"`  // 已归档：默认列表不显示，仍可解密
+	AccessWindowStart *string        `json:"access_window_start"`                           // 可选的每日解密时间窗口开始（HH:MM）
+	AccessWindowEnd   *string        `json:"access_window_end"`                             // 时间窗口结束（HH:MM）
+	AccessWindowTZ    string         `json:"access_window_tz"`                              // 时间窗口所用时区（IANA 名称，默认 UTC）
+	Nonce             string         `json:"nonce" gorm:"not null"`                         // 用于解密时的防重放攻击
+	NonceIssuedAt     time.Time      `json:"nonce_issued_at"`                               // nonce 签发时间，用于过期判断
+	ExpiresAt         *time.Time     `json:"expires_at" gorm:"index"`                       // 可选的内容保留期限，过期后不可解密或分享
+	AvailableAt       *time.Time     `json:"available_at"`                                  // 可选的解密开放时间，之前只能查看不能解密
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"` // 移入回收站的时间，非空表示已软删除
+}
+
+// Expired 内容是否已过保留期限
+func (c *EncryptedContent) Expired(now time.Time) bool {
+	return c.ExpiresAt != nil && !now.Before(*c.ExpiresAt)
+}
+
+// Scheduled 内容是否尚未到开放解密的时间
+func (c *EncryptedContent) Scheduled(now time.Time) bool {
+	return c.AvailableAt != nil && now.Before(*c.AvailableAt)
+}
+
+// Status 内容当前状态
+func (c *EncryptedContent) Status(now time.Time) string {
+	switch {
+	case c.Expired(now):
+		return "expired"
+	case c.Scheduled(now):
+		return "scheduled"
+	default:
+		return "available"
+	}
+}
+
+// ShareLink 内容分享链接
+type ShareLink struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Token        string     `json:"token" gorm:"uniqueIndex;not null"`
+	ContentID    uint       `json:"content_id" gorm:"index;not null"`
+	OwnerAddress string     `json:"owner_address" gorm:"index;not null"`
+	EncryptedKey string     `json:"encrypted_key" gorm:"type:text;not null"` // 为链接重新包装的对称密钥
+	ExpiresAt    *time.Time `json:"expires_at"`
+	Views        int        `json:"views" gorm:"not null;default:0"`
+	Active       bool       `json:"active" gorm:"not null;default:true"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ContentRecipient 内容的指定接收者，EncryptedKey 为用接收者公钥重新包装的对称密钥
+type ContentRecipient struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	ContentID        uint      `json:"content_id" gorm:"uniqueIndex:idx_recipient_content_address;not null"`
+	OwnerAddress     string    `json:"owner_address" gorm:"index;not null"`
+	RecipientAddress string    `json:"recipient_address" gorm:"uniqueIndex:idx_recipient_content_address;index;not null"`
+	EncryptedKey     string    `json:"encrypted_key" gorm:"type:text;not null"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ContentRevision 内容被更新前的加密正文快照，用于恢复历史版本
+type ContentRevision struct {
 	ID            uint      `json:"id" gorm:"primaryKey"`
-	UserAddress   string    `json:"user_address" gorm:"index;not null"`
-	Title         string    `json:"title" gorm:"not null"`
-	EncryptedData string    `json:"encrypted_data" gorm:"type:text;not null"` // 加密后的正文
-	EncryptedKey  string    `json:"encrypted_key" gorm:"type:text;not null"`  // 使用用户公钥加密的对称密钥
-	IV            string    `json:"iv" gorm:"type:text;not null"`             // 初始化向量
-	Nonce         string    `json:"nonce" gorm:"not null"`                    // 用于解密时的防重放攻击
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ContentID     uint      `json:"content_id" gorm:"uniqueIndex:idx_revision_content_version;not null"`
+	Version       int       `json:"version" gorm:"uniqueIndex:idx_revision_content_version;not null"`
+	Title         string    `json:"title"`
+	EncryptedData string    `json:"encrypted_data" gorm:"type:text;not null"`
+	EncryptedKey  string    `json:"encrypted_key" gorm:"type:text;not null"`
+	IV            string    `json:"iv" gorm:"type:text;not null"`
+	KeyID         *uint     `json:"key_id"`
+	CreatedAt     time.Time `json:"created_at"` // 该版本被替换的时间
+}
+
+// DecryptChallenge 一次性的解密 nonce：签发给指定地址，解密时按 (content_id, nonce, 地址) 消费
+// 每次获取详情或解密挑战都签发新记录，并发的解密请求各自使用自己的 nonce，互不影响
+type DecryptChallenge struct {
+	ID          uint      `json:"-" gorm:"primaryKey"`
+	ContentID   uint      `json:"content_id" gorm:"uniqueIndex:idx_decrypt_challenge;not null"`
+	Nonce       string    `json:"nonce" gorm:"uniqueIndex:idx_decrypt_challenge;not null"`
+	UserAddress string    `json:"-" gorm:"index;not null"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index;not null"` // 之后仍有 DECRYPT_NONCE_GRACE 的宽限期
+	CreatedAt   time.Time `json:"-"`
+}
+
+// DecryptLog 解密尝试记录，包括签名错误等失败的尝试，供所有者查看内容的访问历史
+// 内容永久删除后记录仍保留（content_id 指向已删除的内容），仅在管理员清除用户数据时删除
+type DecryptLog struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ContentID   uint      `json:"content_id" gorm:"index;not null"`
+	UserAddress string    `json:"user_address" gorm:"not null"` // 发起解密的地址，可能不是所有者
+	ClientIP    string    `json:"client_ip"`
+	Success     bool      `json:"success"`
+	Reason      string    `json:"reason,omitempty"` // 失败原因，如 invalid_signature、invalid_nonce
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// Upload 分块上传会话：正文按序号分块上传，完成时拼接并校验整体哈希后创建内容
+type Upload struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserAddress string    `json:"-" gorm:"index;not null"`
+	TotalChunks int       `json:"total_chunks" gorm:"not null"`
+	SHA256      string    `json:"sha256" gorm:"not null"` // 拼接后 encrypted_data 的 SHA-256（十六进制）
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// UploadChunk 分块上传中已接收的分块，同一序号重复上传时覆盖
+type UploadChunk struct {
+	UploadID   uint   `gorm:"primaryKey;autoIncrement:false"`
+	ChunkIndex int    `gorm:"primaryKey;autoIncrement:false"`
+	Data       string `gorm:"type:text;not null"`
+	SHA256     string `gorm:"not null"`
+	CreatedAt  time.Time
+}
+
+// Folder 用户的内容文件夹
+type Folder struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	OwnerAddress string    `json:"owner_address" gorm:"index;not null"`
+	Name         string    `json:"name" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Label 用户的彩色标签，每条内容最多指定一个
+type Label struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Address   string    `json:"address" gorm:"index;not null"`
+	Name      string    `json:"name" gorm:"not null"`
+	Color     string    `json:"color" gorm:"not null"` // 十六进制颜色，如 #ff8800
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Tag 用户的内容标签，名称按用户隔离；一条内容可带多个标签
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Address   string    `json:"address" gorm:"uniqueIndex:idx_tag_address_name;not null"`
+	Name      string    `json:"name" gorm:"uniqueIndex:idx_tag_address_name;not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ContentTag 内容与标签的多对多关联
+type ContentTag struct {
+	ContentID uint `json:"content_id" gorm:"primaryKey"`
+	TagID     uint `json:"tag_id" gorm:"primaryKey;index"`
+}
+
+// Summary 列表和详情中返回的标签信息，nil 标签返回 nil
+func (l *Label) Summary() *LabelSummary {
+	if l == nil {
+		return nil
+	}
+	return &LabelSummary{ID: l.ID, Name: l.Name, Color: l.Color}
+}
+
+// UsedNonce 已消费的 nonce（只保存哈希），用于识别重放
+type UsedNonce struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	NonceHash string    `json:"nonce_hash" gorm:"uniqueIndex;not null"`
+	Address   string    `json:"address" gorm:"index;not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// AuditLog 审计日志
+type AuditLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Event     string    `json:"event" gorm:"index;index:idx_audit_event_created,priority:1;not null"`
+	Address   string    `json:"address" gorm:"index"`
+	Detail    string    `json:"detail" gorm:"type:text"`
+	ClientIP  string    `json:"client_ip"`
+	CreatedAt time.Time `json:"created_at" gorm:"index;index:idx_audit_event_created,priority:2"`
+}
+
+// Setting 服务级的持久化设置（键值对），如紧急锁定状态
+type Setting struct {
+	Key       string    `json:"key" gorm:"primaryKey"`
+	Value     string    `json:"value" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TitleEscrowRequest 开启或关闭标题托管
+type TitleEscrowRequest struct {
+	Enabled     *bool  `json:"enabled" binding:"required"`
+	TitleKey    string `json:"title_key"`   // 开启时必填：客户端加密标题所用的 AES-256 密钥（base64）
+	Acknowledge bool   `json:"acknowledge"` // 开启时必须为 true，表示用户已知晓服务端将能够读取标题
+}
+
+// SessionPolicyRequest 管理员设置用户的会话策略，字段为 null 表示恢复全局配置
+type SessionPolicyRequest struct {
+	NonceTTLSeconds *int `json:"nonce_ttl_seconds" binding:"omitempty,min=10,max=86400"`
+	TokenTTLSeconds *int `json:"token_ttl_seconds" binding:"omitempty,min=60,max=2592000"`
+}
+
+// UserQuotaRequest 管理员设置用户的内容条数上限，null 表示恢复全局配置，0 表示不限制
+type UserQuotaRequest struct {
+	MaxContent *int64 `json:"max_content" binding:"omitempty,min=0"`
+}
+
+// SetLockdownRequest 开启或解除紧急锁定
+type SetLockdownRequest struct {
+	Active *bool  `json:"active" binding:"required"`
+	Reason string `json:"reason" binding:"max=200"` // 可选，写入审计日志
 }
 
 // LoginRequest 登录请求
 type LoginRequest struct {
-	Address   string `json:"address" binding:"required"`
-	Signature string `json:"signature" binding:"required"`
-	Message   string `json:"message" binding:"required"`
-	Nonce     string `json:"nonce" binding:"required"`
+	Address     string  `json:"address" binding:"required"`
+	Signature   string  `json:"signature" binding:"required"`
+	Message     string  `json:"message" binding:"required"`
+	Nonce       string  `json:"nonce" binding:"required"`
+	BlockNumber *uint64 `json:"block_number"` // 启用区块新鲜度校验时，消息中引用的区块
+	BlockHash   string  `json:"block_hash"`
+	ChainID     uint64  `json:"chain_id"` // 可选，签名 V 为 EIP-155 格式时用于解析恢复位
 }
 
 // RegisterPublicKeyRequest 注册公钥请求
 type RegisterPublicKeyRequest struct {
 	Address   string `json:"address" binding:"required"`
 	PublicKey string `json:"public_key" binding:"required"`
+	Label     string `json:"label" binding:"max=50"`
 	Signature string `json:"signature" binding:"required"`
 	Message   string `json:"message" binding:"required"`
+
+	ConfirmReplace bool `json:"confirm_replace"` // 替换已注册的其他公钥时必须为 true
+}
+
+// ResetNonceRequest 重置 nonce 请求，签名消息中须包含当前 nonce
+type ResetNonceRequest struct {
+	Address   string `json:"address" binding:"required"`
+	Nonce     string `json:"nonce" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+// RotateKeyRequest 轮换加密公钥，同时提交用新公钥重新包装的全部内容密钥
+// 新公钥可以与地址无关，由 Signature（地址对轮换消息的签名）授权
+type RotateKeyRequest struct {
+	PublicKey string         `json:"public_key" binding:"required"`
+	Label     string         `json:"label" binding:"max=50"`
+	Nonce     string         `json:"nonce" binding:"required"` // 当前登录 nonce
+	Signature string         `json:"signature" binding:"required"`
+	Keys      []RewrappedKey `json:"keys" binding:"required,dive"` // 须恰好覆盖用户的全部内容（含回收站），没有内容时为空数组
+
+	// 共享给该用户的内容用新公钥重新包装的密钥，须恰好覆盖全部共享记录，没有时可省略
+	RecipientKeys []RewrappedKey `json:"recipient_keys" binding:"dive"`
+}
+
+// RewrappedKey 用新公钥重新包装的内容密钥
+type RewrappedKey struct {
+	ContentID    uint   `json:"content_id" binding:"required"`
+	EncryptedKey string `json:"encrypted_key" binding:"required"`
+}
+
+// MatchSignerRequest 识别签名地址请求
+type MatchSignerRequest struct {
+	Message   string   `json:"message" binding:"required"`
+	Signature string   `json:"signature" binding:"required"`
+	Addresses []string `json:"addresses" binding:"max=20"` // 可选的候选地址，为空时匹配任意已注册地址
+}
+
+// ContentFields 创建内容时除正文外的字段；分块上传完成时单独提交
+type ContentFields struct {
+	Title             string            `json:"title" binding:"required_without=EncryptedTitle,max=100"`
+	EncryptedTitle    string            `json:"encrypted_title" binding:"max=1024"`               // 可选，客户端加密的标题（与 title 二选一）
+	ContentType       string            `json:"content_type" binding:"omitempty,max=32,alphanum"` // 可选，默认 note
+	Metadata          map[string]string `json:"metadata"`                                         // 类型相关的（加密）元数据
+	EncryptedKey      string            `json:"encrypted_key" binding:"required"`                 // 使用公钥加密的对称密钥
+	IV                string            `json:"iv" binding:"required"`                            // 初始化向量
+	KeyID             *uint             `json:"key_id"`                                           // 可选，默认使用当前激活的公钥
+	AccessWindowStart *string           `json:"access_window_start"`                              // 可选的每日解密时间窗口，例如 "09:00"
+	AccessWindowEnd   *string           `json:"access_window_end"`                                // 例如 "18:00"，早于开始时间表示跨越午夜
+	AccessWindowTZ    string            `json:"access_window_tz"`                                 // IANA 时区名称，默认 UTC
+	ExpiresAt         *time.Time        `json:"expires_at"`                                       // 可选的内容保留期限
+	AvailableAt       *time.Time        `json:"available_at"`                                     // 可选的解密开放时间
+	Strength          *int              `json:"strength" binding:"omitempty,min=0,max=4"`         // 可选，客户端计算的密码强度，仅 password 类型
+	Tags              []string          `json:"tags" binding:"max=20,dive,required,max=50"`       // 可选，标签名称，按用户隔离
 }
 
 // CreateContentRequest 创建内容请求
 type CreateContentRequest struct {
-	Title         string `json:"title" binding:"required,max=100"`
-	EncryptedKey  string `json:"encrypted_key" binding:"required"`  // 使用公钥加密的对称密钥
-	IV            string `json:"iv" binding:"required"`             // 初始化向量
+	ContentFields
 	EncryptedData string `json:"encrypted_data" binding:"required"` // 加密后的内容
 }
 
-// DecryptContentRequest 解密内容请求
-type DecryptContentRequest struct {
-	ContentID uint   `json:"content_id" binding:"required"`
+// StartUploadRequest 开始分块上传请求
+type StartUploadRequest struct {
+	TotalChunks int    `json:"total_chunks" binding:"required,min=1"`
+	SHA256      string `json:"sha256" binding:"required,len=64,hexadecimal"` // 完整 encrypted_data 的 SHA-256
+}
+
+// UploadChunkRequest 上传单个分块请求
+type UploadChunkRequest struct {
+	Data   string `json:"data" binding:"required"`                      // encrypted_data 的一段
+	SHA256 string `json:"sha256" binding:"required,len=64,hexadecimal"` // 该分块的 SHA-256
+}
+
+// CompleteUploadRequest 完成分块上传请求，正文由已上传的分块拼接
+type CompleteUploadRequest struct {
+	ContentFields
+}
+
+// DeleteContentRequest 删除内容请求，签名消息由 GenerateDeleteMessage 生成
+type DeleteContentRequest struct {
 	Signature string `json:"signature" binding:"required"`
-	Message   string `json:"message" binding:"required"`
 	Nonce     string `json:"nonce" binding:"required"`
 }
 
+// UpdateContentRequest 更新内容请求，签名消息由 GenerateUpdateMessage 生成
+type UpdateContentRequest struct {
+	Title         string `json:"title" binding:"max=100"`           // 可选，为空时保留原标题
+	EncryptedData string `json:"encrypted_data" binding:"required"` // 新的加密正文
+	EncryptedKey  string `json:"encrypted_key" binding:"required"`  // 新的加密对称密钥
+	IV            string `json:"iv" binding:"required"`             // 新的初始化向量
+	KeyID         *uint  `json:"key_id"`                            // 可选，默认使用当前激活的公钥
+	Version       int    `json:"version" binding:"required,min=1"`  // 客户端读取到的版本号，与当前版本不一致时返回 409
+	Signature     string `json:"signature" binding:"required"`
+	Nonce         string `json:"nonce" binding:"required"`
+}
+
+// RestoreRevisionRequest 恢复历史版本请求，签名消息由 GenerateRestoreRevisionMessage 生成
+type RestoreRevisionRequest struct {
+	Signature string `json:"signature" binding:"required"`
+	Nonce     string `json:"nonce" binding:"required"`
+}
+
+// DecryptContentRequest 解密内容请求
+type DecryptContentRequest struct {
+	ContentID     uint   `json:"content_id" binding:"required"`
+	Signature     string `json:"signature" binding:"required"`
+	Message       string `json:"message"` // personal_sign 时为签名的消息，服务端按 content_id 和 nonce 重新生成，仅为兼容保留
+	Nonce         string `json:"nonce" binding:"required"`
+	SignatureType string `json:"signature_type" binding:"omitempty,oneof=personal_sign eip712"` // 默认 personal_sign
+	ChainID       uint64 `json:"chain_id" binding:"required_if=SignatureType eip712"`           // EIP-712 域中的链 ID，须与服务端 CHAIN_ID 一致
+}
+
+// TransferContentRequest 转移内容所有权请求（需新旧两个地址分别签名）
+type TransferContentRequest struct {
+	NewAddress       string `json:"new_address" binding:"required"`
+	Nonce            string `json:"nonce" binding:"required"`
+	CurrentSignature string `json:"current_signature" binding:"required"` // 当前地址的签名
+	NewSignature     string `json:"new_signature" binding:"required"`     // 新地址的签名
+}
+
+// CreateShareLinkRequest 创建分享链接请求
+type CreateShareLinkRequest struct {
+	EncryptedKey     string `json:"encrypted_key" binding:"required"`             // 客户端为链接重新包装的对称密钥
+	ExpiresInSeconds int64  `json:"expires_in_seconds" binding:"omitempty,min=0"` // 0 表示不过期
+}
+
+// AddRecipientRequest 添加内容接收者请求
+type AddRecipientRequest struct {
+	Address      string `json:"address" binding:"required"`
+	EncryptedKey string `json:"encrypted_key" binding:"required"`
+}
+
+// ShareContentRequest 分享内容给指定用户请求，encrypted_key 为用接收者公钥重新包装的对称密钥
+type ShareContentRequest struct {
+	RecipientAddress string `json:"recipient_address" binding:"required"`
+	EncryptedKey     string `json:"encrypted_key" binding:"required"`
+}
+
+// CreateFolderRequest 创建文件夹请求
+type CreateFolderRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// CreateLabelRequest 创建标签请求
+type CreateLabelRequest struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Color string `json:"color" binding:"required,hexcolor"`
+}
+
+// SetContentLabelRequest 设置内容标签请求
+type SetContentLabelRequest struct {
+	LabelID *uint `json:"label_id"` // 为空表示清除标签
+}
+
+// MoveContentRequest 批量移动内容请求
+type MoveContentRequest struct {
+	ContentIDs []uint `json:"content_ids" binding:"required,min=1,max=500"`
+	FolderID   *uint  `json:"folder_id"` // 为空表示移到根目录
+}
+
+// BulkTagRequest 批量为内容添加或移除标签
+type BulkTagRequest struct {
+	ContentIDs []uint   `json:"content_ids" binding:"required,min=1,max=500"`
+	Tags       []string `json:"tags" binding:"required,min=1,max=20,dive,required,max=50"`
+	Mode       string   `json:"mode" binding:"omitempty,oneof=add remove"` // 默认 add
+}
+
+// ImportItem 导入文件中的单条内容，格式与 ExportItem 兼容（忽略 id 和时间戳）
+type ImportItem struct {
+	Title             string            `json:"title"`
+	EncryptedTitle    string            `json:"encrypted_title"`
+	EncryptedData     string            `json:"encrypted_data"`
+	EncryptedKey      string            `json:"encrypted_key"`
+	IV                string            `json:"iv"`
+	ContentType       string            `json:"content_type"` // 默认 note
+	Metadata          map[string]string `json:"metadata"`
+	Strength          *int              `json:"strength"`
+	AccessWindowStart *string           `json:"access_window_start"`
+	AccessWindowEnd   *string           `json:"access_window_end"`
+	AccessWindowTZ    string            `json:"access_window_tz"`
+	ExpiresAt         *time.Time        `json:"expires_at"`
+	AvailableAt       *time.Time        `json:"available_at"`
+	Folder            string            `json:"folder"` // 文件夹名称，不存在时创建
+	Tags              []string          `json:"tags"`
+}
+
+// ImportPreflightRequest 导入前的配额预检，只包含清单信息
+type ImportPreflightRequest struct {
+	ItemCount  int64 `json:"item_count" binding:"min=0"`
+	TotalBytes int64 `json:"total_bytes" binding:"min=0"` // 密文总字节数
+}
+
 // API 响应结构
 type LoginResponse struct {
-	Success bool   `json:"success"`
-	Token   string `json:"token,omitempty"`
-	Address string `json:"address"`
-	Message string `json:"message,omitempty"`
+	Success   bool       `json:"success"`
+	Token     string     `json:"token,omitempty"`
+	Address   string     `json:"address"`
+	Message   string     `json:"message,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type ContentResponse struct {
-	ID        uint      `json:"id"`
-	Title     string    `json:"title"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             uint          `json:"id"`
+	Title          string        `json:"title"`
+	TitleEncrypted bool          `json:"title_encrypted,omitempty"`
+	EncryptedTitle string        `json:"encrypted_title,omitempty"` // 标题加密时由客户端解密
+	ContentType    string        `json:"content_type"`
+	KeyID          *uint         `json:"key_id"`
+	FolderID       *uint         `json:"folder_id"`
+	Label          *LabelSummary `json:"label"`
+	Strength       *int          `json:"strength,omitempty"`
+	Archived       bool          `json:"archived,omitempty"`
+	Version        int           `json:"version"`
+	Tags           []string      `json:"tags"`
+	TitleDecrypted bool          `json:"title_decrypted,omitempty"` // 标题由服务端通过托管密钥解密
+	KeyDeactivated bool          `json:"key_deactivated,omitempty"` // 引用的公钥已停用，需要重新加密
+	Shared         bool          `json:"shared,omitempty"`          // 其他用户共享的内容，当前用户只读
+	OwnerAddress   string        `json:"owner_address,omitempty"`   // 共享内容的所有者
+	ExpiresAt      *time.Time    `json:"expires_at,omitempty"`
+	AvailableAt    *time.Time    `json:"available_at,omitempty"`
+	Status         string        `json:"status"` // available、scheduled 或 expired
+	CreatedAt      time.Time     `json:"created_at"`
 }
 
 type ContentDetailResponse struct {
@@ -80,6 +537,58 @@ type ContentDetailResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ExportItem 导出文件中的单条内容（仍为密文）
+type ExportItem struct {
+	ID             uint      `json:"id"`
+	Title          string    `json:"title"`
+	TitleEncrypted bool      `json:"title_encrypted,omitempty"`
+	EncryptedTitle string    `json:"encrypted_title,omitempty"`
+	EncryptedData  string    `json:"encrypted_data"`
+	EncryptedKey   string    `json:"encrypted_key"`
+	IV             string    `json:"iv"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	ContentType       string            `json:"content_type"`
+	Metadata          map[string]string `json:"metadata"`
+	Strength          *int              `json:"strength,omitempty"`
+	AccessWindowStart *string           `json:"access_window_start,omitempty"`
+	AccessWindowEnd   *string           `json:"access_window_end,omitempty"`
+	AccessWindowTZ    string            `json:"access_window_tz,omitempty"`
+	ExpiresAt         *time.Time        `json:"expires_at,omitempty"`
+	AvailableAt       *time.Time        `json:"available_at,omitempty"`
+	Folder            string            `json:"folder,omitempty"` // 文件夹名称（各账户的文件夹 ID 不同）
+	Tags              []string          `json:"tags,omitempty"`
+}
+
+// ShareLinkResponse 分享链接管理视图（不返回完整 token）
+type ShareLinkResponse struct {
+	ID           uint       `json:"id"` // 用于 DELETE /shares/by-id/:id
+	TokenPrefix  string     `json:"token_prefix"`
+	ContentID    uint       `json:"content_id"`
+	OwnerAddress string     `json:"owner_address,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	Views        int        `json:"views"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// LabelSummary 标签名称和颜色
+type LabelSummary struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// UserKeyResponse 公钥列表项
+type UserKeyResponse struct {
+	ID           uint      `json:"id"`
+	Label        string    `json:"label"`
+	PublicKey    string    `json:"public_key"`
+	Active       bool      `json:"active"`
+	ContentCount int64     `json:"content_count"` // 引用该公钥的内容数量
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
@@ -0,0 +1,44 @@
+package selfcheck
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ExitCode 自检失败时进程的退出码
+const ExitCode = 3
+
+// Check 单项启动自检
+type Check struct {
+	Name     string
+	Critical bool // 关键项失败时中止启动
+	Run      func(ctx context.Context) error
+}
+
+// Run 依次执行自检并输出汇总，存在关键项失败时返回错误
+func Run(ctx context.Context, checks []Check) error {
+	var failed []string
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := check.Run(checkCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			log.Printf("[selfcheck] OK    %s", check.Name)
+		case check.Critical:
+			log.Printf("[selfcheck] FAIL  %s: %v", check.Name, err)
+			failed = append(failed, check.Name)
+		default:
+			log.Printf("[selfcheck] WARN  %s: %v", check.Name, err)
+		}
+	}
+
+	log.Printf("[selfcheck] %d checks, %d critical failures", len(checks), len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("critical self-checks failed: %v", failed)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package siem
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"vaultseed-backend/internal/models"
+)
+
+// Options SIEM 转发配置，SyslogAddr 与 HTTPURL 可同时配置
+type Options struct {
+	SyslogAddr string // RFC 5424 syslog 接收端（TCP，host:port），为空时不转发
+	SyslogTLS  bool   // 是否使用 TLS 连接 syslog
+	HTTPURL    string // 通用 HTTP 收集端，逐条 POST JSON，为空时不转发
+	BufferSize int    // 待发送事件的缓冲条数，写满后丢弃新事件
+	AppName    string // syslog APP-NAME
+}
+
+// maxAttempts 单个事件在每个接收端的最大投递次数
+const maxAttempts = 3
+
+var (
+	queue   chan models.AuditLog
+	dropped atomic.Int64
+
+	client   = &http.Client{Timeout: 10 * time.Second}
+	hostname = "-"
+)
+
+// Configure 按配置启动后台转发，未配置任何接收端时不启动
+func Configure(opts Options) {
+	if opts.SyslogAddr == "" && opts.HTTPURL == "" {
+		return
+	}
+	if h, err := os.Hostname(); err == nil && h != "" {
+		hostname = h
+	}
+	queue = make(chan models.AuditLog, opts.BufferSize)
+	go run(opts)
+}
+
+// Enabled 是否启用了 SIEM 转发
+func Enabled() bool {
+	return queue != nil
+}
+
+// Dropped 因缓冲已满而丢弃的事件数
+func Dropped() int64 {
+	return dropped.Load()
+}
+
+// Forward 将审计事件放入发送缓冲，不阻塞调用方；缓冲已满时丢弃并计数
+func Forward(entry models.AuditLog) {
+	if queue == nil {
+		return
+	}
+	select {
+	case queue <- entry:
+	default:
+		if dropped.Add(1) == 1 {
+			log.Println("SIEM buffer full; dropping audit events")
+		}
+	}
+}
+
+// run 后台逐条投递，失败按指数退避重试，重试耗尽后记录日志并继续下一条
+func run(opts Options) {
+	s := &syslogSink{addr: opts.SyslogAddr, useTLS: opts.SyslogTLS, appName: opts.AppName}
+	for entry := range queue {
+		if opts.SyslogAddr != "" {
+			deliver("syslog", func() error { return s.send(entry) })
+		}
+		if opts.HTTPURL != "" {
+			deliver("http", func() error { return sendHTTP(opts.HTTPURL, entry) })
+		}
+	}
+}
+
+func deliver(sink string, send func() error) {
+	backoff := time.Second
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = send(); err == nil {
+			return
+		}
+		if attempt < maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	log.Printf("SIEM %s delivery failed after %d attempts: %v", sink, maxAttempts, err)
+}
+
+// syslogSink 维持到 syslog 接收端的长连接，写入失败时断开并在下次重连
+type syslogSink struct {
+	addr    string
+	useTLS  bool
+	appName string
+	conn    net.Conn
+}
+
+func (s *syslogSink) send(entry models.AuditLog) error {
+	if s.conn == nil {
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		var err error
+		if s.useTLS {
+			s.conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, &tls.Config{MinVersion: tls.VersionTLS12})
+		} else {
+			s.conn, err = dialer.Dial("tcp", s.addr)
+		}
+		if err != nil {
+			s.conn = nil
+			return err
+		}
+	}
+	msg := formatSyslog(entry, s.appName)
+	// RFC 6587 octet-counting 分帧
+	frame := fmt.Sprintf("%d %s", len(msg), msg)
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := s.conn.Write([]byte(frame)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// syslogPriority facility 13（log audit）、severity 5（notice）
+const syslogPriority = 13*8 + 5
+
+// sdEscaper 转义 RFC 5424 结构化数据参数值中的特殊字符
+var sdEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// formatSyslog 生成 RFC 5424 消息：事件类型作为 MSGID，地址和客户端 IP 放在结构化数据中，detail 作为正文
+func formatSyslog(entry models.AuditLog, appName string) string {
+	ts := entry.CreatedAt
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	app := strings.ReplaceAll(appName, " ", "-")
+	if app == "" {
+		app = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s [audit@32473 id=\"%d\" address=\"%s\" client_ip=\"%s\"] %s",
+		syslogPriority, ts.UTC().Format(time.RFC3339Nano), hostname, app, os.Getpid(), entry.Event,
+		entry.ID, sdEscaper.Replace(entry.Address), sdEscaper.Replace(entry.ClientIP), entry.Detail)
+}
+
+// sendHTTP 将单条审计事件以 JSON POST 到收集端，非 2xx 视为失败
+func sendHTTP(url string, entry models.AuditLog) error {
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// serverKey 服务端签名私钥，未配置时不签发证明
+var serverKey *ecdsa.PrivateKey
+
+// ErrNoServerKey 未配置 SERVER_SIGNING_KEY
+var ErrNoServerKey = errors.New("server signing key is not configured")
+
+// SetServerKey 设置服务端签名私钥（十六进制，可带 0x 前缀），为空表示关闭
+func SetServerKey(hexKey string) error {
+	if hexKey == "" {
+		serverKey = nil
+		return nil
+	}
+	key, err := crypto.HexToECDSA(normalizeHex(hexKey)[2:])
+	if err != nil {
+		return fmt.Errorf("invalid server signing key: %w", err)
+	}
+	serverKey = key
+	return nil
+}
+
+// ServerAddress 服务端签名私钥对应的地址，未配置时为空
+func ServerAddress() string {
+	if serverKey == nil {
+		return ""
+	}
+	return crypto.PubkeyToAddress(serverKey.PublicKey).Hex()
+}
+
+// DeletionAttestation 删除证明的签名内容
+type DeletionAttestation struct {
+	Type       string    `json:"type"` // 固定为 "deletion"
+	Address    string    `json:"address"`
+	ContentIDs []uint    `json:"content_ids"`
+	DeletedAt  time.Time `json:"deleted_at"`
+}
+
+// SignedAttestation 服务端签名的证明
+// signature 为对 payload 原文的 personal_sign 签名，可用 signer 地址校验
+type SignedAttestation struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+	Signer    string `json:"signer"`
+}
+
+// Reference 证明的引用（payload 的 SHA-256），写入审计日志
+func (a *SignedAttestation) Reference() string {
+	sum := sha256.Sum256([]byte(a.Payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignDeletion 签发删除证明
+func SignDeletion(address string, contentIDs []uint, deletedAt time.Time) (*SignedAttestation, error) {
+	if serverKey == nil {
+		return nil, ErrNoServerKey
+	}
+	if contentIDs == nil {
+		contentIDs = []uint{}
+	}
+	payload, err := json.Marshal(DeletionAttestation{
+		Type:       "deletion",
+		Address:    address,
+		ContentIDs: contentIDs,
+		DeletedAt:  deletedAt.UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(payload), payload)
+	sig, err := crypto.Sign(crypto.Keccak256([]byte(prefix)), serverKey)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+
+	return &SignedAttestation{
+		Payload:   string(payload),
+		Signature: hexutil.Encode(sig),
+		Signer:    ServerAddress(),
+	}, nil
+}
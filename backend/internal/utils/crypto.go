@@ -1,17 +1,137 @@
 package utils
 
 import (
+	"crypto/ecdsa"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/big"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// ErrInvalidSignature 签名格式错误或无法恢复公钥
+var ErrInvalidSignature = errors.New("invalid signature")
+
 // VerifyEthereumSignature 验证以太坊签名
 func VerifyEthereumSignature(message, signature, expectedAddress string) bool {
+	return VerifyEthereumSignatureWithChainID(message, signature, expectedAddress, 0)
+}
+
+// VerifyEthereumSignatureWithChainID 验证以太坊签名，V 为 EIP-155 格式时按指定链 ID 解析
+// chainID 为 0 表示未知链，此时只按奇偶性取恢复位
+func VerifyEthereumSignatureWithChainID(message, signature, expectedAddress string, chainID uint64) bool {
+	if allowShortSignatures {
+		if cleaned, sigBytes, err := decodeSignedMessage(message, signature); err == nil && len(sigBytes) == 64 {
+			return verifyShortSignature(cleaned, sigBytes, expectedAddress)
+		}
+	}
+
+	recovered, err := RecoverSignerWithChainID(message, signature, chainID)
+	if err != nil {
+		return false
+	}
+
+	// 使用字符串比较，确保大小写不敏感
+	return strings.ToLower(recovered) == strings.ToLower(expectedAddress)
+}
+
+// allowShortSignatures 是否接受不带 V 的 64 字节旧式签名
+var allowShortSignatures = false
+
+// SetAllowShortSignatures 设置是否接受 64 字节签名，启动时调用
+func SetAllowShortSignatures(allow bool) {
+	allowShortSignatures = allow
+}
+
+// verifyShortSignature 64 字节签名缺少恢复位：依次尝试 V=0 和 V=1，任一恢复出期望地址即通过
+func verifyShortSignature(message string, rs []byte, expectedAddress string) bool {
+	for _, recID := range []byte{0, 1} {
+		recovered, err := recoverAddress(message, rs, recID)
+		if err == nil && strings.EqualFold(recovered, expectedAddress) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecoverSigner 从 personal_sign 签名中恢复签名者地址（不做比较）
+func RecoverSigner(message, signature string) (string, error) {
+	return RecoverSignerWithChainID(message, signature, 0)
+}
+
+// maxVBytes V 值允许的最大字节数，足以容纳常见链 ID 的 EIP-155 V
+const maxVBytes = 8
+
+// recoveryID 将签名的 V 值换算为 0/1 恢复位
+// 支持 0/1、27/28 以及 EIP-155 的 chainID*2+35/36；chainID 为 0 时不校验 V 与链 ID 是否一致
+func recoveryID(v *big.Int, chainID uint64) (byte, error) {
+	switch {
+	case v.Cmp(big.NewInt(1)) <= 0:
+		return byte(v.Uint64()), nil
+	case v.Cmp(big.NewInt(27)) == 0 || v.Cmp(big.NewInt(28)) == 0:
+		return byte(v.Uint64() - 27), nil
+	case v.Cmp(big.NewInt(35)) >= 0:
+		rec := new(big.Int).Sub(v, big.NewInt(35))
+		if chainID == 0 {
+			return byte(rec.Bit(0)), nil
+		}
+		rec.Sub(rec, new(big.Int).Mul(big.NewInt(2), new(big.Int).SetUint64(chainID)))
+		if rec.Sign() < 0 || rec.Cmp(big.NewInt(1)) > 0 {
+			return 0, ErrInvalidSignature
+		}
+		return byte(rec.Uint64()), nil
+	}
+	return 0, ErrInvalidSignature
+}
+
+// RecoverSignerWithChainID 与 RecoverSigner 相同，V 为 EIP-155 格式时按指定链 ID 解析
+// V 可以超过一个字节（如 Polygon 等链 ID 较大的链）
+func RecoverSignerWithChainID(message, signature string, chainID uint64) (string, error) {
+	cleanedMessage, sigBytes, err := decodeSignedMessage(message, signature)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sigBytes) < 65 || len(sigBytes) > 64+maxVBytes {
+		return "", ErrInvalidSignature
+	}
+
+	// 处理 V 值
+	recID, err := recoveryID(new(big.Int).SetBytes(sigBytes[64:]), chainID)
+	if err != nil {
+		return "", err
+	}
+	return recoverAddress(cleanedMessage, sigBytes[:64], recID)
+}
+
+// personalMessageHash 计算 personal_sign（EIP-191）消息哈希
+func personalMessageHash(message string) [32]byte {
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(message), message)
+	return crypto.Keccak256Hash([]byte(prefix))
+}
+
+// PersonalMessageHash 按与签名校验相同的方式清理消息后计算 personal_sign 哈希，并解码签名
+// 用于合约钱包（EIP-1271）校验
+func PersonalMessageHash(message, signature string) ([32]byte, []byte, error) {
+	cleaned, sigBytes, err := decodeSignedMessage(message, signature)
+	if err != nil {
+		return [32]byte{}, nil, err
+	}
+	return personalMessageHash(cleaned), sigBytes, nil
+}
+
+// decodeSignedMessage 清理消息并解码十六进制签名
+func decodeSignedMessage(message, signature string) (string, []byte, error) {
 	// 清理消息
 	cleanedMessage := strings.TrimSpace(message)
 	if len(cleanedMessage) >= 2 && cleanedMessage[0] == '"' && cleanedMessage[len(cleanedMessage)-1] == '"' {
@@ -19,75 +139,269 @@ func VerifyEthereumSignature(message, signature, expectedAddress string) bool {
 	}
 	cleanedMessage = strings.TrimSpace(cleanedMessage)
 
-	// 确保签名有 0x 前缀
-	if !strings.HasPrefix(signature, "0x") {
-		signature = "0x" + signature
-	}
-
 	// 解码签名
-	sigBytes, err := hexutil.Decode(signature)
+	sigBytes, err := hexutil.Decode(normalizeHex(signature))
 	if err != nil {
-		return false
+		return "", nil, ErrInvalidSignature
 	}
+	return cleanedMessage, sigBytes, nil
+}
 
-	if len(sigBytes) != 65 {
-		return false
-	}
+// recoverAddress 用 R||S 和恢复位恢复 personal_sign 签名者地址
+func recoverAddress(message string, rs []byte, recID byte) (string, error) {
+	// 使用 Ethereum 标准消息哈希方法
+	return recoverHashSigner(personalMessageHash(message), rs, recID)
+}
 
-	// 处理 V 值
+// recoverHashSigner 用 R||S 和恢复位从消息摘要恢复签名者地址
+func recoverHashSigner(hash [32]byte, rs []byte, recID byte) (string, error) {
 	adjustedSigBytes := make([]byte, 65)
-	copy(adjustedSigBytes, sigBytes)
-
-	v := sigBytes[64]
-	if v == 27 || v == 28 {
-		adjustedSigBytes[64] = v - 27
-	} else if v == 0 || v == 1 {
-		// 已经是正确格式
-	} else {
-		// 尝试调整
-		if v >= 35 {
-			adjustedSigBytes[64] = v - 35 - 2 // 假设链ID为1
-		} else if v >= 27 {
-			adjustedSigBytes[64] = v - 27
-		} else {
-			adjustedSigBytes[64] = 0
-		}
-	}
+	copy(adjustedSigBytes, rs)
+	adjustedSigBytes[64] = recID
 
-	// 使用 Ethereum 标准消息哈希方法
-	msgBytes := []byte(cleanedMessage)
-	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(msgBytes), cleanedMessage)
-	hash := crypto.Keccak256Hash([]byte(prefix))
+	// 拒绝 R/S 为零或超出曲线阶的退化签名
+	r := new(big.Int).SetBytes(adjustedSigBytes[:32])
+	s := new(big.Int).SetBytes(adjustedSigBytes[32:64])
+	if !crypto.ValidateSignatureValues(recID, r, s, false) {
+		return "", ErrInvalidSignature
+	}
 
 	// 从签名恢复公钥
-	pubKey, err := crypto.SigToPub(hash.Bytes(), adjustedSigBytes)
+	pubKey, err := crypto.SigToPub(hash[:], adjustedSigBytes)
 	if err != nil {
-		return false
+		return "", ErrInvalidSignature
+	}
+
+	// 恢复出无穷远点的公钥不对应任何账户
+	if pubKey.X == nil || pubKey.Y == nil || (pubKey.X.Sign() == 0 && pubKey.Y.Sign() == 0) {
+		return "", ErrInvalidSignature
 	}
 
 	// 从公钥生成地址
-	recoveredAddr := crypto.PubkeyToAddress(*pubKey)
+	return crypto.PubkeyToAddress(*pubKey).Hex(), nil
+}
 
-	// 使用字符串比较，确保大小写不敏感
-	return strings.ToLower(recoveredAddr.Hex()) == strings.ToLower(expectedAddress)
+// ErrInvalidPublicKey 公钥不是合法的 secp256k1 公钥
+var ErrInvalidPublicKey = errors.New("invalid public key")
+
+// PublicKeyAddress 由十六进制 secp256k1 公钥推导以太坊地址
+// 支持 65 字节非压缩（04 前缀）、33 字节压缩以及去掉 04 前缀的 64 字节格式，0x 前缀可选
+func PublicKeyAddress(hexKey string) (string, error) {
+	raw, err := hexutil.Decode(normalizeHex(hexKey))
+	if err != nil {
+		return "", ErrInvalidPublicKey
+	}
+	var pubKey *ecdsa.PublicKey
+	switch len(raw) {
+	case 65:
+		pubKey, err = crypto.UnmarshalPubkey(raw)
+	case 64:
+		pubKey, err = crypto.UnmarshalPubkey(append([]byte{0x04}, raw...))
+	case 33:
+		pubKey, err = crypto.DecompressPubkey(raw)
+	default:
+		return "", ErrInvalidPublicKey
+	}
+	if err != nil {
+		return "", ErrInvalidPublicKey
+	}
+	return crypto.PubkeyToAddress(*pubKey).Hex(), nil
+}
+
+// normalizeHex 统一十六进制字符串格式：去除空白和 0x/0X 前缀、转为小写后补回 0x 前缀
+func normalizeHex(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		s = s[2:]
+	}
+	return "0x" + strings.ToLower(s)
+}
+
+// ciphertextDecodings 按十六进制（可带 0x 前缀）和 base64（标准或 URL 字母表，带或不带填充）尝试解码，返回所有成功的结果
+// 十六进制字符串同时也是合法的 base64，因此十六进制结果在前
+func ciphertextDecodings(s string) [][]byte {
+	s = strings.TrimSpace(s)
+	var out [][]byte
+	if b, err := hex.DecodeString(strings.TrimPrefix(s, "0x")); err == nil {
+		out = append(out, b)
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			out = append(out, b)
+			break
+		}
+	}
+	return out
+}
+
+// decodeCiphertext 返回首选的解码结果
+func decodeCiphertext(s string) ([]byte, bool) {
+	if decoded := ciphertextDecodings(s); len(decoded) > 0 {
+		return decoded[0], true
+	}
+	return nil, false
+}
+
+// CiphertextSize 返回编码密文解码后的字节数，无法解码时按原始字符串长度计算
+func CiphertextSize(s string) int {
+	if b, ok := decodeCiphertext(s); ok {
+		return len(b)
+	}
+	return len(strings.TrimSpace(s))
+}
+
+// ValidateCiphertextFields 校验密文字段格式，避免保存永远无法解密的数据
+// encrypted_data 须为 base64 或十六进制；encrypted_key 须为 base64、十六进制或 JSON 对象（包装密钥的信封格式）；
+// iv 解码后须为 12 字节（AES-GCM）或 16 字节（AES-CBC）
+func ValidateCiphertextFields(encryptedData, encryptedKey, iv string) error {
+	if b, ok := decodeCiphertext(encryptedData); !ok || len(b) == 0 {
+		return errors.New("encrypted_data must be base64 or hex encoded")
+	}
+	if err := ValidateEncryptedKey(encryptedKey); err != nil {
+		return err
+	}
+	decoded := ciphertextDecodings(iv)
+	if len(decoded) == 0 {
+		return errors.New("iv must be base64 or hex encoded")
+	}
+	// 同时是合法十六进制和 base64 的 IV 任一解码长度符合即可
+	for _, b := range decoded {
+		if len(b) == 12 || len(b) == 16 {
+			return nil
+		}
+	}
+	return fmt.Errorf("iv must decode to 12 or 16 bytes, got %d", len(decoded[0]))
+}
+
+// ValidateEncryptedKey 校验 encrypted_key 格式：base64、十六进制或 JSON 对象（包装密钥的信封格式）
+func ValidateEncryptedKey(encryptedKey string) error {
+	if _, ok := decodeCiphertext(encryptedKey); ok {
+		return nil
+	}
+	trimmed := strings.TrimSpace(encryptedKey)
+	if !strings.HasPrefix(trimmed, "{") || !json.Valid([]byte(trimmed)) {
+		return errors.New("encrypted_key must be base64, hex or a JSON object")
+	}
+	return nil
+}
+
+// HashKeyIV 计算 (encrypted_key, iv) 组合的哈希，用于检测同一密钥下的 IV 重用
+func HashKeyIV(encryptedKey, iv string) string {
+	sum := sha256.Sum256([]byte(encryptedKey + "\x00" + iv))
+	return hex.EncodeToString(sum[:])
+}
+
+// randReader 随机数来源，默认为 crypto/rand
+var randReader io.Reader = rand.Reader
+
+// ErrShortRandomRead 随机数来源返回的字节数不足
+var ErrShortRandomRead = errors.New("short read from random source")
+
+// readRandom 读满 buf，任何错误或读取不足都视为失败
+func readRandom(buf []byte) error {
+	n, err := io.ReadFull(randReader, buf)
+	if err != nil {
+		if n > 0 && n < len(buf) {
+			return ErrShortRandomRead
+		}
+		return err
+	}
+	return nil
+}
+
+// CheckRandomness 确认随机数来源可用，供启动自检使用
+func CheckRandomness() error {
+	buf := make([]byte, 32)
+	if err := readRandom(buf); err != nil {
+		return err
+	}
+	for _, b := range buf {
+		if b != 0 {
+			return nil
+		}
+	}
+	return errors.New("random source returned all zero bytes")
 }
 
 // GenerateNonce 生成随机 nonce
 func GenerateNonce() (string, error) {
 	bytes := make([]byte, 32)
-	_, err := rand.Read(bytes)
-	if err != nil {
+	if err := readRandom(bytes); err != nil {
 		return "", err
 	}
 	return hex.EncodeToString(bytes), nil
 }
 
+// appName 签名消息中使用的应用名称
+var appName = "VaultSeed"
+
+// SetAppName 设置签名消息中使用的应用名称，启动时调用
+func SetAppName(name string) {
+	appName = name
+}
+
 // GenerateMessageForSigning 生成用于签名的消息
 func GenerateMessageForSigning(address, nonce string) string {
-	return fmt.Sprintf("Sign this message to authenticate with VaultSeed. Address: %s, Nonce: %s", address, nonce)
+	return fmt.Sprintf("Sign this message to authenticate with %s. Address: %s, Nonce: %s", appName, address, nonce)
+}
+
+// BlockReference 签名消息中引用区块的固定格式，用于区块新鲜度校验
+func BlockReference(number uint64, hash string) string {
+	return fmt.Sprintf("Block: %d %s", number, strings.ToLower(hash))
 }
 
 // GenerateDecryptMessage 生成用于解密的签名消息
 func GenerateDecryptMessage(contentID uint, nonce string) string {
 	return fmt.Sprintf("Sign this message to decrypt content. Content ID: %d, Nonce: %s", contentID, nonce)
 }
+
+// GenerateDeleteMessage 生成用于删除内容的签名消息
+func GenerateDeleteMessage(contentID uint, nonce string) string {
+	return fmt.Sprintf("Sign this message to delete content. Content ID: %d, Nonce: %s", contentID, nonce)
+}
+
+// GenerateUpdateMessage 生成用于更新内容的签名消息
+func GenerateUpdateMessage(contentID uint, nonce string) string {
+	return fmt.Sprintf("Sign this message to update content. Content ID: %d, Nonce: %s", contentID, nonce)
+}
+
+// GenerateRestoreRevisionMessage 生成用于恢复内容历史版本的签名消息
+func GenerateRestoreRevisionMessage(contentID uint, version int, nonce string) string {
+	return fmt.Sprintf("Sign this message to restore content version. Content ID: %d, Version: %d, Nonce: %s", contentID, version, nonce)
+}
+
+// GenerateKeyRotationMessage 生成用于轮换加密公钥的签名消息，包含新公钥，签名不能用于注册其他公钥
+func GenerateKeyRotationMessage(address, publicKey, nonce string) string {
+	return fmt.Sprintf("Sign this message to rotate your %s encryption key. Address: %s, New Public Key: %s, Nonce: %s", appName, address, publicKey, nonce)
+}
+
+// GenerateNonceResetMessage 生成用于重置 nonce 的签名消息
+func GenerateNonceResetMessage(address, nonce string) string {
+	return fmt.Sprintf("Sign this message to reset your %s nonces. Address: %s, Nonce: %s", appName, address, nonce)
+}
+
+// GenerateReauthMessage 生成敏感操作二次认证的签名消息，包含操作名和签发时间，签名只对该操作有效
+func GenerateReauthMessage(address, operation string, issuedAt time.Time, nonce string) string {
+	return fmt.Sprintf("Confirm a sensitive action on %s. Operation: %s, Address: %s, Issued At: %s, Nonce: %s",
+		appName, operation, address, issuedAt.UTC().Format(time.RFC3339), nonce)
+}
+
+// ParseReauthMessage 解析 GenerateReauthMessage 生成的消息
+func ParseReauthMessage(message string) (address, operation string, issuedAt time.Time, nonce string, err error) {
+	pattern := regexp.MustCompile("^Confirm a sensitive action on " + regexp.QuoteMeta(appName) +
+		`\. Operation: ([a-z-]+), Address: (0x[0-9a-fA-F]{40}), Issued At: ([^,]+), Nonce: ([0-9a-f]+)$`)
+	m := pattern.FindStringSubmatch(strings.TrimSpace(message))
+	if m == nil {
+		return "", "", time.Time{}, "", errors.New("malformed re-authentication message")
+	}
+	issuedAt, err = time.Parse(time.RFC3339, m[3])
+	if err != nil {
+		return "", "", time.Time{}, "", errors.New("malformed re-authentication timestamp")
+	}
+	return m[2], m[1], issuedAt, m[4], nil
+}
+
+// GenerateTransferMessage 生成用于转移内容所有权的签名消息
+func GenerateTransferMessage(fromAddress, toAddress, nonce string) string {
+	return fmt.Sprintf("Sign this message to transfer all %s content from %s to %s. Nonce: %s", appName, fromAddress, toAddress, nonce)
+}
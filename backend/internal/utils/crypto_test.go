@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testSigner 生成随机私钥，返回按 personal_sign 签名（V 为 27/28）的函数和对应地址
+func testSigner(t testing.TB) (func(message string) string, string) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return func(message string) string { return signPersonal(t, key, message) }, crypto.PubkeyToAddress(key.PublicKey).Hex()
+}
+
+func signPersonal(t testing.TB, key *ecdsa.PrivateKey, message string) string {
+	t.Helper()
+	hash := personalMessageHash(message)
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	sig[64] += 27
+	return hexutil.Encode(sig)
+}
+
+func TestVerifyEthereumSignatureHexCase(t *testing.T) {
+	sign, address := testSigner(t)
+	message := GenerateMessageForSigning(address, "nonce")
+	sig := sign(message)
+
+	tests := []struct {
+		name      string
+		signature string
+	}{
+		{"lowercase", sig},
+		{"uppercase digits", "0x" + strings.ToUpper(sig[2:])},
+		{"uppercase prefix", "0X" + strings.ToUpper(sig[2:])},
+		{"mixed case", "0x" + strings.ToUpper(sig[2:34]) + sig[34:]},
+		{"surrounding whitespace", " " + strings.ToUpper(sig) + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !VerifyEthereumSignature(message, tt.signature, address) {
+				t.Errorf("signature %q rejected", tt.signature)
+			}
+		})
+	}
+}
+
+// stubReader 依次返回 chunks 中的数据，之后返回 err
+type stubReader struct {
+	chunks [][]byte
+	err    error
+}
+
+func (r *stubReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func TestRandomSourceFailures(t *testing.T) {
+	failure := errors.New("entropy unavailable")
+	tests := []struct {
+		name    string
+		reader  io.Reader
+		wantErr error
+	}{
+		{"read error", &stubReader{err: failure}, failure},
+		{"short read", &stubReader{chunks: [][]byte{make([]byte, 10)}, err: io.EOF}, ErrShortRandomRead},
+		{"empty source", &stubReader{err: io.EOF}, io.EOF},
+		{"all zero", bytes.NewReader(make([]byte, 64)), nil},
+		{"healthy", rand.Reader, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(r io.Reader) { randReader = r }(randReader)
+			randReader = tt.reader
+
+			nonce, err := GenerateNonce()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GenerateNonce err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && len(nonce) != 64 {
+				t.Fatalf("nonce %q has length %d", nonce, len(nonce))
+			}
+		})
+	}
+
+	defer func(r io.Reader) { randReader = r }(randReader)
+	randReader = bytes.NewReader(make([]byte, 32))
+	if err := CheckRandomness(); err == nil {
+		t.Error("CheckRandomness accepted an all-zero source")
+	}
+}
+
+func TestDegenerateSignaturesRejected(t *testing.T) {
+	sign, address := testSigner(t)
+	message := GenerateMessageForSigning(address, "nonce")
+	valid, err := hexutil.Decode(sign(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := crypto.S256().Params().N.FillBytes(make([]byte, 32))
+
+	with := func(r, s []byte, v byte) string {
+		sig := make([]byte, 65)
+		copy(sig, valid)
+		if r != nil {
+			copy(sig[:32], r)
+		}
+		if s != nil {
+			copy(sig[32:64], s)
+		}
+		sig[64] = v
+		return hexutil.Encode(sig)
+	}
+	zero := make([]byte, 32)
+	one := append(make([]byte, 31), 1)
+
+	tests := []struct {
+		name      string
+		signature string
+		malformed bool // 应在恢复公钥之前就被拒绝
+	}{
+		{"all zero", hexutil.Encode(make([]byte, 65)), true},
+		{"all zero v=27", with(zero, zero, 27), true},
+		{"zero r", with(zero, nil, valid[64]), true},
+		{"zero s", with(nil, zero, valid[64]), true},
+		{"r equals curve order", with(order, nil, valid[64]), true},
+		{"s equals curve order", with(nil, order, valid[64]), true},
+		{"all ff", hexutil.Encode(bytes.Repeat([]byte{0xff}, 65)), true},
+		{"r and s one", with(one, one, 27), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if VerifyEthereumSignature(message, tt.signature, address) {
+				t.Fatalf("degenerate signature %s accepted", tt.signature)
+			}
+			signer, err := RecoverSigner(message, tt.signature)
+			if tt.malformed && err != ErrInvalidSignature {
+				t.Fatalf("RecoverSigner = %s, %v; want ErrInvalidSignature", signer, err)
+			}
+			if err == nil && strings.EqualFold(signer, address) {
+				t.Fatalf("recovered the expected address from %s", tt.signature)
+			}
+		})
+	}
+}
+
+// signEIP155 按 personal_sign 签名，V 编码为 EIP-155 的 recID+35+2*chainID（大端，可能超过一个字节）
+func signEIP155(t *testing.T, key *ecdsa.PrivateKey, message string, chainID uint64) string {
+	t.Helper()
+	hash := personalMessageHash(message)
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	v := new(big.Int).SetUint64(uint64(sig[64]) + 35 + 2*chainID)
+	return hexutil.Encode(append(sig[:64], v.Bytes()...))
+}
+
+func TestVerifyEthereumSignatureWithChainID(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	message := GenerateMessageForSigning(address, "nonce")
+	legacy := signPersonal(t, key, message)
+
+	tests := []struct {
+		name      string
+		signature string
+		chainID   uint64
+		want      bool
+	}{
+		{"mainnet", signEIP155(t, key, message, 1), 1, true},
+		{"polygon", signEIP155(t, key, message, 137), 137, true},
+		{"arbitrum", signEIP155(t, key, message, 42161), 42161, true},
+		{"polygon signature checked as mainnet", signEIP155(t, key, message, 137), 1, false},
+		{"mainnet signature checked as polygon", signEIP155(t, key, message, 1), 137, false},
+		{"unknown chain uses parity", signEIP155(t, key, message, 137), 0, true},
+		{"legacy v with chain id", legacy, 137, true},
+		{"legacy v without chain id", legacy, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyEthereumSignatureWithChainID(message, tt.signature, address, tt.chainID); got != tt.want {
+				t.Errorf("verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortSignatures(t *testing.T) {
+	_, other := testSigner(t)
+	tests := []struct {
+		name     string
+		allow    bool
+		expected func(address string) string
+		want     bool
+	}{
+		{"accepted when allowed", true, func(a string) string { return a }, true},
+		{"rejected when strict", false, func(a string) string { return a }, false},
+		{"wrong address", true, func(string) string { return other }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer SetAllowShortSignatures(allowShortSignatures)
+			SetAllowShortSignatures(tt.allow)
+
+			// 多签几次，确保 V=0 和 V=1 两种恢复位都被覆盖
+			seen := map[byte]bool{}
+			for i := 0; len(seen) < 2 && i < 64; i++ {
+				key, err := crypto.GenerateKey()
+				if err != nil {
+					t.Fatal(err)
+				}
+				address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+				message := GenerateMessageForSigning(address, "nonce")
+				full, err := hexutil.Decode(signPersonal(t, key, message))
+				if err != nil {
+					t.Fatal(err)
+				}
+				seen[full[64]-27] = true
+				if got := VerifyEthereumSignature(message, hexutil.Encode(full[:64]), tt.expected(address)); got != tt.want {
+					t.Fatalf("64-byte signature with v=%d: verify = %v, want %v", full[64]-27, got, tt.want)
+				}
+			}
+			if len(seen) < 2 {
+				t.Fatal("did not produce both recovery ids")
+			}
+		})
+	}
+}
+
+func TestValidateCiphertextFields(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xab}, 48))
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xcd}, 32))
+	gcmIV := base64.StdEncoding.EncodeToString(make([]byte, 12))
+	tests := []struct {
+		name          string
+		data, key, iv string
+		wantErr       string // 为空表示应通过
+	}{
+		{"valid base64", data, key, gcmIV, ""},
+		{"valid hex", "0x" + strings.Repeat("ab", 48), strings.Repeat("cd", 32), strings.Repeat("00", 16), ""},
+		{"url-safe base64 without padding", base64.RawURLEncoding.EncodeToString([]byte{0xfb, 0xff, 0xfe}), key, gcmIV, ""},
+		{"JSON key envelope", data, `{"version":"x25519-xsalsa20-poly1305","ciphertext":"..."}`, gcmIV, ""},
+		{"data not encoded", "not base64!", key, gcmIV, "encrypted_data must be base64 or hex encoded"},
+		{"data empty after decoding", "   ", key, gcmIV, "encrypted_data must be base64 or hex encoded"},
+		{"key not encoded", data, "not a key!", gcmIV, "encrypted_key must be base64, hex or a JSON object"},
+		{"key is a JSON array", data, `["not","an","object"]`, gcmIV, "encrypted_key must be base64, hex or a JSON object"},
+		{"key is truncated JSON", data, `{"ciphertext":`, gcmIV, "encrypted_key must be base64, hex or a JSON object"},
+		{"iv not encoded", data, key, "iv?", "iv must be base64 or hex encoded"},
+		{"iv too short", data, key, base64.StdEncoding.EncodeToString(make([]byte, 8)), "iv must decode to 12 or 16 bytes, got 8"},
+		{"iv too long", data, key, base64.StdEncoding.EncodeToString(make([]byte, 32)), "iv must decode to 12 or 16 bytes, got 32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCiphertextFields(tt.data, tt.key, tt.iv)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
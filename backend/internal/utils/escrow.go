@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// escrowKey 标题托管主密钥（AES-256），用于包装用户托管的标题密钥；未配置时不能开启托管
+//
+// 安全提示：持有该主密钥和数据库的人可以解密所有开启托管用户的标题。
+// 正文不受影响，但开启托管的用户其标题不再是零知识的。
+var escrowKey []byte
+
+// ErrNoEscrowKey 未配置 TITLE_ESCROW_KEY
+var ErrNoEscrowKey = errors.New("title escrow key is not configured")
+
+// ErrInvalidEscrowData 托管数据或加密标题格式不正确、认证失败
+var ErrInvalidEscrowData = errors.New("invalid escrow data")
+
+// SetEscrowKey 设置标题托管主密钥（64 位十六进制，可带 0x 前缀），为空表示关闭
+func SetEscrowKey(hexKey string) error {
+	if hexKey == "" {
+		escrowKey = nil
+		return nil
+	}
+	key, err := hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(hexKey, "0x"), "0X"))
+	if err != nil || len(key) != 32 {
+		return errors.New("TITLE_ESCROW_KEY must be 32 bytes of hex")
+	}
+	escrowKey = key
+	return nil
+}
+
+// EscrowEnabled 服务端是否配置了标题托管主密钥
+func EscrowEnabled() bool {
+	return escrowKey != nil
+}
+
+// SealEscrowedKey 用主密钥包装用户的标题密钥，返回 base64(nonce || 密文)
+func SealEscrowedKey(titleKey []byte) (string, error) {
+	if escrowKey == nil {
+		return "", ErrNoEscrowKey
+	}
+	gcm, err := newGCM(escrowKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if err := readRandom(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, titleKey, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenEscrowedKey 解开 SealEscrowedKey 包装的标题密钥
+func OpenEscrowedKey(sealed string) ([]byte, error) {
+	if escrowKey == nil {
+		return nil, ErrNoEscrowKey
+	}
+	return openGCM(escrowKey, sealed)
+}
+
+// DecryptTitle 用标题密钥解密客户端加密的标题
+// 托管模式要求 encrypted_title 为 base64(12 字节 IV || AES-256-GCM 密文)
+func DecryptTitle(titleKey []byte, encryptedTitle string) (string, error) {
+	plain, err := openGCM(titleKey, encryptedTitle)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// openGCM 解密 base64(nonce || 密文)
+func openGCM(key []byte, encoded string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidEscrowData
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrInvalidEscrowData
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrInvalidEscrowData
+	}
+	return plain, nil
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// siweHeaderSuffix EIP-4361 消息首行 "${domain} wants you to sign in with your Ethereum account:" 的固定部分
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// siweNoncePattern EIP-4361 要求 nonce 至少 8 位字母数字
+var siweNoncePattern = regexp.MustCompile(`^[A-Za-z0-9]{8,}$`)
+
+// SIWEMessage 解析后的 Sign-In with Ethereum（EIP-4361）消息
+type SIWEMessage struct {
+	Scheme         string // 可选，如 https
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        uint64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// IsSIWEMessage 判断消息首行是否为 EIP-4361 格式
+func IsSIWEMessage(message string) bool {
+	first, _, _ := strings.Cut(message, "\n")
+	return strings.HasSuffix(strings.TrimRight(first, "\r"), siweHeaderSuffix)
+}
+
+// ParseSIWEMessage 按 EIP-4361 的 ABNF 解析消息，字段必须按规范顺序出现
+// 只做格式校验；域名、nonce、有效期等由调用方按业务规则判断
+func ParseSIWEMessage(message string) (*SIWEMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	next := func() (string, bool) {
+		if len(lines) == 0 {
+			return "", false
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return line, true
+	}
+
+	var m SIWEMessage
+	header, _ := next()
+	domain, ok := strings.CutSuffix(header, siweHeaderSuffix)
+	if !ok || domain == "" {
+		return nil, errors.New("missing SIWE header")
+	}
+	if scheme, rest, found := strings.Cut(domain, "://"); found {
+		m.Scheme, domain = scheme, rest
+	}
+	m.Domain = domain
+
+	address, _ := next()
+	if !common.IsHexAddress(address) || !strings.HasPrefix(address, "0x") {
+		return nil, errors.New("invalid SIWE address")
+	}
+	m.Address = address
+
+	if blank, _ := next(); blank != "" {
+		return nil, errors.New("expected empty line after address")
+	}
+	// 可选的 statement，其后还有一个空行
+	if len(lines) > 0 && !strings.HasPrefix(lines[0], "URI: ") {
+		m.Statement, _ = next()
+		if blank, _ := next(); blank != "" {
+			return nil, errors.New("expected empty line after statement")
+		}
+	}
+
+	field := func(name string, required bool) (string, error) {
+		if len(lines) == 0 || !strings.HasPrefix(lines[0], name+": ") {
+			if required {
+				return "", fmt.Errorf("missing SIWE field %q", name)
+			}
+			return "", nil
+		}
+		line, _ := next()
+		return strings.TrimPrefix(line, name+": "), nil
+	}
+	timeField := func(name string, required bool) (*time.Time, error) {
+		v, err := field(name, required)
+		if err != nil || v == "" {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SIWE %s", name)
+		}
+		return &t, nil
+	}
+
+	var err error
+	if m.URI, err = field("URI", true); err != nil {
+		return nil, err
+	}
+	if m.Version, err = field("Version", true); err != nil {
+		return nil, err
+	}
+	if m.Version != "1" {
+		return nil, errors.New("unsupported SIWE version")
+	}
+	chainID, err := field("Chain ID", true)
+	if err != nil {
+		return nil, err
+	}
+	if m.ChainID, err = strconv.ParseUint(chainID, 10, 64); err != nil {
+		return nil, errors.New("invalid SIWE Chain ID")
+	}
+	if m.Nonce, err = field("Nonce", true); err != nil {
+		return nil, err
+	}
+	if !siweNoncePattern.MatchString(m.Nonce) {
+		return nil, errors.New("invalid SIWE Nonce")
+	}
+	issuedAt, err := timeField("Issued At", true)
+	if err != nil {
+		return nil, err
+	}
+	m.IssuedAt = *issuedAt
+	if m.ExpirationTime, err = timeField("Expiration Time", false); err != nil {
+		return nil, err
+	}
+	if m.NotBefore, err = timeField("Not Before", false); err != nil {
+		return nil, err
+	}
+	if m.RequestID, err = field("Request ID", false); err != nil {
+		return nil, err
+	}
+	if len(lines) > 0 && lines[0] == "Resources:" {
+		next()
+		for len(lines) > 0 && strings.HasPrefix(lines[0], "- ") {
+			line, _ := next()
+			m.Resources = append(m.Resources, strings.TrimPrefix(line, "- "))
+		}
+	}
+	// 允许末尾换行，其余多出的内容视为格式错误
+	for _, line := range lines {
+		if line != "" {
+			return nil, fmt.Errorf("unexpected SIWE line %q", line)
+		}
+	}
+	return &m, nil
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidToken 访问令牌缺失、签名无效、已过期或内容不合法
+var ErrInvalidToken = errors.New("invalid token")
+
+// tokenSecret HS256 签名密钥，启动时由 SetTokenSecret 设置
+var tokenSecret []byte
+
+// tokenTTL 访问令牌有效期
+var tokenTTL = 24 * time.Hour
+
+// Claims 访问令牌携带的声明
+type Claims struct {
+	Address string `json:"address"`
+	jwt.RegisteredClaims
+}
+
+// SetTokenSecret 设置访问令牌的签名密钥和有效期，启动时调用
+// secret 为空时生成随机密钥，返回 true 表示密钥为临时生成（重启后已签发的令牌全部失效）
+func SetTokenSecret(secret string, ttl time.Duration) (bool, error) {
+	tokenTTL = ttl
+	if secret != "" {
+		tokenSecret = []byte(secret)
+		return false, nil
+	}
+	buf := make([]byte, 32)
+	if err := readRandom(buf); err != nil {
+		return false, err
+	}
+	tokenSecret = buf
+	return true, nil
+}
+
+// TokenTTL 全局访问令牌有效期
+func TokenTTL() time.Duration {
+	return tokenTTL
+}
+
+// IssueToken 为地址签发有效期为 ttl 的访问令牌，返回令牌及其过期时间
+func IssueToken(address string, ttl time.Duration) (string, time.Time, error) {
+	if len(tokenSecret) == 0 {
+		return "", time.Time{}, errors.New("token secret not configured")
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := Claims{
+		Address: address,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   address,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(tokenSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseToken 校验 Authorization 头中的访问令牌（可带 Bearer 前缀）并返回其声明
+func ParseToken(header string) (*Claims, error) {
+	raw := strings.TrimSpace(header)
+	if len(raw) > 7 && strings.EqualFold(raw[:7], "Bearer ") {
+		raw = strings.TrimSpace(raw[7:])
+	}
+	if raw == "" || len(tokenSecret) == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	token, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		return tokenSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	// 必须带过期时间，且地址合法
+	if claims.ExpiresAt == nil || !common.IsHexAddress(claims.Address) {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// EIP-712 类型定义，字段顺序即编码顺序，客户端 eth_signTypedData_v4 的 types 必须与此一致
+const (
+	eip712DomainType         = "EIP712Domain(string name,uint256 chainId)"
+	decryptAuthorizationType = "DecryptAuthorization(uint256 contentID,string nonce)"
+)
+
+var (
+	eip712DomainTypeHash         = crypto.Keccak256([]byte(eip712DomainType))
+	decryptAuthorizationTypeHash = crypto.Keccak256([]byte(decryptAuthorizationType))
+)
+
+// typedDataDomainSeparator 计算域分隔符：应用名称（APP_NAME）+ 链 ID
+func typedDataDomainSeparator(chainID uint64) []byte {
+	return crypto.Keccak256(
+		eip712DomainTypeHash,
+		crypto.Keccak256([]byte(appName)),
+		math.U256Bytes(new(big.Int).SetUint64(chainID)),
+	)
+}
+
+// DecryptAuthorizationHash 计算 DecryptAuthorization{contentID, nonce} 的 EIP-712 签名摘要
+func DecryptAuthorizationHash(contentID uint, nonce string, chainID uint64) [32]byte {
+	structHash := crypto.Keccak256(
+		decryptAuthorizationTypeHash,
+		math.U256Bytes(new(big.Int).SetUint64(uint64(contentID))),
+		crypto.Keccak256([]byte(nonce)),
+	)
+	return crypto.Keccak256Hash([]byte("\x19\x01"), typedDataDomainSeparator(chainID), structHash)
+}
+
+// VerifyTypedDataSignature 验证对 EIP-712 摘要的签名（eth_signTypedData_v4），V 支持 0/1 和 27/28
+func VerifyTypedDataSignature(digest [32]byte, signature, expectedAddress string) bool {
+	sigBytes, err := hexutil.Decode(normalizeHex(signature))
+	if err != nil || len(sigBytes) != 65 {
+		return false
+	}
+	recID, err := recoveryID(new(big.Int).SetUint64(uint64(sigBytes[64])), 0)
+	if err != nil {
+		return false
+	}
+	recovered, err := recoverHashSigner(digest, sigBytes[:64], recID)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(recovered, expectedAddress)
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// VerifyRequest 批量验证中的单个签名
+type VerifyRequest struct {
+	Message         string
+	Signature       string
+	ExpectedAddress string
+}
+
+// VerifyBatch 并行验证一批以太坊签名，结果顺序与输入一致
+// 并发的 goroutine 数量不超过 CPU 核数
+func VerifyBatch(reqs []VerifyRequest) []bool {
+	results, _ := VerifyBatchContext(context.Background(), reqs)
+	return results
+}
+
+// VerifyBatchContext 与 VerifyBatch 相同，但在 ctx 取消后停止分发剩余任务并返回 ctx 的错误
+func VerifyBatchContext(ctx context.Context, reqs []VerifyRequest) ([]bool, error) {
+	results := make([]bool, len(reqs))
+	if len(reqs) == 0 {
+		return results, nil
+	}
+
+	workers := runtime.NumCPU()
+	if workers > len(reqs) {
+		workers = len(reqs)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := reqs[i]
+				results[i] = VerifyEthereumSignature(r.Message, r.Signature, r.ExpectedAddress)
+			}
+		}()
+	}
+
+dispatch:
+	for i := range reqs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, ctx.Err()
+}
@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// batchRequests 生成 n 个签名请求，每隔 bad 个放一个签名与地址不匹配的请求（bad 为 0 时全部有效）
+func batchRequests(t testing.TB, n, bad int) ([]VerifyRequest, []bool) {
+	t.Helper()
+	sign, address := testSigner(t)
+	_, other := testSigner(t)
+	reqs := make([]VerifyRequest, n)
+	want := make([]bool, n)
+	for i := range reqs {
+		message := fmt.Sprintf("item %d", i)
+		reqs[i] = VerifyRequest{Message: message, Signature: sign(message), ExpectedAddress: address}
+		want[i] = true
+		if bad > 0 && i%bad == 0 {
+			reqs[i].ExpectedAddress = other
+			want[i] = false
+		}
+	}
+	return reqs, want
+}
+
+func TestVerifyBatchPreservesOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+	}{
+		{"empty", 0},
+		{"single", 1},
+		{"more than workers", 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs, want := batchRequests(t, tt.n, 3)
+			got := VerifyBatch(reqs)
+			if len(got) != len(want) {
+				t.Fatalf("got %d results, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("result %d = %v, want %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyBatchContextCanceled(t *testing.T) {
+	reqs, _ := batchRequests(t, 16, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := VerifyBatchContext(ctx, reqs); err != context.Canceled {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func BenchmarkVerifySerial(b *testing.B) {
+	reqs, _ := batchRequests(b, 256, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, r := range reqs {
+			VerifyEthereumSignature(r.Message, r.Signature, r.ExpectedAddress)
+		}
+	}
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	reqs, _ := batchRequests(b, 256, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		VerifyBatch(reqs)
+	}
+}
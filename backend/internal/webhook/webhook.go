@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+var (
+	// endpoint 告警 webhook 地址，为空时不发送
+	endpoint string
+	// secret 用于对事件签名
+	secret string
+
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Configure 设置 webhook 地址和签名密钥
+func Configure(webhookURL, webhookSecret string) {
+	endpoint = webhookURL
+	secret = webhookSecret
+}
+
+// Event webhook 事件
+type Event struct {
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Result 单次投递结果
+type Result struct {
+	StatusCode int
+	Latency    time.Duration
+}
+
+// Enabled 是否配置了 webhook
+func Enabled() bool {
+	return endpoint != ""
+}
+
+// SendAsync 异步投递事件，失败只记录日志
+func SendAsync(eventType string, data map[string]interface{}) {
+	if !Enabled() {
+		return
+	}
+	go func() {
+		if _, err := Send(eventType, data); err != nil {
+			log.Printf("Webhook delivery failed for %s: %v", eventType, err)
+		}
+	}()
+}
+
+// Send 同步投递事件，请求体使用 HMAC-SHA256 签名放在 X-VaultSeed-Signature 头中
+func Send(eventType string, data map[string]interface{}) (Result, error) {
+	body, err := json.Marshal(Event{Type: eventType, Timestamp: time.Now().UTC(), Data: data})
+	if err != nil {
+		return Result{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-VaultSeed-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Latency: latency}, err
+	}
+	resp.Body.Close()
+
+	return Result{StatusCode: resp.StatusCode, Latency: latency}, nil
+}